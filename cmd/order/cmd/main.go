@@ -10,8 +10,15 @@ import (
 	"github.com/tricong1998/go-ecom/cmd/order/internal/api"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/database"
+	paymentGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/payment/grpc"
+	productGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/product/grpc"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/rabbit_handler"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/order/pkg/logger"
+	"github.com/tricong1998/go-ecom/pkg/events"
 	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/gorm"
 )
 
@@ -48,19 +55,141 @@ func main() {
 		log.Fatal().Err(err).Msg("Cannot connect rabbit")
 	}
 
-	runGinServer(cfg, db, log, &rabbitConfig, rabbitConn)
+	outboxRelay := rabbitmq.NewOutboxRelay(context.Background(), db, rabbitConn, log, rabbitmq.E_COM_EXCHANGE, "direct")
+	go outboxRelay.Run()
+	runPaymentConfirmationConsumer(cfg, db, log, &rabbitConfig, rabbitConn)
+	runTokenRevocationConsumer(db, log, &rabbitConfig, rabbitConn)
+	if cfg.ProcessingMode == events.ProcessingModeAsync {
+		runOrderEventConsumers(cfg, db, log, &rabbitConfig, rabbitConn)
+		go resumeOrderChoreography(cfg, db, log)
+	} else {
+		go resumeOrderSagas(cfg, db, log)
+	}
+	runGinServer(cfg, db, log)
 }
 
-func runGinServer(
+// resumeOrderSagas finishes the sagas that were interrupted by a restart of
+// the order service.
+func resumeOrderSagas(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
+	orderRepo := repository.NewOrderRepository(db)
+	sagaRepo := repository.NewSagaRepository(db)
+	paymentGateway := paymentGrpc.New(cfg.PaymentServer.Host, cfg.PaymentServer.Port)
+	productGateway := productGrpc.New(cfg.ProductServer.Host, cfg.ProductServer.Port)
+	orderSaga := services.NewOrderSaga(orderRepo, sagaRepo, paymentGateway, productGateway)
+
+	err := orderSaga.Resume()
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot resume order sagas")
+	}
+}
+
+// resumeOrderChoreography publishes again the orders that were created but
+// not picked up before a restart of the order service.
+func resumeOrderChoreography(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
+	orderChoreography := newOrderChoreography(cfg, db)
+
+	err := orderChoreography.Resume()
+	if err != nil {
+		log.Error().Err(err).Msg("Cannot resume order choreography")
+	}
+}
+
+// runOrderEventConsumers updates the orders processed asynchronously from the
+// events of the product and payment services.
+func runOrderEventConsumers(
 	cfg *config.Config,
 	db *gorm.DB,
 	log zerolog.Logger,
 	rabbitConfig *rabbitmq.RabbitMQConfig,
-	conn *amqp.Connection,
+	rabbitConn *amqp.Connection,
 ) {
+	orderEventDependencies := rabbit_handler.OrderEventDependencies{
+		Logger:            log,
+		OrderChoreography: newOrderChoreography(cfg, db),
+	}
+	consumers := []struct {
+		handler    func(queue string, msg amqp.Delivery, dependencies *rabbit_handler.OrderEventDependencies) error
+		queue      string
+		routingKey string
+	}{
+		{rabbit_handler.StockReserved, rabbitmq.ORDER_STOCK_RESERVED_QUEUE, rabbitmq.STOCK_RESERVED_ROUTING_KEY},
+		{rabbit_handler.StockReservationFailed, rabbitmq.ORDER_STOCK_RESERVATION_FAILED_QUEUE, rabbitmq.STOCK_RESERVATION_FAILED_ROUTING_KEY},
+		{rabbit_handler.PaymentSucceeded, rabbitmq.ORDER_PAYMENT_SUCCEEDED_QUEUE, rabbitmq.PAYMENT_SUCCEEDED_ROUTING_KEY},
+		{rabbit_handler.PaymentFailed, rabbitmq.ORDER_PAYMENT_FAILED_QUEUE, rabbitmq.PAYMENT_FAILED_ROUTING_KEY},
+		{rabbit_handler.StockCommitted, rabbitmq.ORDER_STOCK_COMMITTED_QUEUE, rabbitmq.STOCK_COMMITTED_ROUTING_KEY},
+		{rabbit_handler.PaymentCaptured, rabbitmq.ORDER_PAYMENT_CAPTURED_QUEUE, rabbitmq.PAYMENT_CAPTURED_ROUTING_KEY},
+		{rabbit_handler.StockCommitFailed, rabbitmq.ORDER_STOCK_COMMIT_FAILED_QUEUE, rabbitmq.STOCK_COMMIT_FAILED_ROUTING_KEY},
+	}
+	for _, c := range consumers {
+		consumer := rabbitmq.NewConsumer[*rabbit_handler.OrderEventDependencies](context.Background(), rabbitConfig, rabbitConn, log, c.handler, rabbitmq.E_COM_EXCHANGE, "direct", c.queue, c.routingKey)
+		go func() {
+			err := consumer.ConsumeMessage(nil, &orderEventDependencies)
+			if err != nil {
+				log.Error().Err(err).Msg("Consume message error")
+			}
+		}()
+	}
+}
+
+// runPaymentConfirmationConsumer updates the orders whose payment the payment
+// provider confirmed after the fact, in both processing modes.
+func runPaymentConfirmationConsumer(
+	cfg *config.Config,
+	db *gorm.DB,
+	log zerolog.Logger,
+	rabbitConfig *rabbitmq.RabbitMQConfig,
+	rabbitConn *amqp.Connection,
+) {
+	orderRepo := repository.NewOrderRepository(db)
+	sagaRepo := repository.NewSagaRepository(db)
+	paymentGateway := paymentGrpc.New(cfg.PaymentServer.Host, cfg.PaymentServer.Port)
+	productGateway := productGrpc.New(cfg.ProductServer.Host, cfg.ProductServer.Port)
+	orderSaga := services.NewOrderSaga(orderRepo, sagaRepo, paymentGateway, productGateway)
+	paymentConfirmationDependencies := rabbit_handler.PaymentConfirmationDependencies{
+		Logger:                     log,
+		PaymentConfirmationService: services.NewPaymentConfirmationService(orderRepo, productGateway, orderSaga),
+	}
+	consumer := rabbitmq.NewConsumer[*rabbit_handler.PaymentConfirmationDependencies](context.Background(), rabbitConfig, rabbitConn, log, rabbit_handler.PaymentConfirmed, rabbitmq.E_COM_EXCHANGE, "direct", rabbitmq.ORDER_PAYMENT_CONFIRMED_QUEUE, rabbitmq.PAYMENT_CONFIRMED_ROUTING_KEY)
+	go func() {
+		err := consumer.ConsumeMessage(nil, &paymentConfirmationDependencies)
+		if err != nil {
+			log.Error().Err(err).Msg("Consume message error")
+		}
+	}()
+}
+
+// runTokenRevocationConsumer rejects the access tokens revoked by the user
+// service.
+func runTokenRevocationConsumer(
+	db *gorm.DB,
+	log zerolog.Logger,
+	rabbitConfig *rabbitmq.RabbitMQConfig,
+	rabbitConn *amqp.Connection,
+) {
+	tokenRevocationDependencies := rabbit_handler.TokenRevocationDependencies{
+		Logger:          log,
+		RevocationStore: token.NewGormRevocationStore(db),
+	}
+	consumer := rabbitmq.NewConsumer[*rabbit_handler.TokenRevocationDependencies](context.Background(), rabbitConfig, rabbitConn, log, rabbit_handler.TokensRevoked, rabbitmq.E_COM_EXCHANGE, "direct", rabbitmq.ORDER_TOKENS_REVOKED_QUEUE, rabbitmq.TOKENS_REVOKED_ROUTING_KEY)
+	go func() {
+		err := consumer.ConsumeMessage(nil, &tokenRevocationDependencies)
+		if err != nil {
+			log.Error().Err(err).Msg("Consume message error")
+		}
+	}()
+}
+
+func newOrderChoreography(cfg *config.Config, db *gorm.DB) *services.OrderChoreography {
+	orderRepo := repository.NewOrderRepository(db)
+	paymentGateway := paymentGrpc.New(cfg.PaymentServer.Host, cfg.PaymentServer.Port)
+	productGateway := productGrpc.New(cfg.ProductServer.Host, cfg.ProductServer.Port)
+	return services.NewOrderChoreography(orderRepo, paymentGateway, productGateway)
+}
+
+func runGinServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
 	// Initialize router
 	routes := gin.Default()
-	api.SetupRoutes(routes, db, cfg, rabbitConfig, conn, log)
+	api.SetupRoutes(routes, db, cfg, log)
 
 	// Start server
 	address := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
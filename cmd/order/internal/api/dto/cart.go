@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+type AddCartItemDto struct {
+	ProductId uint `json:"product_id" binding:"required"`
+	Quantity  uint `json:"quantity" binding:"required,min=1"`
+}
+
+type UpdateCartItemDto struct {
+	Quantity uint `json:"quantity" binding:"required,min=1"`
+}
+
+type CartItemRequest struct {
+	ProductId uint `uri:"product_id" binding:"required,min=1"`
+}
+
+// CheckoutDto pays the order with PaymentMethod, or in cash when it is
+// omitted.
+type CheckoutDto struct {
+	PaymentMethod string `json:"payment_method" binding:"max=32"`
+}
+
+type CartItemResponse struct {
+	ProductId uint        `json:"product_id"`
+	Quantity  uint        `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"`
+	LineTotal money.Money `json:"line_total"`
+}
+
+type CartResponse struct {
+	ID        uint               `json:"id"`
+	UserId    uint               `json:"user_id"`
+	Items     []CartItemResponse `json:"items"`
+	Amount    money.Money        `json:"amount"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// ToCartResponse prices the lines of the cart. It fails when the total does
+// not fit or mixes currencies.
+func ToCartResponse(cart *models.Cart) (*CartResponse, error) {
+	amount, err := cart.Total()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]CartItemResponse, 0, len(cart.Items))
+	for _, v := range cart.Items {
+		lineTotal, err := v.LineTotal()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, CartItemResponse{
+			ProductId: v.ProductId,
+			Quantity:  v.Quantity,
+			UnitPrice: v.UnitPrice,
+			LineTotal: lineTotal,
+		})
+	}
+
+	return &CartResponse{
+		ID:        cart.ID,
+		UserId:    cart.UserId,
+		Items:     items,
+		Amount:    amount,
+		UpdatedAt: cart.UpdatedAt,
+	}, nil
+}
@@ -4,26 +4,48 @@ import (
 	"time"
 
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
+type CreateOrderItemDto struct {
+	ProductId uint `json:"product_id" binding:"required"`
+	Quantity  uint `json:"quantity" binding:"required,min=1"`
+}
+
+// CreateOrderDto places an order paid with PaymentMethod, or in cash when it
+// is omitted.
 type CreateOrderDto struct {
-	ProductId    uint `json:"product_id" binding:"required"`
-	UserId       uint `json:"user_id" binding:"required"`
-	ProductCount uint `json:"product_count" binding:"required"`
+	UserId        uint                 `json:"user_id" binding:"required"`
+	Items         []CreateOrderItemDto `json:"items" binding:"required,min=1,dive"`
+	PaymentMethod string               `json:"payment_method" binding:"max=32"`
+}
+
+type UpdateOrderDto struct {
+	UserId uint `json:"user_id" binding:"required"`
 }
 
 type ReadOrderRequest struct {
 	ID uint `uri:"id" binding:"required,min=1"`
 }
 
+type OrderItemResponse struct {
+	ProductId   uint        `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	Quantity    uint        `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Discount    money.Money `json:"discount"`
+	LineTotal   money.Money `json:"line_total"`
+}
+
 type OrderResponse struct {
-	ID           uint      `json:"id"`
-	ProductId    uint      `json:"product_id"`
-	UserId       uint      `json:"user_id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	ProductCount uint      `json:"product_count"`
-	Amount       uint      `json:"amount"`
+	ID            uint                `json:"id"`
+	UserId        uint                `json:"user_id"`
+	Status        string              `json:"status"`
+	Items         []OrderItemResponse `json:"items"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	Amount        money.Money         `json:"amount"`
+	PaymentMethod string              `json:"payment_method"`
 }
 
 type ListOrderQuery struct {
@@ -37,14 +59,76 @@ type ListOrderResponse struct {
 	Metadata MetadataDto     `json:"metadata"`
 }
 
+func ToOrderItems(input []CreateOrderItemDto) []models.OrderItem {
+	items := make([]models.OrderItem, 0, len(input))
+	for _, v := range input {
+		items = append(items, models.OrderItem{
+			ProductId: v.ProductId,
+			Quantity:  v.Quantity,
+		})
+	}
+	return items
+}
+
 func ToOrderResponse(user *models.Order) *OrderResponse {
+	items := make([]OrderItemResponse, 0, len(user.Items))
+	for _, v := range user.Items {
+		items = append(items, OrderItemResponse{
+			ProductId:   v.ProductId,
+			ProductName: v.ProductName,
+			Quantity:    v.Quantity,
+			UnitPrice:   v.UnitPrice,
+			Discount:    v.Discount,
+			LineTotal:   v.LineTotal,
+		})
+	}
+
 	return &OrderResponse{
-		ID:           user.ID,
-		ProductId:    user.ProductId,
-		UserId:       user.UserId,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		ProductCount: user.ProductCount,
-		Amount:       user.Amount,
+		ID:            user.ID,
+		UserId:        user.UserId,
+		Status:        user.Status,
+		Items:         items,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Amount:        user.Amount,
+		PaymentMethod: user.PaymentMethod,
+	}
+}
+
+type UpdateOrderStatusDto struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// CreateOrderErrorResponse tells why a placed order failed. OrderId can be
+// used to read the order.
+type CreateOrderErrorResponse struct {
+	Error   string `json:"error"`
+	OrderId uint   `json:"order_id"`
+}
+
+type CancelOrderDto struct {
+	Reason string `json:"reason"`
+}
+
+type OrderStatusHistoryResponse struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func ToOrderStatusHistoryResponse(history []models.OrderStatusHistory) []OrderStatusHistoryResponse {
+	response := make([]OrderStatusHistoryResponse, 0, len(history))
+	for _, v := range history {
+		response = append(response, OrderStatusHistoryResponse{
+			FromStatus: v.FromStatus,
+			ToStatus:   v.ToStatus,
+			Actor:      v.Actor,
+			Reason:     v.Reason,
+			CreatedAt:  v.CreatedAt,
+		})
 	}
+	return response
 }
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/api/dto"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/token"
+)
+
+type CartHandler struct {
+	CartService services.ICartService
+}
+
+func NewCartHandler(cartService services.ICartService) *CartHandler {
+	return &CartHandler{cartService}
+}
+
+func (cartHandler *CartHandler) GetCart(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	cart, err := cartHandler.CartService.GetCart(payload.UserId)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	writeCart(ctx, cart)
+}
+
+func (cartHandler *CartHandler) AddCartItem(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.AddCartItemDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cart, err := cartHandler.CartService.AddCartItem(payload.UserId, input.ProductId, input.Quantity)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	writeCart(ctx, cart)
+}
+
+func (cartHandler *CartHandler) UpdateCartItem(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	var itemRequest dto.CartItemRequest
+	if err := ctx.ShouldBindUri(&itemRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var input dto.UpdateCartItemDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cart, err := cartHandler.CartService.UpdateCartItem(payload.UserId, itemRequest.ProductId, input.Quantity)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	writeCart(ctx, cart)
+}
+
+func (cartHandler *CartHandler) RemoveCartItem(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	var itemRequest dto.CartItemRequest
+	if err := ctx.ShouldBindUri(&itemRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cart, err := cartHandler.CartService.RemoveCartItem(payload.UserId, itemRequest.ProductId)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	writeCart(ctx, cart)
+}
+
+func (cartHandler *CartHandler) Checkout(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	// The body is optional: without one the order is paid in cash.
+	var input dto.CheckoutDto
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	order, err := cartHandler.CartService.Checkout(payload.UserId, input.PaymentMethod)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(orderCreatedStatus(order), dto.ToOrderResponse(order))
+}
+
+func writeCart(ctx *gin.Context, cart *models.Cart) {
+	response, err := dto.ToCartResponse(cart)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCartItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCartEmpty),
+		errors.Is(err, services.ErrNotEnoughStock),
+		errors.Is(err, services.ErrMixedCurrencies),
+		errors.Is(err, services.ErrInvalidPaymentMethod),
+		errors.Is(err, money.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// authPayload returns the token payload set by the auth middleware. It writes
+// an unauthorized response when the payload is missing.
+func authPayload(ctx *gin.Context) (*token.Payload, bool) {
+	userPayload, ok := ctx.Get(middleware.AuthorizationPayloadKey)
+	if !ok {
+		err := errors.New("authorization payload is not provided")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+	return userPayload.(*token.Payload), true
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/api/dto"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/mocks"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
+	productPb "github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/token"
+)
+
+func TestAddCartItem(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setupInputFunc func(input *dto.AddCartItemDto, payload *token.Payload, mockProduct *productPb.ReadProductResponse)
+		mockFunc       func(cartRepo *mocks.MockCartRepository, productGateway *mocks.MockProductGateway, mockProduct *productPb.ReadProductResponse)
+		expectFunc     func(w *httptest.ResponseRecorder, input *dto.AddCartItemDto, mockProduct *productPb.ReadProductResponse)
+	}{
+		{
+			name: "OK",
+			setupInputFunc: func(input *dto.AddCartItemDto, payload *token.Payload, mockProduct *productPb.ReadProductResponse) {
+				input.ProductId = 1
+				input.Quantity = 2
+				payload.UserId = 1
+				mockProduct.Product = &productPb.Product{Id: 1, Price: money.ToProto(money.New(100, "USD")), Quantity: 10}
+			},
+			mockFunc: func(cartRepo *mocks.MockCartRepository, productGateway *mocks.MockProductGateway, mockProduct *productPb.ReadProductResponse) {
+				cart := models.Cart{UserId: 1}
+				cart.ID = 1
+				cartRepo.On("GetOrCreateCart", uint(1)).Return(&cart, nil)
+				cartRepo.On("SaveCartItem", mock.AnythingOfType("*models.CartItem")).Return(nil)
+				productGateway.On("Get", context.Background(), uint(1)).Return(mockProduct, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, input *dto.AddCartItemDto, mockProduct *productPb.ReadProductResponse) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response dto.CartResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), response.UserId)
+				assert.Len(t, response.Items, 1)
+				assert.Equal(t, input.ProductId, response.Items[0].ProductId)
+				assert.Equal(t, input.Quantity, response.Items[0].Quantity)
+				assert.Equal(t, money.New(100*int64(input.Quantity), "USD"), response.Amount)
+			},
+		},
+		{
+			name: "BadInput",
+			setupInputFunc: func(input *dto.AddCartItemDto, payload *token.Payload, mockProduct *productPb.ReadProductResponse) {
+				payload.UserId = 1
+			},
+			mockFunc: func(cartRepo *mocks.MockCartRepository, productGateway *mocks.MockProductGateway, mockProduct *productPb.ReadProductResponse) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, input *dto.AddCartItemDto, mockProduct *productPb.ReadProductResponse) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+		{
+			name: "NotEnoughStock",
+			setupInputFunc: func(input *dto.AddCartItemDto, payload *token.Payload, mockProduct *productPb.ReadProductResponse) {
+				input.ProductId = 1
+				input.Quantity = 20
+				payload.UserId = 1
+				mockProduct.Product = &productPb.Product{Id: 1, Price: money.ToProto(money.New(100, "USD")), Quantity: 10}
+			},
+			mockFunc: func(cartRepo *mocks.MockCartRepository, productGateway *mocks.MockProductGateway, mockProduct *productPb.ReadProductResponse) {
+				cart := models.Cart{UserId: 1}
+				cart.ID = 1
+				cartRepo.On("GetOrCreateCart", uint(1)).Return(&cart, nil)
+				productGateway.On("Get", context.Background(), uint(1)).Return(mockProduct, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, input *dto.AddCartItemDto, mockProduct *productPb.ReadProductResponse) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			cartRepo := new(mocks.MockCartRepository)
+			productGateway := new(mocks.MockProductGateway)
+			cartService := services.NewCartService(cartRepo, productGateway, nil, zerolog.Nop())
+			cartHandler := NewCartHandler(cartService)
+			var input dto.AddCartItemDto
+			var payload token.Payload
+			var productMock productPb.ReadProductResponse
+			tc.setupInputFunc(&input, &payload, &productMock)
+			tc.mockFunc(cartRepo, productGateway, &productMock)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set(middleware.AuthorizationPayloadKey, &payload)
+
+			jsonItem, _ := json.Marshal(input)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/carts/items", bytes.NewBuffer(jsonItem))
+			c.Request.Header.Set("Content-Type", "application/json")
+			cartHandler.AddCartItem(c)
+
+			tc.expectFunc(w, &input, &productMock)
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/api/dto"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
+	"gorm.io/gorm"
 )
 
 type OrderHandler struct {
@@ -26,16 +29,16 @@ func (userHandler *OrderHandler) CreateOrder(ctx *gin.Context) {
 	}
 
 	user := models.Order{
-		ProductId:    input.ProductId,
-		UserId:       input.UserId,
-		ProductCount: input.ProductCount,
+		UserId:        input.UserId,
+		Items:         dto.ToOrderItems(input.Items),
+		PaymentMethod: input.PaymentMethod,
 	}
 	if err := userHandler.OrderService.CreateOrder(&user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		createOrderError(ctx, &user, err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, dto.ToOrderResponse(&user))
+	ctx.JSON(orderCreatedStatus(&user), dto.ToOrderResponse(&user))
 }
 
 func (userHandler *OrderHandler) ReadOrder(ctx *gin.Context) {
@@ -56,7 +59,7 @@ func (userHandler *OrderHandler) ReadOrder(ctx *gin.Context) {
 }
 
 func (userHandler *OrderHandler) UpdateOrder(ctx *gin.Context) {
-	var input dto.CreateOrderDto
+	var input dto.UpdateOrderDto
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
@@ -69,8 +72,7 @@ func (userHandler *OrderHandler) UpdateOrder(ctx *gin.Context) {
 	}
 
 	user := models.Order{
-		ProductId: input.ProductId,
-		UserId:    input.UserId,
+		UserId: input.UserId,
 	}
 	user.ID = readOrderRequest.ID
 	if err := userHandler.OrderService.UpdateOrder(&user); err != nil {
@@ -124,3 +126,131 @@ func (userHandler *OrderHandler) DeleteOrder(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+func (userHandler *OrderHandler) UpdateOrderStatus(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	var readOrderRequest dto.ReadOrderRequest
+	if err := ctx.ShouldBindUri(&readOrderRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var input dto.UpdateOrderStatusDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.OrderService.TransitionOrderStatus(readOrderRequest.ID, input.Status, services.Actor(payload), input.Reason)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	order, err := userHandler.OrderService.ReadOrder(readOrderRequest.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ToOrderResponse(order))
+}
+
+func (userHandler *OrderHandler) ListOrderStatusHistory(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	var readOrderRequest dto.ReadOrderRequest
+	if err := ctx.ShouldBindUri(&readOrderRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	history, err := userHandler.OrderService.ListOrderStatusHistory(readOrderRequest.ID, payload)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ToOrderStatusHistoryResponse(history))
+}
+
+func (userHandler *OrderHandler) CancelOrder(ctx *gin.Context) {
+	payload, ok := authPayload(ctx)
+	if !ok {
+		return
+	}
+
+	var readOrderRequest dto.ReadOrderRequest
+	if err := ctx.ShouldBindUri(&readOrderRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var input dto.CancelOrderDto
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	order, err := userHandler.OrderService.CancelOrder(readOrderRequest.ID, payload, input.Reason)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ToOrderResponse(order))
+}
+
+// orderCreatedStatus answers 202 Accepted for an order that is still being
+// processed in the background.
+func orderCreatedStatus(order *models.Order) int {
+	if order.Status == models.OrderStatusCreated {
+		return http.StatusAccepted
+	}
+	return http.StatusCreated
+}
+
+// createOrderError answers an order that could not be created. An order that
+// failed after it was placed is answered with its ID, so that the client can
+// follow it.
+func createOrderError(ctx *gin.Context, order *models.Order, err error) {
+	var status int
+	var message string
+	switch {
+	case errors.Is(err, services.ErrMixedCurrencies),
+		errors.Is(err, services.ErrInvalidPaymentMethod):
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	case errors.Is(err, services.ErrPaymentFailed):
+		status, message = http.StatusPaymentRequired, "the payment of the order was declined"
+	case errors.Is(err, services.ErrOrderSagaFailed):
+		status, message = http.StatusConflict, "the order could not be placed"
+	case errors.Is(err, services.ErrOrderProcessing):
+		status, message = http.StatusConflict, "the order is still being processed"
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(status, dto.CreateOrderErrorResponse{Error: message, OrderId: order.ID})
+}
+
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOrderForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrInvalidOrderTransition),
+		errors.Is(err, services.ErrOrderNotCancellable),
+		errors.Is(err, services.ErrOrderProcessing):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
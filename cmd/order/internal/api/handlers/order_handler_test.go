@@ -17,10 +17,19 @@ import (
 	"github.com/tricong1998/go-ecom/cmd/order/internal/api/dto"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/mocks"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
 	paymentPb "github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
 	productPb "github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
 	userPb "github.com/tricong1998/go-ecom/cmd/user/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 func expectBodyOrder(t *testing.T, w *httptest.ResponseRecorder, mockResponse *models.Order) {
@@ -28,8 +37,17 @@ func expectBodyOrder(t *testing.T, w *httptest.ResponseRecorder, mockResponse *m
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Equal(t, response.ID, mockResponse.ID)
-	assert.Equal(t, response.ProductId, mockResponse.ProductId)
 	assert.Equal(t, response.UserId, mockResponse.UserId)
+	assert.Equal(t, response.Amount, mockResponse.Amount)
+	assert.Len(t, response.Items, len(mockResponse.Items))
+	for i, item := range mockResponse.Items {
+		assert.Equal(t, response.Items[i].ProductId, item.ProductId)
+		assert.Equal(t, response.Items[i].ProductName, item.ProductName)
+		assert.Equal(t, response.Items[i].Quantity, item.Quantity)
+		assert.Equal(t, response.Items[i].UnitPrice, item.UnitPrice)
+		assert.Equal(t, response.Items[i].Discount, item.Discount)
+		assert.Equal(t, response.Items[i].LineTotal, item.LineTotal)
+	}
 	assert.WithinDuration(t, response.CreatedAt, mockResponse.CreatedAt, time.Second)
 	assert.WithinDuration(t, response.UpdatedAt, mockResponse.UpdatedAt, time.Second)
 }
@@ -42,7 +60,7 @@ func TestCreateOrder(t *testing.T) {
 			mockResponse *models.Order,
 			userMock *userPb.User,
 			mockProduct *productPb.ReadProductResponse,
-			mockPayment *paymentPb.CreatePaymentResponse,
+			mockPayment *paymentPb.AuthorizePaymentResponse,
 		)
 		mockFunc func(
 			userRepo *mocks.MockOrderRepository,
@@ -52,8 +70,7 @@ func TestCreateOrder(t *testing.T) {
 			paymentGateway *mocks.MockPaymentGateway,
 			mockUser *userPb.User,
 			mockProduct *productPb.ReadProductResponse,
-			mockPayment *paymentPb.CreatePaymentResponse,
-			publisher *mocks.MockRabbitPublisher,
+			mockPayment *paymentPb.AuthorizePaymentResponse,
 		)
 		expectFunc func(
 			w *httptest.ResponseRecorder, mockResponse *models.Order)
@@ -64,28 +81,38 @@ func TestCreateOrder(t *testing.T) {
 				mockResponse *models.Order,
 				userMock *userPb.User,
 				mockProduct *productPb.ReadProductResponse,
-				mockPayment *paymentPb.CreatePaymentResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
 			) {
 				input.UserId = 1
-				input.ProductId = 1
-				input.ProductCount = 1
+				input.Items = []dto.CreateOrderItemDto{
+					{ProductId: 1, Quantity: 1},
+					{ProductId: 1, Quantity: 2},
+				}
 				product := productPb.Product{
-					Id:       uint64(input.ProductId),
+					Id:       uint64(1),
 					Name:     "product name",
-					Price:    100,
+					Price:    money.ToProto(money.New(100, "EUR")),
 					Quantity: 10,
+					Discount: money.ToProto(money.New(10, "EUR")),
 				}
 				mockProduct.Product = &product
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
 				mockResponse.UserId = input.UserId
-				mockResponse.ProductId = input.ProductId
-				mockResponse.ProductCount = input.ProductCount
-				mockResponse.Amount = uint(mockProduct.Product.Price) * uint(input.ProductCount)
+				mockResponse.Items = []models.OrderItem{{
+					ProductId:   1,
+					ProductName: "product name",
+					Quantity:    3,
+					UnitPrice:   money.New(100, "EUR"),
+					Discount:    money.New(10, "EUR"),
+					LineTotal:   money.New(270, "EUR"),
+				}}
+				mockResponse.Amount = money.New(270, "EUR")
 				payment := paymentPb.Payment{
 					Id:     uint64(1),
-					Amount: uint64(mockResponse.Amount),
+					Amount: money.ToProto(mockResponse.Amount),
+					Status: "authorized",
 				}
 				mockPayment.Payment = &payment
 				userMock.Id = uint64(input.UserId)
@@ -100,8 +127,7 @@ func TestCreateOrder(t *testing.T) {
 				paymentGateway *mocks.MockPaymentGateway,
 				mockUser *userPb.User,
 				mockProduct *productPb.ReadProductResponse,
-				mockPayment *paymentPb.CreatePaymentResponse,
-				publisher *mocks.MockRabbitPublisher,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
 			) {
 				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
 					arg := args.Get(0).(*models.Order)
@@ -109,35 +135,184 @@ func TestCreateOrder(t *testing.T) {
 					arg.CreatedAt = mockResponse.CreatedAt
 					arg.UpdatedAt = mockResponse.UpdatedAt
 				})
-				userRepo.On("UpdateOrderStatus", mock.AnythingOfType("uint"), mock.AnythingOfType("string")).Return(nil)
+				userRepo.On("TransitionOrderStatus",
+					mock.AnythingOfType("uint"),
+					mock.AnythingOfType("string"),
+					services.ActorSystem,
+					mock.AnythingOfType("string"),
+					mock.Anything).Return(nil)
+				userRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil)
 				userGateway.On("Get",
 					context.Background(),
 					mock.AnythingOfType("uint")).Return(mockUser, nil)
 				productGateway.On("Get",
 					context.Background(),
 					mock.AnythingOfType("uint")).Return(mockProduct, nil)
-				productGateway.On("UpdateProductQuantity",
+				productGateway.On("ReserveStock",
 					context.Background(),
-					mock.AnythingOfType("uint"),
-					mock.AnythingOfType("uint")).Return(true, nil)
-				paymentGateway.On("Create",
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				productGateway.On("CommitReservation",
 					context.Background(),
-					mock.AnythingOfType("*pb.CreatePaymentRequest")).
+					uint(1)).Return(&productPb.Reservation{Id: 1}, nil)
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
 					Return(mockPayment, nil)
-				publisher.On("PublishMessage", mock.AnythingOfType("dto.CreateUserPoint")).Return(nil)
+				paymentGateway.On("Capture",
+					mock.Anything,
+					uint(1)).
+					Return(&paymentPb.CapturePaymentResponse{Payment: &paymentPb.Payment{Id: 1, Status: "success"}}, nil).Once()
 			},
 			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
 				assert.Equal(t, http.StatusCreated, w.Code)
 				expectBodyOrder(t, w, mockResponse)
 			},
 		},
+		{
+			name: "PaymentPending",
+			setupInputFunc: func(input *dto.CreateOrderDto,
+				mockResponse *models.Order,
+				userMock *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				input.UserId = 1
+				input.Items = []dto.CreateOrderItemDto{{ProductId: 1, Quantity: 1}}
+				mockProduct.Product = &productPb.Product{
+					Id:       uint64(1),
+					Name:     "product name",
+					Price:    money.ToProto(money.New(100, "USD")),
+					Quantity: 10,
+				}
+				mockResponse.ID = 1
+				mockPayment.Payment = &paymentPb.Payment{
+					Id:     uint64(1),
+					Amount: money.ToProto(money.New(100, "USD")),
+					Status: "pending",
+				}
+				userMock.Id = uint64(input.UserId)
+				userMock.Username = "test"
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				mockResponse *models.Order,
+				userGateway *mocks.MockUserGateway,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockUser *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Order)
+					arg.ID = mockResponse.ID
+				})
+				userRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusAwaitingPayment, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockUser, nil)
+				productGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockProduct, nil)
+				productGateway.On("ReserveStock",
+					context.Background(),
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
+					Return(mockPayment, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var response dto.OrderResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.OrderStatusAwaitingPayment, response.Status)
+			},
+		},
+		{
+			name: "CashOnDelivery",
+			setupInputFunc: func(input *dto.CreateOrderDto,
+				mockResponse *models.Order,
+				userMock *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				input.UserId = 1
+				input.Items = []dto.CreateOrderItemDto{{ProductId: 1, Quantity: 1}}
+				mockProduct.Product = &productPb.Product{
+					Id:       uint64(1),
+					Name:     "product name",
+					Price:    money.ToProto(money.New(100, "USD")),
+					Quantity: 10,
+				}
+				mockResponse.ID = 1
+				mockPayment.Payment = &paymentPb.Payment{
+					Id:                uint64(1),
+					Amount:            money.ToProto(money.New(100, "USD")),
+					Status:            "authorized",
+					CollectOnDelivery: true,
+				}
+				userMock.Id = uint64(input.UserId)
+				userMock.Username = "test"
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				mockResponse *models.Order,
+				userGateway *mocks.MockUserGateway,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockUser *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Order)
+					arg.ID = mockResponse.ID
+				})
+				userRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusAwaitingPayment, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusPaid, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockUser, nil)
+				productGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockProduct, nil)
+				productGateway.On("ReserveStock",
+					context.Background(),
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				productGateway.On("CommitReservation",
+					context.Background(),
+					uint(1)).Return(&productPb.Reservation{Id: 1}, nil)
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
+					Return(mockPayment, nil)
+				paymentGateway.On("Capture",
+					mock.Anything,
+					uint(1)).
+					Return(&paymentPb.CapturePaymentResponse{Payment: &paymentPb.Payment{Id: 1, Status: "pending", CollectOnDelivery: true}}, nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var response dto.OrderResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.OrderStatusPaid, response.Status)
+			},
+		},
 		{
 			name: "BadInput",
 			setupInputFunc: func(input *dto.CreateOrderDto,
 				mockResponse *models.Order,
 				userMock *userPb.User,
 				mockProduct *productPb.ReadProductResponse,
-				mockPayment *paymentPb.CreatePaymentResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
 			) {
 			},
 			mockFunc: func(
@@ -148,8 +323,7 @@ func TestCreateOrder(t *testing.T) {
 				paymentGateway *mocks.MockPaymentGateway,
 				mockUser *userPb.User,
 				mockProduct *productPb.ReadProductResponse,
-				mockPayment *paymentPb.CreatePaymentResponse,
-				publisher *mocks.MockRabbitPublisher,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
 			) {
 				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
 					arg := args.Get(0).(*models.Order)
@@ -157,22 +331,29 @@ func TestCreateOrder(t *testing.T) {
 					arg.CreatedAt = mockResponse.CreatedAt
 					arg.UpdatedAt = mockResponse.UpdatedAt
 				})
-				userRepo.On("UpdateOrderStatus", mock.AnythingOfType("uint"), mock.AnythingOfType("string")).Return(nil)
+				userRepo.On("TransitionOrderStatus",
+					mock.AnythingOfType("uint"),
+					mock.AnythingOfType("string"),
+					services.ActorSystem,
+					mock.AnythingOfType("string"),
+					mock.Anything).Return(nil)
 				userGateway.On("Get",
 					context.Background(),
 					mock.AnythingOfType("uint")).Return(mockUser, nil)
 				productGateway.On("Get",
 					context.Background(),
 					mock.AnythingOfType("uint")).Return(mockProduct, nil)
-				productGateway.On("UpdateProductQuantity",
+				productGateway.On("ReserveStock",
 					context.Background(),
-					mock.AnythingOfType("uint"),
-					mock.AnythingOfType("uint")).Return(true, nil)
-				paymentGateway.On("Create",
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				productGateway.On("CommitReservation",
 					context.Background(),
-					mock.AnythingOfType("*pb.CreatePaymentRequest")).
+					uint(1)).Return(&productPb.Reservation{Id: 1}, nil)
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
 					Return(mockPayment, nil)
-				publisher.On("PublishMessage", mock.AnythingOfType("dto.CreateUserPoint")).Return(nil)
 			},
 			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
 				assert.Equal(t, http.StatusBadRequest, w.Code)
@@ -185,15 +366,17 @@ func TestCreateOrder(t *testing.T) {
 				mockResponse *models.Order,
 				userMock *userPb.User,
 				mockProduct *productPb.ReadProductResponse,
-				mockPayment *paymentPb.CreatePaymentResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
 			) {
 				input.UserId = 1
-				input.ProductId = 1
-				input.ProductCount = 1
+				input.Items = []dto.CreateOrderItemDto{
+					{ProductId: 1, Quantity: 1},
+					{ProductId: 1, Quantity: 2},
+				}
 				product := productPb.Product{
-					Id:       uint64(input.ProductId),
+					Id:       uint64(1),
 					Name:     "product name",
-					Price:    100,
+					Price:    money.ToProto(money.New(100, "USD")),
 					Quantity: 10,
 				}
 				mockProduct.Product = &product
@@ -201,12 +384,17 @@ func TestCreateOrder(t *testing.T) {
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
 				mockResponse.UserId = input.UserId
-				mockResponse.ProductId = input.ProductId
-				mockResponse.ProductCount = input.ProductCount
-				mockResponse.Amount = uint(mockProduct.Product.Price) * uint(input.ProductCount)
+				mockResponse.Items = []models.OrderItem{{
+					ProductId: 1,
+					Quantity:  3,
+					UnitPrice: money.New(100, "USD"),
+					Discount:  money.Zero("USD"),
+					LineTotal: money.New(300, "USD"),
+				}}
+				mockResponse.Amount = money.New(300, "USD")
 				payment := paymentPb.Payment{
 					Id:     uint64(1),
-					Amount: uint64(mockResponse.Amount),
+					Amount: money.ToProto(mockResponse.Amount),
 				}
 				mockPayment.Payment = &payment
 				userMock.Id = uint64(input.UserId)
@@ -221,51 +409,256 @@ func TestCreateOrder(t *testing.T) {
 				paymentGateway *mocks.MockPaymentGateway,
 				mockUser *userPb.User,
 				mockProduct *productPb.ReadProductResponse,
-				mockPayment *paymentPb.CreatePaymentResponse,
-				publisher *mocks.MockRabbitPublisher,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
 			) {
 				err := errors.New("Error")
 				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(err)
-				userRepo.On("UpdateOrderStatus", mock.AnythingOfType("uint"), mock.AnythingOfType("string")).Return(nil)
+				userRepo.On("TransitionOrderStatus",
+					mock.AnythingOfType("uint"),
+					mock.AnythingOfType("string"),
+					services.ActorSystem,
+					mock.AnythingOfType("string"),
+					mock.Anything).Return(nil)
 				userGateway.On("Get",
 					context.Background(),
 					mock.AnythingOfType("uint")).Return(mockUser, nil)
 				productGateway.On("Get",
 					context.Background(),
 					mock.AnythingOfType("uint")).Return(mockProduct, nil)
-				productGateway.On("UpdateProductQuantity",
+				productGateway.On("ReserveStock",
 					context.Background(),
-					mock.AnythingOfType("uint"),
-					mock.AnythingOfType("uint")).Return(true, nil)
-				paymentGateway.On("Create",
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				productGateway.On("CommitReservation",
 					context.Background(),
-					mock.AnythingOfType("*pb.CreatePaymentRequest")).
+					uint(1)).Return(&productPb.Reservation{Id: 1}, nil)
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
 					Return(mockPayment, nil)
-				publisher.On("PublishMessage", mock.AnythingOfType("dto.CreateUserPoint")).Return(nil)
 			},
 			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
 				assert.Equal(t, http.StatusInternalServerError, w.Code)
 			},
 		},
+		{
+			name: "PaymentErrorReleasesStock",
+			setupInputFunc: func(
+				input *dto.CreateOrderDto,
+				mockResponse *models.Order,
+				userMock *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				input.UserId = 1
+				input.Items = []dto.CreateOrderItemDto{
+					{ProductId: 1, Quantity: 1},
+					{ProductId: 1, Quantity: 2},
+				}
+				product := productPb.Product{
+					Id:       uint64(1),
+					Name:     "product name",
+					Price:    money.ToProto(money.New(100, "USD")),
+					Quantity: 10,
+				}
+				mockProduct.Product = &product
+				mockResponse.ID = 1
+				mockResponse.CreatedAt = time.Now()
+				mockResponse.UpdatedAt = mockResponse.CreatedAt
+				payment := paymentPb.Payment{
+					Id:     uint64(1),
+					Amount: money.ToProto(money.New(100, "USD")),
+					Status: "success",
+				}
+				mockPayment.Payment = &payment
+				userMock.Id = uint64(input.UserId)
+				userMock.Username = "test"
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				mockResponse *models.Order,
+				userGateway *mocks.MockUserGateway,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockUser *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Order)
+					arg.ID = mockResponse.ID
+				})
+				userRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusAwaitingPayment, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusFailed, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockUser, nil)
+				productGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockProduct, nil)
+				productGateway.On("ReserveStock",
+					context.Background(),
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				productGateway.On("ReleaseReservation", context.Background(), uint(1)).
+					Return(&productPb.Reservation{Id: 1}, nil).Once()
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
+					Return((*paymentPb.AuthorizePaymentResponse)(nil), errors.New("payment declined"))
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+				var response dto.CreateOrderErrorResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, mockResponse.ID, response.OrderId)
+			},
+		},
+		{
+			name: "PaymentDeclined",
+			setupInputFunc: func(
+				input *dto.CreateOrderDto,
+				mockResponse *models.Order,
+				userMock *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				input.UserId = 1
+				input.Items = []dto.CreateOrderItemDto{
+					{ProductId: 1, Quantity: 1},
+					{ProductId: 1, Quantity: 2},
+				}
+				product := productPb.Product{
+					Id:       uint64(1),
+					Name:     "product name",
+					Price:    money.ToProto(money.New(100, "USD")),
+					Quantity: 10,
+				}
+				mockProduct.Product = &product
+				mockResponse.ID = 1
+				mockResponse.CreatedAt = time.Now()
+				mockResponse.UpdatedAt = mockResponse.CreatedAt
+				payment := paymentPb.Payment{
+					Id:     uint64(1),
+					Amount: money.ToProto(money.New(100, "USD")),
+					Status: "failed",
+				}
+				mockPayment.Payment = &payment
+				userMock.Id = uint64(input.UserId)
+				userMock.Username = "test"
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				mockResponse *models.Order,
+				userGateway *mocks.MockUserGateway,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockUser *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Order)
+					arg.ID = mockResponse.ID
+				})
+				userRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusAwaitingPayment, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusFailed, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				userGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockUser, nil)
+				productGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockProduct, nil)
+				productGateway.On("ReserveStock",
+					context.Background(),
+					"order-1",
+					mock.AnythingOfType("[]*pb.ProductQuantity")).Return(&productPb.Reservation{Id: 1}, nil)
+				productGateway.On("ReleaseReservation", context.Background(), uint(1)).
+					Return(&productPb.Reservation{Id: 1}, nil).Once()
+				paymentGateway.On("Authorize",
+					mock.Anything,
+					mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
+					Return(mockPayment, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusPaymentRequired, w.Code)
+				var response dto.CreateOrderErrorResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, mockResponse.ID, response.OrderId)
+			},
+		},
+		{
+			name: "InvalidPaymentMethod",
+			setupInputFunc: func(
+				input *dto.CreateOrderDto,
+				mockResponse *models.Order,
+				userMock *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				input.UserId = 1
+				input.Items = []dto.CreateOrderItemDto{{ProductId: 1, Quantity: 1}}
+				input.PaymentMethod = "cheque"
+				mockProduct.Product = &productPb.Product{
+					Id:       uint64(1),
+					Name:     "product name",
+					Price:    money.ToProto(money.New(100, "USD")),
+					Quantity: 10,
+				}
+				userMock.Id = uint64(input.UserId)
+				userMock.Username = "test"
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				mockResponse *models.Order,
+				userGateway *mocks.MockUserGateway,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockUser *userPb.User,
+				mockProduct *productPb.ReadProductResponse,
+				mockPayment *paymentPb.AuthorizePaymentResponse,
+			) {
+				userGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockUser, nil)
+				productGateway.On("Get",
+					context.Background(),
+					mock.AnythingOfType("uint")).Return(mockProduct, nil)
+				paymentGateway.On("ValidateMethod", mock.Anything, "cheque", mock.Anything).
+					Return((*paymentPb.PaymentMethod)(nil), status.Error(codes.InvalidArgument, "unknown payment method"))
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
 	}
 
 	for i := range testCases {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
 			userRepo := new(mocks.MockOrderRepository)
-			publisher := new(mocks.MockRabbitPublisher)
 			userGateway := new(mocks.MockUserGateway)
 			productGateway := new(mocks.MockProductGateway)
 			paymentGateway := new(mocks.MockPaymentGateway)
-			userService := services.NewOrderService(userRepo, userGateway, paymentGateway, publisher, productGateway)
+			sagaRepo := new(mocks.MockSagaRepository)
+			orderSaga := services.NewOrderSaga(userRepo, sagaRepo, paymentGateway, productGateway)
+			userService := services.NewOrderService(userRepo, userGateway, productGateway, orderSaga)
 			userHandler := NewOrderHandler(userService)
 			var user dto.CreateOrderDto
 			var mockResponse models.Order
 			var userMock userPb.User
 			var productMock productPb.ReadProductResponse
-			var paymentMock paymentPb.CreatePaymentResponse
+			var paymentMock paymentPb.AuthorizePaymentResponse
 			tc.setupInputFunc(&user, &mockResponse, &userMock, &productMock, &paymentMock)
-			tc.mockFunc(userRepo, &mockResponse, userGateway, productGateway, paymentGateway, &userMock, &productMock, &paymentMock, publisher)
+			tc.mockFunc(userRepo, &mockResponse, userGateway, productGateway, paymentGateway, &userMock, &productMock, &paymentMock)
+			paymentGateway.On("ValidateMethod", mock.Anything, services.DefaultPaymentMethod, mock.Anything).
+				Return(&paymentPb.PaymentMethod{Name: services.DefaultPaymentMethod, Enabled: true}, nil)
+			sagaRepo.On("CreateSaga", mock.AnythingOfType("*models.OrderSaga")).Return(nil)
+			sagaRepo.On("UpdateSaga", mock.AnythingOfType("*models.OrderSaga")).Return(nil)
 			gin.SetMode(gin.TestMode)
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
@@ -280,6 +673,118 @@ func TestCreateOrder(t *testing.T) {
 	}
 }
 
+func TestCreateOrderAsync(t *testing.T) {
+	userRepo := new(mocks.MockOrderRepository)
+	userGateway := new(mocks.MockUserGateway)
+	productGateway := new(mocks.MockProductGateway)
+	paymentGateway := new(mocks.MockPaymentGateway)
+	orderChoreography := services.NewOrderChoreography(userRepo, paymentGateway, productGateway)
+	orderService := services.NewOrderService(userRepo, userGateway, productGateway, orderChoreography)
+	orderHandler := NewOrderHandler(orderService)
+
+	input := dto.CreateOrderDto{
+		UserId: 1,
+		Items:  []dto.CreateOrderItemDto{{ProductId: 1, Quantity: 2}},
+	}
+	userRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.Order).ID = 1
+	})
+	userRepo.On("WriteOrderEvents", mock.MatchedBy(func(events []repository.OrderEvent) bool {
+		return len(events) == 1 && events[0].RoutingKey == rabbitmq.ORDER_CREATED_ROUTING_KEY
+	})).Return(nil).Once()
+	userGateway.On("Get", context.Background(), uint(1)).Return(&userPb.User{Id: 1, Username: "test"}, nil)
+	productGateway.On("Get", context.Background(), uint(1)).
+		Return(&productPb.ReadProductResponse{Product: &productPb.Product{Id: 1, Price: money.ToProto(money.New(100, "USD")), Quantity: 10}}, nil)
+	paymentGateway.On("ValidateMethod", mock.Anything, services.DefaultPaymentMethod, mock.Anything).
+		Return(&paymentPb.PaymentMethod{Name: services.DefaultPaymentMethod, Enabled: true}, nil)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	jsonOrder, _ := json.Marshal(input)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/orders", bytes.NewBuffer(jsonOrder))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	orderHandler.CreateOrder(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var response dto.OrderResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCreated, response.Status)
+	assert.Equal(t, money.New(200, "USD"), response.Amount)
+	userRepo.AssertExpectations(t)
+	paymentGateway.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything)
+}
+
+func TestCreateOrderPendingCapture(t *testing.T) {
+	orderRepo := new(mocks.MockOrderRepository)
+	userGateway := new(mocks.MockUserGateway)
+	productGateway := new(mocks.MockProductGateway)
+	paymentGateway := new(mocks.MockPaymentGateway)
+	sagaRepo := new(mocks.MockSagaRepository)
+	orderSaga := services.NewOrderSaga(orderRepo, sagaRepo, paymentGateway, productGateway)
+	orderService := services.NewOrderService(orderRepo, userGateway, productGateway, orderSaga)
+	orderHandler := NewOrderHandler(orderService)
+	paymentConfirmationService := services.NewPaymentConfirmationService(orderRepo, productGateway, orderSaga)
+
+	var order *models.Order
+	var saga models.OrderSaga
+	orderRepo.On("CreateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
+		order = args.Get(0).(*models.Order)
+		order.ID = 1
+	})
+	orderRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil)
+	orderRepo.On("TransitionOrderStatus", uint(1), models.OrderStatusAwaitingPayment, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+	orderRepo.On("TransitionOrderStatus", uint(1), models.OrderStatusPaid, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+	sagaRepo.On("CreateSaga", mock.AnythingOfType("*models.OrderSaga")).Return(nil)
+	sagaRepo.On("UpdateSaga", mock.AnythingOfType("*models.OrderSaga")).Return(nil).Run(func(args mock.Arguments) {
+		saga = *args.Get(0).(*models.OrderSaga)
+	})
+	userGateway.On("Get", context.Background(), uint(1)).Return(&userPb.User{Id: 1, Username: "test"}, nil)
+	productGateway.On("Get", context.Background(), uint(1)).
+		Return(&productPb.ReadProductResponse{Product: &productPb.Product{Id: 1, Price: money.ToProto(money.New(100, "USD")), Quantity: 10}}, nil)
+	productGateway.On("ReserveStock", context.Background(), "order-1", mock.AnythingOfType("[]*pb.ProductQuantity")).
+		Return(&productPb.Reservation{Id: 1}, nil)
+	productGateway.On("CommitReservation", context.Background(), uint(1)).Return(&productPb.Reservation{Id: 1}, nil)
+	paymentGateway.On("ValidateMethod", mock.Anything, services.DefaultPaymentMethod, mock.Anything).
+		Return(&paymentPb.PaymentMethod{Name: services.DefaultPaymentMethod, Enabled: true}, nil)
+	paymentGateway.On("Authorize", mock.Anything, mock.AnythingOfType("*pb.AuthorizePaymentRequest")).
+		Return(&paymentPb.AuthorizePaymentResponse{Payment: &paymentPb.Payment{Id: 1, Status: "authorized"}}, nil)
+	paymentGateway.On("Capture", mock.Anything, uint(1)).
+		Return(&paymentPb.CapturePaymentResponse{Payment: &paymentPb.Payment{Id: 1, Status: "pending"}}, nil).Once()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	jsonOrder, _ := json.Marshal(dto.CreateOrderDto{
+		UserId: 1,
+		Items:  []dto.CreateOrderItemDto{{ProductId: 1, Quantity: 1}},
+	})
+	c.Request, _ = http.NewRequest(http.MethodPost, "/orders", bytes.NewBuffer(jsonOrder))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	orderHandler.CreateOrder(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, services.SagaStatusAwaitingPayment, saga.Status)
+	assert.Equal(t, services.SagaStepCapturePayment, saga.Step)
+
+	orderRepo.On("ReadOrder", uint(1)).Return(order, nil)
+	sagaRepo.On("ReadSagaByOrderId", uint(1)).Return(&saga, nil)
+	err := paymentConfirmationService.PaymentConfirmed(events.PaymentConfirmed{
+		OrderId:   1,
+		PaymentId: 1,
+		Status:    "success",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, services.SagaStatusCompleted, saga.Status)
+	assert.Equal(t, models.OrderStatusPaid, order.Status)
+	orderRepo.AssertExpectations(t)
+	paymentGateway.AssertNumberOfCalls(t, "Capture", 1)
+}
+
 func TestReadOrder(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -292,7 +797,7 @@ func TestReadOrder(t *testing.T) {
 			setupInputFunc: func(input *dto.ReadOrderRequest, mockResponse *models.Order) {
 				input.ID = 1
 				mockResponse.UserId = 1
-				mockResponse.ProductId = 1
+				mockResponse.Items = []models.OrderItem{{ProductId: 1, Quantity: 1}}
 				mockResponse.ID = input.ID
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
@@ -335,11 +840,12 @@ func TestReadOrder(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Arrange
 			userRepo := new(mocks.MockOrderRepository)
-			publisher := new(mocks.MockRabbitPublisher)
 			userGateway := new(mocks.MockUserGateway)
 			productGateway := new(mocks.MockProductGateway)
 			paymentGateway := new(mocks.MockPaymentGateway)
-			userService := services.NewOrderService(userRepo, userGateway, paymentGateway, publisher, productGateway)
+			sagaRepo := new(mocks.MockSagaRepository)
+			orderSaga := services.NewOrderSaga(userRepo, sagaRepo, paymentGateway, productGateway)
+			userService := services.NewOrderService(userRepo, userGateway, productGateway, orderSaga)
 			userHandler := NewOrderHandler(userService)
 			var input dto.ReadOrderRequest
 			var mockResponse models.Order
@@ -381,8 +887,8 @@ func TestListOrder(t *testing.T) {
 				*total = 10
 				now := time.Now()
 				user1 := models.Order{
-					ProductId: 1,
-					UserId:    1,
+					UserId: 1,
+					Items:  []models.OrderItem{{ProductId: 1, Quantity: 1}},
 				}
 				user1.CreatedAt = now
 				user1.UpdatedAt = now
@@ -390,8 +896,8 @@ func TestListOrder(t *testing.T) {
 				mockResponse = append(mockResponse, user1)
 
 				user2 := models.Order{
-					ProductId: 2,
-					UserId:    2,
+					UserId: 2,
+					Items:  []models.OrderItem{{ProductId: 2, Quantity: 1}},
 				}
 				user2.CreatedAt = now
 				user2.UpdatedAt = now
@@ -416,7 +922,7 @@ func TestListOrder(t *testing.T) {
 				assert.Equal(t, response.Metadata.PerPage, input.PerPage)
 				assert.Equal(t, response.Metadata.Total, *total)
 				assert.Len(t, response.Items, len(mockResponse))
-				assert.Equal(t, response.Items[0].ProductId, mockResponse[0].ProductId)
+				assert.Equal(t, response.Items[0].Items[0].ProductId, mockResponse[0].Items[0].ProductId)
 				assert.Equal(t, response.Items[0].UserId, mockResponse[0].UserId)
 			},
 		},
@@ -449,11 +955,12 @@ func TestListOrder(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Arrange
 			userRepo := new(mocks.MockOrderRepository)
-			publisher := new(mocks.MockRabbitPublisher)
 			userGateway := new(mocks.MockUserGateway)
 			productGateway := new(mocks.MockProductGateway)
 			paymentGateway := new(mocks.MockPaymentGateway)
-			userService := services.NewOrderService(userRepo, userGateway, paymentGateway, publisher, productGateway)
+			sagaRepo := new(mocks.MockSagaRepository)
+			orderSaga := services.NewOrderSaga(userRepo, sagaRepo, paymentGateway, productGateway)
+			userService := services.NewOrderService(userRepo, userGateway, productGateway, orderSaga)
 			userHandler := NewOrderHandler(userService)
 			var input dto.ListOrderQuery
 			var total int64
@@ -477,19 +984,17 @@ func TestListOrder(t *testing.T) {
 func TestUpdateOrder(t *testing.T) {
 	testCases := []struct {
 		name           string
-		setupInputFunc func(input *dto.CreateOrderDto, mockResponse *models.Order)
+		setupInputFunc func(input *dto.UpdateOrderDto, mockResponse *models.Order)
 		mockFunc       func(userRepo *mocks.MockOrderRepository, mockResponse *models.Order)
 		expectFunc     func(w *httptest.ResponseRecorder, mockResponse *models.Order)
 	}{
 		{
 			name: "OK",
-			setupInputFunc: func(input *dto.CreateOrderDto, mockResponse *models.Order) {
-				input.ProductId = 1
+			setupInputFunc: func(input *dto.UpdateOrderDto, mockResponse *models.Order) {
 				input.UserId = 1
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
-				mockResponse.ProductId = input.ProductId
 				mockResponse.UserId = input.UserId
 
 			},
@@ -508,7 +1013,7 @@ func TestUpdateOrder(t *testing.T) {
 		},
 		{
 			name: "BadInput",
-			setupInputFunc: func(input *dto.CreateOrderDto, mockResponse *models.Order) {
+			setupInputFunc: func(input *dto.UpdateOrderDto, mockResponse *models.Order) {
 			},
 			mockFunc: func(userRepo *mocks.MockOrderRepository, mockResponse *models.Order) {
 				userRepo.On("UpdateOrder", mock.AnythingOfType("*models.Order")).Return(nil).Run(func(args mock.Arguments) {
@@ -524,9 +1029,8 @@ func TestUpdateOrder(t *testing.T) {
 		},
 		{
 			name: "UpdateOrderError",
-			setupInputFunc: func(input *dto.CreateOrderDto, mockResponse *models.Order) {
+			setupInputFunc: func(input *dto.UpdateOrderDto, mockResponse *models.Order) {
 				input.UserId = 1
-				input.ProductId = 1
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
@@ -545,13 +1049,14 @@ func TestUpdateOrder(t *testing.T) {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
 			userRepo := new(mocks.MockOrderRepository)
-			publisher := new(mocks.MockRabbitPublisher)
 			userGateway := new(mocks.MockUserGateway)
 			productGateway := new(mocks.MockProductGateway)
 			paymentGateway := new(mocks.MockPaymentGateway)
-			userService := services.NewOrderService(userRepo, userGateway, paymentGateway, publisher, productGateway)
+			sagaRepo := new(mocks.MockSagaRepository)
+			orderSaga := services.NewOrderSaga(userRepo, sagaRepo, paymentGateway, productGateway)
+			userService := services.NewOrderService(userRepo, userGateway, productGateway, orderSaga)
 			userHandler := NewOrderHandler(userService)
-			var user dto.CreateOrderDto
+			var user dto.UpdateOrderDto
 			var mockResponse models.Order
 			tc.setupInputFunc(&user, &mockResponse)
 			tc.mockFunc(userRepo, &mockResponse)
@@ -570,3 +1075,278 @@ func TestUpdateOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestCancelOrder(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setupInputFunc func(payload *token.Payload, mockResponse *models.Order)
+		mockFunc       func(
+			userRepo *mocks.MockOrderRepository,
+			sagaRepo *mocks.MockSagaRepository,
+			productGateway *mocks.MockProductGateway,
+			paymentGateway *mocks.MockPaymentGateway,
+			mockResponse *models.Order,
+		)
+		expectFunc func(w *httptest.ResponseRecorder, mockResponse *models.Order)
+	}{
+		{
+			name: "OK",
+			setupInputFunc: func(payload *token.Payload, mockResponse *models.Order) {
+				payload.UserId = 1
+				payload.Role = "user"
+				mockResponse.ID = 1
+				mockResponse.UserId = 1
+				mockResponse.PaymentId = 1
+				mockResponse.Status = models.OrderStatusPaid
+				mockResponse.Items = []models.OrderItem{{ProductId: 1, Quantity: 1}}
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				sagaRepo *mocks.MockSagaRepository,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockResponse *models.Order,
+			) {
+				userRepo.On("ReadOrder", mockResponse.ID).Return(mockResponse, nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusCancelled, "user:1", "changed my mind", mock.Anything).Return(nil).Once()
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusRefunded, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				sagaRepo.On("ReadSagaByOrderId", mockResponse.ID).Return(&models.OrderSaga{OrderID: mockResponse.ID, Status: services.SagaStatusCompleted}, nil)
+				sagaRepo.On("UpdateSaga", mock.AnythingOfType("*models.OrderSaga")).Return(nil)
+				paymentGateway.On("Refund", mock.Anything, uint(1)).Return(&paymentPb.RefundPaymentResponse{}, nil).Once()
+				productGateway.On("RestoreProductQuantity", context.Background(), mock.AnythingOfType("[]*pb.ProductQuantity")).Return(true, nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response dto.OrderResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.OrderStatusRefunded, response.Status)
+			},
+		},
+		{
+			name: "RetryInterruptedCancellation",
+			setupInputFunc: func(payload *token.Payload, mockResponse *models.Order) {
+				payload.UserId = 1
+				payload.Role = "user"
+				mockResponse.ID = 1
+				mockResponse.UserId = 1
+				mockResponse.PaymentId = 1
+				mockResponse.Status = models.OrderStatusCancelled
+				mockResponse.Items = []models.OrderItem{{ProductId: 1, Quantity: 1}}
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				sagaRepo *mocks.MockSagaRepository,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockResponse *models.Order,
+			) {
+				userRepo.On("ReadOrder", mockResponse.ID).Return(mockResponse, nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusRefunded, services.ActorSystem, mock.AnythingOfType("string"), mock.Anything).Return(nil).Once()
+				sagaRepo.On("ReadSagaByOrderId", mockResponse.ID).Return(&models.OrderSaga{OrderID: mockResponse.ID, Status: services.SagaStatusCancelling, Reason: "changed my mind"}, nil)
+				sagaRepo.On("UpdateSaga", mock.MatchedBy(func(saga *models.OrderSaga) bool {
+					return saga.Status == services.SagaStatusCancelled
+				})).Return(nil).Once()
+				paymentGateway.On("Refund", mock.Anything, uint(1)).Return(&paymentPb.RefundPaymentResponse{}, nil).Once()
+				productGateway.On("RestoreProductQuantity", context.Background(), mock.AnythingOfType("[]*pb.ProductQuantity")).Return(true, nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response dto.OrderResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.OrderStatusRefunded, response.Status)
+			},
+		},
+		{
+			name: "RefundFails",
+			setupInputFunc: func(payload *token.Payload, mockResponse *models.Order) {
+				payload.UserId = 1
+				payload.Role = "user"
+				mockResponse.ID = 1
+				mockResponse.UserId = 1
+				mockResponse.PaymentId = 1
+				mockResponse.Status = models.OrderStatusPaid
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				sagaRepo *mocks.MockSagaRepository,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockResponse *models.Order,
+			) {
+				userRepo.On("ReadOrder", mockResponse.ID).Return(mockResponse, nil)
+				userRepo.On("TransitionOrderStatus", mockResponse.ID, models.OrderStatusCancelled, "user:1", "changed my mind", mock.Anything).Return(nil).Once()
+				sagaRepo.On("ReadSagaByOrderId", mockResponse.ID).Return(&models.OrderSaga{OrderID: mockResponse.ID, Status: services.SagaStatusCompleted}, nil)
+				sagaRepo.On("UpdateSaga", mock.MatchedBy(func(saga *models.OrderSaga) bool {
+					return saga.Status == services.SagaStatusCancelling && saga.Reason == "changed my mind"
+				})).Return(nil).Once()
+				paymentGateway.On("Refund", mock.Anything, uint(1)).Return((*paymentPb.RefundPaymentResponse)(nil), errors.New("payment service unavailable")).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusInternalServerError, w.Code)
+			},
+		},
+		{
+			name: "NotOwner",
+			setupInputFunc: func(payload *token.Payload, mockResponse *models.Order) {
+				payload.UserId = 2
+				payload.Role = "user"
+				mockResponse.ID = 1
+				mockResponse.UserId = 1
+				mockResponse.Status = models.OrderStatusPaid
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				sagaRepo *mocks.MockSagaRepository,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockResponse *models.Order,
+			) {
+				userRepo.On("ReadOrder", mockResponse.ID).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+			},
+		},
+		{
+			name: "CustomerAfterFulfillment",
+			setupInputFunc: func(payload *token.Payload, mockResponse *models.Order) {
+				payload.UserId = 1
+				payload.Role = "user"
+				mockResponse.ID = 1
+				mockResponse.UserId = 1
+				mockResponse.Status = models.OrderStatusFulfilling
+			},
+			mockFunc: func(
+				userRepo *mocks.MockOrderRepository,
+				sagaRepo *mocks.MockSagaRepository,
+				productGateway *mocks.MockProductGateway,
+				paymentGateway *mocks.MockPaymentGateway,
+				mockResponse *models.Order,
+			) {
+				userRepo.On("ReadOrder", mockResponse.ID).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Order) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := new(mocks.MockOrderRepository)
+			userGateway := new(mocks.MockUserGateway)
+			productGateway := new(mocks.MockProductGateway)
+			paymentGateway := new(mocks.MockPaymentGateway)
+			sagaRepo := new(mocks.MockSagaRepository)
+			orderSaga := services.NewOrderSaga(userRepo, sagaRepo, paymentGateway, productGateway)
+			userService := services.NewOrderService(userRepo, userGateway, productGateway, orderSaga)
+			userHandler := NewOrderHandler(userService)
+			var payload token.Payload
+			var mockResponse models.Order
+			tc.setupInputFunc(&payload, &mockResponse)
+			tc.mockFunc(userRepo, sagaRepo, productGateway, paymentGateway, &mockResponse)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set(middleware.AuthorizationPayloadKey, &payload)
+
+			body, _ := json.Marshal(dto.CancelOrderDto{Reason: "changed my mind"})
+			c.Request, _ = http.NewRequest(http.MethodPost, "/orders/1/cancel", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(mockResponse.ID)}}
+
+			userHandler.CancelOrder(c)
+
+			tc.expectFunc(w, &mockResponse)
+		})
+	}
+}
+
+func TestListOrderStatusHistory(t *testing.T) {
+	history := []models.OrderStatusHistory{
+		{OrderID: 1, FromStatus: models.OrderStatusCreated, ToStatus: models.OrderStatusPaid, Actor: services.ActorSystem},
+	}
+
+	testCases := []struct {
+		name       string
+		payload    token.Payload
+		mockFunc   func(userRepo *mocks.MockOrderRepository)
+		expectFunc func(w *httptest.ResponseRecorder, userRepo *mocks.MockOrderRepository)
+	}{
+		{
+			name:    "Owner",
+			payload: token.Payload{UserId: 1, Role: "user"},
+			mockFunc: func(userRepo *mocks.MockOrderRepository) {
+				userRepo.On("ReadOrder", uint(1)).Return(&models.Order{Model: gorm.Model{ID: 1}, UserId: 1}, nil)
+				userRepo.On("ListOrderStatusHistory", uint(1)).Return(history, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockOrderRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response []dto.OrderStatusHistoryResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Len(t, response, 1)
+			},
+		},
+		{
+			name:    "Admin",
+			payload: token.Payload{UserId: 2, Role: "admin"},
+			mockFunc: func(userRepo *mocks.MockOrderRepository) {
+				userRepo.On("ReadOrder", uint(1)).Return(&models.Order{Model: gorm.Model{ID: 1}, UserId: 1}, nil)
+				userRepo.On("ListOrderStatusHistory", uint(1)).Return(history, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockOrderRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:    "OtherCustomer",
+			payload: token.Payload{UserId: 2, Role: "user"},
+			mockFunc: func(userRepo *mocks.MockOrderRepository) {
+				userRepo.On("ReadOrder", uint(1)).Return(&models.Order{Model: gorm.Model{ID: 1}, UserId: 1}, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockOrderRepository) {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				userRepo.AssertNotCalled(t, "ListOrderStatusHistory", mock.Anything)
+			},
+		},
+		{
+			name:    "OrderNotFound",
+			payload: token.Payload{UserId: 1, Role: "user"},
+			mockFunc: func(userRepo *mocks.MockOrderRepository) {
+				userRepo.On("ReadOrder", uint(1)).Return((*models.Order)(nil), gorm.ErrRecordNotFound)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockOrderRepository) {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			userRepo := new(mocks.MockOrderRepository)
+			productGateway := new(mocks.MockProductGateway)
+			orderSaga := services.NewOrderSaga(userRepo, new(mocks.MockSagaRepository), new(mocks.MockPaymentGateway), productGateway)
+			userService := services.NewOrderService(userRepo, new(mocks.MockUserGateway), productGateway, orderSaga)
+			userHandler := NewOrderHandler(userService)
+			tc.mockFunc(userRepo)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set(middleware.AuthorizationPayloadKey, &tc.payload)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/orders/1/history", nil)
+			c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+			// Act
+			userHandler.ListOrderStatusHistory(c)
+
+			// Assert
+			tc.expectFunc(w, userRepo)
+		})
+	}
+}
@@ -1,11 +1,8 @@
 package api
 
 import (
-	"context"
-
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
-	"github.com/streadway/amqp"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/api/handlers"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/config"
 	paymentGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/payment/grpc"
@@ -13,8 +10,9 @@ import (
 	userGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/user/grpc"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/events"
 	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
-	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/idempotency"
 	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/gorm"
 )
@@ -23,8 +21,6 @@ func SetupRoutes(
 	routes *gin.Engine,
 	db *gorm.DB,
 	cfg *config.Config,
-	rabbitCfg *rabbitmq.RabbitMQConfig,
-	rabbitConn *amqp.Connection,
 	log zerolog.Logger,
 ) {
 
@@ -32,29 +28,53 @@ func SetupRoutes(
 	userGateway := userGrpc.New(cfg.UserServer.Host, cfg.UserServer.Port)
 	paymentGateway := paymentGrpc.New(cfg.PaymentServer.Host, cfg.PaymentServer.Port)
 	productGateway := productGrpc.New(cfg.ProductServer.Host, cfg.ProductServer.Port)
-	createOrderPublisher := rabbitmq.NewPublisher(
-		context.Background(),
-		rabbitCfg,
-		rabbitConn,
-		log,
-		rabbitmq.E_COM_EXCHANGE,
-		"direct",
-		rabbitmq.PAYMENT_ORDER_COMPLETED_QUEUE,
-	)
-	userService := services.NewOrderService(userRepo, userGateway, paymentGateway, createOrderPublisher, productGateway)
+	var orderProcessor services.IOrderSaga
+	if cfg.ProcessingMode == events.ProcessingModeAsync {
+		orderProcessor = services.NewOrderChoreography(userRepo, paymentGateway, productGateway)
+	} else {
+		sagaRepo := repository.NewSagaRepository(db)
+		orderProcessor = services.NewOrderSaga(userRepo, sagaRepo, paymentGateway, productGateway)
+	}
+	userService := services.NewOrderService(userRepo, userGateway, productGateway, orderProcessor)
 	userHandler := handlers.NewOrderHandler(userService)
+	cartRepo := repository.NewCartRepository(db)
+	cartService := services.NewCartService(cartRepo, productGateway, userService, log)
+	cartHandler := handlers.NewCartHandler(cartService)
 
 	userGroup := routes.Group("orders")
-	tokenMaker, err := token.NewJWTMaker(cfg.Auth.AccessTokenSecret)
+	tokenMaker, err := token.NewAccessTokenVerifier(token.VerifierConfig{
+		Format:       cfg.Auth.AccessTokenFormat,
+		Secret:       cfg.Auth.AccessTokenSecret,
+		JWKSURL:      cfg.Auth.JWKSURL,
+		JWKSCacheTTL: cfg.Auth.JWKSCacheTTL,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("Cannot create token maker")
 		return
 	}
-	authRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, []string{}))
+	revocationStore := token.NewGormRevocationStore(db)
+	idempotencyStore := idempotency.NewGormStore(db)
+	idempotencyMiddleware := middleware.IdempotencyMiddleware(idempotencyStore, cfg.Idempotency.KeyTTL)
+	authRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{}))
 	{
-		authRoutes.POST("", userHandler.CreateOrder)
+		authRoutes.POST("", idempotencyMiddleware, userHandler.CreateOrder)
 		authRoutes.GET("/:id", userHandler.ReadOrder)
 		authRoutes.GET("", userHandler.ListOrders)
 		authRoutes.PUT("/:id", userHandler.UpdateOrder)
+		authRoutes.GET("/:id/history", userHandler.ListOrderStatusHistory)
+		authRoutes.POST("/:id/cancel", userHandler.CancelOrder)
+	}
+	adminRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{"admin"}))
+	{
+		adminRoutes.PUT("/:id/status", userHandler.UpdateOrderStatus)
+	}
+
+	cartRoutes := routes.Group("carts").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{}))
+	{
+		cartRoutes.GET("", cartHandler.GetCart)
+		cartRoutes.POST("/items", cartHandler.AddCartItem)
+		cartRoutes.PUT("/items/:product_id", cartHandler.UpdateCartItem)
+		cartRoutes.DELETE("/items/:product_id", cartHandler.RemoveCartItem)
+		cartRoutes.POST("/checkout", idempotencyMiddleware, cartHandler.Checkout)
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"github.com/tricong1998/go-ecom/pkg/util"
 )
 
@@ -21,11 +23,21 @@ type ServerConfig struct {
 	Port string
 }
 
+// AuthConfig verifies access tokens with the public keys published at
+// JWKSURL by the user service, or with AccessTokenSecret when it is empty.
+// AccessTokenFormat has to match the format of the user service.
 type AuthConfig struct {
+	AccessTokenFormat    string
 	AccessTokenDuration  time.Duration
 	AccessTokenSecret    string
 	RefreshTokenSecret   string
 	RefreshTokenDuration time.Duration
+	JWKSURL              string
+	JWKSCacheTTL         time.Duration
+}
+
+type IdempotencyConfig struct {
+	KeyTTL time.Duration
 }
 
 type Config struct {
@@ -36,6 +48,10 @@ type Config struct {
 	DB             DBConfig
 	RabbitMQConfig RabbitMQConfig
 	Auth           AuthConfig
+	Idempotency    IdempotencyConfig
+	// ProcessingMode is either events.ProcessingModeSync or
+	// events.ProcessingModeAsync.
+	ProcessingMode string
 }
 
 type RabbitMQConfig struct {
@@ -53,6 +69,7 @@ func Load() (*Config, error) {
 	}
 
 	config := &Config{
+		ProcessingMode: os.Getenv("ORDER_PROCESSING_MODE"),
 		Server: ServerConfig{
 			Port: os.Getenv("ORDER_SERVER_PORT"),
 			Host: os.Getenv("ORDER_SERVER_HOST"),
@@ -83,11 +100,25 @@ func Load() (*Config, error) {
 			Password: os.Getenv("AMQP_SERVER_PASSWORD"),
 		},
 		Auth: AuthConfig{
+			AccessTokenFormat:    os.Getenv("ACCESS_TOKEN_FORMAT"),
 			AccessTokenSecret:    os.Getenv("ACCESS_TOKEN_SECRET"),
 			RefreshTokenSecret:   os.Getenv("REFRESH_TOKEN_SECRET"),
 			AccessTokenDuration:  util.ParseDuration(os.Getenv("ACCESS_TOKEN_DURATION"), 15*time.Minute),
 			RefreshTokenDuration: util.ParseDuration(os.Getenv("REFRESH_TOKEN_DURATION"), 24*time.Hour),
+			JWKSURL:              os.Getenv("JWKS_URL"),
+			JWKSCacheTTL:         util.ParseDuration(os.Getenv("JWKS_CACHE_TTL"), 10*time.Minute),
 		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL: util.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"), 24*time.Hour),
+		},
+	}
+
+	if config.ProcessingMode == "" {
+		config.ProcessingMode = events.ProcessingModeSync
+	}
+
+	if config.Auth.AccessTokenFormat == "" {
+		config.Auth.AccessTokenFormat = token.FormatJWT
 	}
 
 	if config.Server.Port == "" {
@@ -5,6 +5,10 @@ import (
 
 	"github.com/tricong1998/go-ecom/cmd/order/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/pkg/idempotency"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -32,8 +36,97 @@ func Initialize(dbConfig *config.DBConfig) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.Order{},
+		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.OrderSaga{},
+		&rabbitmq.OutboxMessage{},
+		&idempotency.Record{},
+		&token.RevokedToken{},
+		&token.RevokedUserTokens{},
 		// Add other models here as needed
 	)
+	if err != nil {
+		return err
+	}
+
+	err = migrateMoneyColumns(db)
+	if err != nil {
+		return err
+	}
+
+	return backfillOrderItems(db)
+}
+
+// migrateMoneyColumns moves the amounts that used to be plain integers to
+// their money columns.
+func migrateMoneyColumns(db *gorm.DB) error {
+	columns := []struct {
+		model          interface{}
+		column         string
+		currencyColumn string
+		prefix         string
+	}{
+		{&models.Order{}, "amount", "currency", "amount_"},
+		{&models.OrderItem{}, "unit_price", "currency", "unit_price_"},
+		{&models.OrderItem{}, "discount", "currency", "discount_"},
+		{&models.OrderItem{}, "line_total", "currency", "line_total_"},
+		{&models.CartItem{}, "unit_price", "", "unit_price_"},
+	}
+	for _, c := range columns {
+		err := money.MigrateColumn(db, c.model, c.column, c.currencyColumn, c.prefix)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillOrderItems moves the product and count of orders placed before
+// orders had items to an order item, then drops them from orders. It runs
+// after the money columns were migrated, and does nothing once the product
+// column of orders is gone.
+func backfillOrderItems(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.Order{}, "product_id") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// The amount of a legacy order is the total of its only line. Orders
+		// that already have items are left alone.
+		err := tx.Exec(`
+			INSERT INTO order_items (
+				created_at, updated_at, order_id, product_id, product_name, quantity,
+				unit_price_minor, unit_price_currency,
+				discount_minor, discount_currency,
+				line_total_minor, line_total_currency
+			)
+			SELECT
+				o.created_at, o.updated_at, o.id, o.product_id, '', COALESCE(o.product_count, 0),
+				COALESCE(o.amount_minor / NULLIF(o.product_count, 0), 0), o.amount_currency,
+				0, o.amount_currency,
+				o.amount_minor, o.amount_currency
+			FROM orders o
+			WHERE COALESCE(o.product_id, 0) <> 0
+				AND NOT EXISTS (SELECT 1 FROM order_items i WHERE i.order_id = o.id)
+		`).Error
+		if err != nil {
+			return err
+		}
+
+		for _, column := range []string{"product_id", "product_count"} {
+			if !tx.Migrator().HasColumn(&models.Order{}, column) {
+				continue
+			}
+			err = tx.Migrator().DropColumn(&models.Order{}, column)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the database of ORDER_TEST_DATABASE_DSN, in a schema
+// of its own that is dropped when the test ends.
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("ORDER_TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("ORDER_TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// The search path is set on the connection, so there must only be one.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		db.Exec("DROP SCHEMA IF EXISTS order_migrate_test CASCADE")
+		sqlDB.Close()
+	})
+
+	require.NoError(t, db.Exec("DROP SCHEMA IF EXISTS order_migrate_test CASCADE").Error)
+	require.NoError(t, db.Exec("CREATE SCHEMA order_migrate_test").Error)
+	require.NoError(t, db.Exec("SET search_path TO order_migrate_test").Error)
+	return db
+}
+
+func TestMigrateBackfillsOrderItems(t *testing.T) {
+	db := openTestDB(t)
+	// Orders as they were stored before they had items.
+	require.NoError(t, db.Exec(`
+		CREATE TABLE orders (
+			id bigserial PRIMARY KEY,
+			created_at timestamptz,
+			updated_at timestamptz,
+			deleted_at timestamptz,
+			status text,
+			product_id bigint,
+			user_id bigint,
+			username text,
+			product_count bigint,
+			amount bigint
+		)
+	`).Error)
+	require.NoError(t, db.Exec(`
+		INSERT INTO orders (created_at, updated_at, status, product_id, user_id, username, product_count, amount)
+		VALUES (now(), now(), 'success', 7, 1, 'username', 2, 500),
+			(now(), now(), 'success', 8, 1, 'username', 1, 300)
+	`).Error)
+
+	// Migrating again must not copy the orders twice.
+	require.NoError(t, Migrate(db))
+	require.NoError(t, Migrate(db))
+
+	assert.False(t, db.Migrator().HasColumn(&models.Order{}, "product_id"))
+	assert.False(t, db.Migrator().HasColumn(&models.Order{}, "product_count"))
+	var items []models.OrderItem
+	require.NoError(t, db.Order("order_id").Find(&items).Error)
+	require.Len(t, items, 2)
+	assert.Equal(t, uint(1), items[0].OrderID)
+	assert.Equal(t, uint(7), items[0].ProductId)
+	assert.Equal(t, uint(2), items[0].Quantity)
+	assert.Equal(t, money.New(250, money.DefaultCurrency), items[0].UnitPrice)
+	assert.Equal(t, money.New(500, money.DefaultCurrency), items[0].LineTotal)
+	assert.Equal(t, money.Zero(money.DefaultCurrency), items[0].Discount)
+	assert.Equal(t, uint(2), items[1].OrderID)
+	assert.Equal(t, uint(8), items[1].ProductId)
+	assert.Equal(t, money.New(300, money.DefaultCurrency), items[1].LineTotal)
+}
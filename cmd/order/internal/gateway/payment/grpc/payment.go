@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/money"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -13,6 +14,11 @@ import (
 type IPaymentGateway interface {
 	Get(ctx context.Context, paymentId uint) (*pb.Payment, error)
 	Create(ctx context.Context, payment *pb.CreatePaymentRequest) (*pb.CreatePaymentResponse, error)
+	Refund(ctx context.Context, paymentId uint) (*pb.RefundPaymentResponse, error)
+	Authorize(ctx context.Context, payment *pb.AuthorizePaymentRequest) (*pb.AuthorizePaymentResponse, error)
+	Capture(ctx context.Context, paymentId uint) (*pb.CapturePaymentResponse, error)
+	Void(ctx context.Context, paymentId uint) (*pb.VoidPaymentResponse, error)
+	ValidateMethod(ctx context.Context, method string, amount money.Money) (*pb.PaymentMethod, error)
 }
 
 type PaymentGateway struct {
@@ -64,3 +70,98 @@ func (g *PaymentGateway) Create(ctx context.Context, payment *pb.CreatePaymentRe
 	}
 	return resp, nil
 }
+
+func (g *PaymentGateway) Refund(ctx context.Context, paymentId uint) (*pb.RefundPaymentResponse, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewPaymentGrpcClient(conn)
+	resp, err := client.RefundPayment(ctx, &pb.RefundPaymentRequest{PaymentId: (uint64)(paymentId)})
+	if err != nil {
+		log.Println("Error refunding payment:", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (g *PaymentGateway) Authorize(ctx context.Context, payment *pb.AuthorizePaymentRequest) (*pb.AuthorizePaymentResponse, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewPaymentGrpcClient(conn)
+	resp, err := client.AuthorizePayment(ctx, payment)
+	if err != nil {
+		log.Println("Error authorizing payment:", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (g *PaymentGateway) Capture(ctx context.Context, paymentId uint) (*pb.CapturePaymentResponse, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewPaymentGrpcClient(conn)
+	resp, err := client.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: (uint64)(paymentId)})
+	if err != nil {
+		log.Println("Error capturing payment:", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (g *PaymentGateway) Void(ctx context.Context, paymentId uint) (*pb.VoidPaymentResponse, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewPaymentGrpcClient(conn)
+	resp, err := client.VoidPayment(ctx, &pb.VoidPaymentRequest{PaymentId: (uint64)(paymentId)})
+	if err != nil {
+		log.Println("Error voiding payment:", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ValidateMethod checks that method can pay amount. The payment service
+// answers InvalidArgument when it cannot.
+func (g *PaymentGateway) ValidateMethod(ctx context.Context, method string, amount money.Money) (*pb.PaymentMethod, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewPaymentGrpcClient(conn)
+	resp, err := client.ValidatePaymentMethod(ctx, &pb.ValidatePaymentMethodRequest{
+		Method: method,
+		Amount: money.ToProto(amount),
+	})
+	if err != nil {
+		log.Println("Error validating payment method:", err)
+		return nil, err
+	}
+	return resp.GetMethod(), nil
+}
@@ -12,7 +12,11 @@ import (
 
 type IProductGateway interface {
 	Get(ctx context.Context, productId uint) (*pb.ReadProductResponse, error)
-	UpdateProductQuantity(ctx context.Context, productId uint, quantity uint) (bool, error)
+	UpdateProductQuantity(ctx context.Context, items []*pb.ProductQuantity) (bool, error)
+	RestoreProductQuantity(ctx context.Context, items []*pb.ProductQuantity) (bool, error)
+	ReserveStock(ctx context.Context, reference string, items []*pb.ProductQuantity) (*pb.Reservation, error)
+	CommitReservation(ctx context.Context, reservationId uint) (*pb.Reservation, error)
+	ReleaseReservation(ctx context.Context, reservationId uint) (*pb.Reservation, error)
 }
 
 type ProductGateway struct {
@@ -42,7 +46,7 @@ func (g *ProductGateway) Get(ctx context.Context, productId uint) (*pb.ReadProdu
 	return resp, nil
 }
 
-func (g *ProductGateway) UpdateProductQuantity(ctx context.Context, productId uint, quantity uint) (bool, error) {
+func (g *ProductGateway) UpdateProductQuantity(ctx context.Context, items []*pb.ProductQuantity) (bool, error) {
 	address := fmt.Sprintf("%s:%s", g.host, g.port)
 
 	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -52,10 +56,82 @@ func (g *ProductGateway) UpdateProductQuantity(ctx context.Context, productId ui
 	defer conn.Close()
 
 	client := pb.NewProductGrpcClient(conn)
-	resp, err := client.UpdateProductQuantity(ctx, &pb.UpdateProductQuantityRequest{ProductId: (uint64)(productId), Quantity: (uint64)(quantity)})
+	resp, err := client.UpdateProductQuantity(ctx, &pb.UpdateProductQuantityRequest{Items: items})
 	if err != nil {
 		log.Println("Error updating product quantity:", err)
 		return false, err
 	}
 	return resp.Success, nil
 }
+
+func (g *ProductGateway) RestoreProductQuantity(ctx context.Context, items []*pb.ProductQuantity) (bool, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	client := pb.NewProductGrpcClient(conn)
+	resp, err := client.RestoreProductQuantity(ctx, &pb.RestoreProductQuantityRequest{Items: items})
+	if err != nil {
+		log.Println("Error restoring product quantity:", err)
+		return false, err
+	}
+	return resp.Success, nil
+}
+
+func (g *ProductGateway) ReserveStock(ctx context.Context, reference string, items []*pb.ProductQuantity) (*pb.Reservation, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewProductGrpcClient(conn)
+	resp, err := client.ReserveStock(ctx, &pb.ReserveStockRequest{Reference: reference, Items: items})
+	if err != nil {
+		log.Println("Error reserving stock:", err)
+		return nil, err
+	}
+	return resp.Reservation, nil
+}
+
+func (g *ProductGateway) CommitReservation(ctx context.Context, reservationId uint) (*pb.Reservation, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewProductGrpcClient(conn)
+	resp, err := client.CommitReservation(ctx, &pb.CommitReservationRequest{ReservationId: (uint64)(reservationId)})
+	if err != nil {
+		log.Println("Error committing reservation:", err)
+		return nil, err
+	}
+	return resp.Reservation, nil
+}
+
+func (g *ProductGateway) ReleaseReservation(ctx context.Context, reservationId uint) (*pb.Reservation, error) {
+	address := fmt.Sprintf("%s:%s", g.host, g.port)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := pb.NewProductGrpcClient(conn)
+	resp, err := client.ReleaseReservation(ctx, &pb.ReleaseReservationRequest{ReservationId: (uint64)(reservationId)})
+	if err != nil {
+		log.Println("Error releasing reservation:", err)
+		return nil, err
+	}
+	return resp.Reservation, nil
+}
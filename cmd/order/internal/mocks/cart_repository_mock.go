@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+)
+
+type MockCartRepository struct {
+	mock.Mock
+}
+
+func (m *MockCartRepository) GetOrCreateCart(userId uint) (*models.Cart, error) {
+	args := m.Called(userId)
+	return args.Get(0).(*models.Cart), args.Error(1)
+}
+
+func (m *MockCartRepository) SaveCartItem(input *models.CartItem) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockCartRepository) DeleteCartItem(cartId, productId uint) error {
+	args := m.Called(cartId, productId)
+	return args.Error(0)
+}
+
+func (m *MockCartRepository) ClearCart(cartId uint) error {
+	args := m.Called(cartId)
+	return args.Error(0)
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"github.com/stretchr/testify/mock"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
 )
 
 type MockOrderRepository struct {
@@ -37,7 +38,22 @@ func (m *MockOrderRepository) DeleteOrder(id uint) error {
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) UpdateOrderStatus(orderId uint, status string) error {
-	args := m.Called(orderId, status)
+func (m *MockOrderRepository) TransitionOrderStatus(orderId uint, status, actor, reason string, events ...repository.OrderEvent) error {
+	args := m.Called(orderId, status, actor, reason, events)
+	return args.Error(0)
+}
+
+func (m *MockOrderRepository) ListOrderStatusHistory(orderId uint) ([]models.OrderStatusHistory, error) {
+	args := m.Called(orderId)
+	return args.Get(0).([]models.OrderStatusHistory), args.Error(1)
+}
+
+func (m *MockOrderRepository) ListOrdersByStatus(status string) ([]models.Order, error) {
+	args := m.Called(status)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) WriteOrderEvents(events ...repository.OrderEvent) error {
+	args := m.Called(events)
 	return args.Error(0)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
 type MockPaymentGateway struct {
@@ -20,3 +21,28 @@ func (m *MockPaymentGateway) Create(ctx context.Context, payment *pb.CreatePayme
 	args := m.Called(ctx, payment)
 	return args.Get(0).(*pb.CreatePaymentResponse), args.Error(1)
 }
+
+func (m *MockPaymentGateway) Refund(ctx context.Context, paymentId uint) (*pb.RefundPaymentResponse, error) {
+	args := m.Called(ctx, paymentId)
+	return args.Get(0).(*pb.RefundPaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentGateway) Authorize(ctx context.Context, payment *pb.AuthorizePaymentRequest) (*pb.AuthorizePaymentResponse, error) {
+	args := m.Called(ctx, payment)
+	return args.Get(0).(*pb.AuthorizePaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentGateway) Capture(ctx context.Context, paymentId uint) (*pb.CapturePaymentResponse, error) {
+	args := m.Called(ctx, paymentId)
+	return args.Get(0).(*pb.CapturePaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentGateway) Void(ctx context.Context, paymentId uint) (*pb.VoidPaymentResponse, error) {
+	args := m.Called(ctx, paymentId)
+	return args.Get(0).(*pb.VoidPaymentResponse), args.Error(1)
+}
+
+func (m *MockPaymentGateway) ValidateMethod(ctx context.Context, method string, amount money.Money) (*pb.PaymentMethod, error) {
+	args := m.Called(ctx, method, amount)
+	return args.Get(0).(*pb.PaymentMethod), args.Error(1)
+}
@@ -16,7 +16,27 @@ func (m *MockProductGateway) Get(ctx context.Context, productId uint) (*pb.ReadP
 	return args.Get(0).(*pb.ReadProductResponse), args.Error(1)
 }
 
-func (m *MockProductGateway) UpdateProductQuantity(ctx context.Context, productId uint, quantity uint) (bool, error) {
-	args := m.Called(ctx, productId, quantity)
+func (m *MockProductGateway) UpdateProductQuantity(ctx context.Context, items []*pb.ProductQuantity) (bool, error) {
+	args := m.Called(ctx, items)
 	return args.Bool(0), args.Error(1)
 }
+
+func (m *MockProductGateway) RestoreProductQuantity(ctx context.Context, items []*pb.ProductQuantity) (bool, error) {
+	args := m.Called(ctx, items)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProductGateway) ReserveStock(ctx context.Context, reference string, items []*pb.ProductQuantity) (*pb.Reservation, error) {
+	args := m.Called(ctx, reference, items)
+	return args.Get(0).(*pb.Reservation), args.Error(1)
+}
+
+func (m *MockProductGateway) CommitReservation(ctx context.Context, reservationId uint) (*pb.Reservation, error) {
+	args := m.Called(ctx, reservationId)
+	return args.Get(0).(*pb.Reservation), args.Error(1)
+}
+
+func (m *MockProductGateway) ReleaseReservation(ctx context.Context, reservationId uint) (*pb.Reservation, error) {
+	args := m.Called(ctx, reservationId)
+	return args.Get(0).(*pb.Reservation), args.Error(1)
+}
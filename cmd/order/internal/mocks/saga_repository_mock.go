@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+)
+
+type MockSagaRepository struct {
+	mock.Mock
+}
+
+func (m *MockSagaRepository) CreateSaga(saga *models.OrderSaga) error {
+	args := m.Called(saga)
+	return args.Error(0)
+}
+
+func (m *MockSagaRepository) UpdateSaga(saga *models.OrderSaga) error {
+	args := m.Called(saga)
+	return args.Error(0)
+}
+
+func (m *MockSagaRepository) ReadSagaByOrderId(orderId uint) (*models.OrderSaga, error) {
+	args := m.Called(orderId)
+	return args.Get(0).(*models.OrderSaga), args.Error(1)
+}
+
+func (m *MockSagaRepository) ListSagasByStatus(statuses ...string) ([]models.OrderSaga, error) {
+	args := m.Called(statuses)
+	return args.Get(0).([]models.OrderSaga), args.Error(1)
+}
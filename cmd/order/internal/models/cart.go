@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
+
+type Cart struct {
+	gorm.Model
+	UserId uint       `json:"user_id" gorm:"uniqueIndex"`
+	Items  []CartItem `json:"items"`
+}
+
+type CartItem struct {
+	gorm.Model
+	CartID    uint        `json:"cart_id" gorm:"index"`
+	ProductId uint        `json:"product_id"`
+	Quantity  uint        `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+}
+
+func (item *CartItem) LineTotal() (money.Money, error) {
+	return item.UnitPrice.Mul(int64(item.Quantity))
+}
+
+// Total sums the lines of the cart. It fails when the lines are in different
+// currencies.
+func (c *Cart) Total() (money.Money, error) {
+	var total money.Money
+	for i := range c.Items {
+		lineTotal, err := c.Items[i].LineTotal()
+		if err != nil {
+			return money.Money{}, err
+		}
+		total, err = total.Add(lineTotal)
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
@@ -1,13 +1,18 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
 
 type Order struct {
 	gorm.Model
-	Status       string `json:"status"`
-	ProductId    uint   `json:"product_id"`
-	UserId       uint   `json:"user_id"`
-	Username     string `json:"username"`
-	ProductCount uint   `json:"product_count"`
-	Amount       uint   `json:"amount"`
+	Status        string      `json:"status"`
+	UserId        uint        `json:"user_id"`
+	Username      string      `json:"username"`
+	Amount        money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	PaymentMethod string      `json:"payment_method"`
+	PaymentId     uint        `json:"payment_id"`
+	ReservationId uint        `json:"reservation_id"`
+	Items         []OrderItem `json:"items"`
 }
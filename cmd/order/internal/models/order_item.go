@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
+
+// OrderItem is a line of an order. The product name, price, currency and
+// discount are copied from the catalog when the order is placed, so the order
+// keeps its meaning when the product changes or is deleted.
+type OrderItem struct {
+	gorm.Model
+	OrderID     uint        `json:"order_id" gorm:"index"`
+	ProductId   uint        `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	Quantity    uint        `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	// Discount is the amount taken off every unit.
+	Discount  money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	LineTotal money.Money `json:"line_total" gorm:"embedded;embeddedPrefix:line_total_"`
+}
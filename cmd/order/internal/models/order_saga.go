@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// OrderSaga is the persisted state of the create order saga, so that an
+// interrupted saga can be resumed after the order service restarts.
+type OrderSaga struct {
+	gorm.Model
+	OrderID uint   `json:"order_id" gorm:"uniqueIndex"`
+	Step    string `json:"step"`
+	Status  string `json:"status"`
+	Error   string `json:"error"`
+	// Reason is the reason given when the order was cancelled.
+	Reason string `json:"reason"`
+}
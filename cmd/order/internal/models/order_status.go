@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	OrderStatusCreated         = "created"
+	OrderStatusAwaitingPayment = "awaiting_payment"
+	OrderStatusPaid            = "paid"
+	OrderStatusFulfilling      = "fulfilling"
+	OrderStatusShipped         = "shipped"
+	OrderStatusDelivered       = "delivered"
+	OrderStatusCancelled       = "cancelled"
+	OrderStatusRefunded        = "refunded"
+	OrderStatusFailed          = "failed"
+)
+
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderTransitions lists, for every order status, the statuses the order is
+// allowed to move to. Statuses without an entry are terminal.
+var orderTransitions = map[string][]string{
+	OrderStatusCreated:         {OrderStatusAwaitingPayment, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusAwaitingPayment: {OrderStatusPaid, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusPaid:            {OrderStatusFulfilling, OrderStatusCancelled, OrderStatusFailed},
+	OrderStatusFulfilling:      {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:         {OrderStatusDelivered, OrderStatusCancelled},
+	OrderStatusDelivered:       {OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusCancelled:       {OrderStatusRefunded},
+}
+
+// ValidateOrderTransition returns ErrInvalidOrderTransition when an order in
+// status from is not allowed to move to status to.
+func ValidateOrderTransition(from, to string) error {
+	for _, v := range orderTransitions[from] {
+		if v == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrInvalidOrderTransition, from, to)
+}
+
+// OrderStatusHistory records a single transition of an order, who made it and
+// why.
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	OrderID    uint      `json:"order_id" gorm:"index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
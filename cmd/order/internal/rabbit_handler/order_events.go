@@ -0,0 +1,106 @@
+package rabbit_handler
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/events"
+)
+
+type OrderEventDependencies struct {
+	OrderChoreography *services.OrderChoreography
+	Logger            zerolog.Logger
+}
+
+func StockReserved(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockReserved events.StockReserved
+
+	err := json.Unmarshal(msg.Body, &stockReserved)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.StockReserved(stockReserved)
+}
+
+func StockReservationFailed(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockReservationFailed events.StockReservationFailed
+
+	err := json.Unmarshal(msg.Body, &stockReservationFailed)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.StockReservationFailed(stockReservationFailed)
+}
+
+func PaymentSucceeded(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var paymentSucceeded events.PaymentSucceeded
+
+	err := json.Unmarshal(msg.Body, &paymentSucceeded)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.PaymentSucceeded(paymentSucceeded)
+}
+
+func PaymentFailed(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var paymentFailed events.PaymentFailed
+
+	err := json.Unmarshal(msg.Body, &paymentFailed)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.PaymentFailed(paymentFailed)
+}
+
+func StockCommitted(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockCommitted events.StockCommitted
+
+	err := json.Unmarshal(msg.Body, &stockCommitted)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.StockCommitted(stockCommitted)
+}
+
+func StockCommitFailed(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockCommitFailed events.StockCommitFailed
+
+	err := json.Unmarshal(msg.Body, &stockCommitFailed)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.StockCommitFailed(stockCommitFailed)
+}
+
+func PaymentCaptured(queue string, msg amqp.Delivery, dependencies *OrderEventDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var paymentCaptured events.PaymentCaptured
+
+	err := json.Unmarshal(msg.Body, &paymentCaptured)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.OrderChoreography.PaymentCaptured(paymentCaptured)
+}
@@ -0,0 +1,28 @@
+package rabbit_handler
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/events"
+)
+
+type PaymentConfirmationDependencies struct {
+	PaymentConfirmationService *services.PaymentConfirmationService
+	Logger                     zerolog.Logger
+}
+
+func PaymentConfirmed(queue string, msg amqp.Delivery, dependencies *PaymentConfirmationDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var paymentConfirmed events.PaymentConfirmed
+
+	err := json.Unmarshal(msg.Body, &paymentConfirmed)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.PaymentConfirmationService.PaymentConfirmed(paymentConfirmed)
+}
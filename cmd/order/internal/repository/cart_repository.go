@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"gorm.io/gorm"
+)
+
+type CartRepository struct {
+	DB *gorm.DB
+}
+
+type ICartRepository interface {
+	GetOrCreateCart(userId uint) (*models.Cart, error)
+	SaveCartItem(input *models.CartItem) error
+	DeleteCartItem(cartId, productId uint) error
+	ClearCart(cartId uint) error
+}
+
+func NewCartRepository(db *gorm.DB) *CartRepository {
+	return &CartRepository{db}
+}
+
+// GetOrCreateCart returns the cart of the user with its items, creating an
+// empty cart the first time the user touches it.
+func (cartRepo *CartRepository) GetOrCreateCart(userId uint) (*models.Cart, error) {
+	var cart models.Cart
+	err := cartRepo.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("user_id = ?", userId).First(&cart).Error
+	if err == nil {
+		return &cart, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	cart = models.Cart{UserId: userId}
+	err = cartRepo.DB.Create(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (cartRepo *CartRepository) SaveCartItem(input *models.CartItem) error {
+	return cartRepo.DB.Save(input).Error
+}
+
+func (cartRepo *CartRepository) DeleteCartItem(cartId, productId uint) error {
+	result := cartRepo.DB.Unscoped().
+		Where("cart_id = ? AND product_id = ?", cartId, productId).
+		Delete(&models.CartItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (cartRepo *CartRepository) ClearCart(cartId uint) error {
+	return cartRepo.DB.Unscoped().Where("cart_id = ?", cartId).Delete(&models.CartItem{}).Error
+}
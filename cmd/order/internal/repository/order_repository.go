@@ -2,13 +2,22 @@ package repository
 
 import (
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository struct {
 	DB *gorm.DB
 }
 
+// OrderEvent is a message written to the outbox in the same transaction as an
+// order status transition.
+type OrderEvent struct {
+	RoutingKey string
+	Message    interface{}
+}
+
 type IOrderRepository interface {
 	CreateOrder(input *models.Order) error
 	ReadOrder(id uint) (*models.Order, error)
@@ -18,20 +27,37 @@ type IOrderRepository interface {
 	) ([]models.Order, int64, error)
 	UpdateOrder(input *models.Order) error
 	DeleteOrder(id uint) error
-	UpdateOrderStatus(orderId uint, status string) error
+	TransitionOrderStatus(orderId uint, status, actor, reason string, events ...OrderEvent) error
+	ListOrderStatusHistory(orderId uint) ([]models.OrderStatusHistory, error)
+	ListOrdersByStatus(status string) ([]models.Order, error)
+	WriteOrderEvents(events ...OrderEvent) error
 }
 
 func NewOrderRepository(db *gorm.DB) *OrderRepository {
 	return &OrderRepository{db}
 }
 
+// CreateOrder inserts the order with its items and records the initial status
+// in the status history.
 func (orderRepo *OrderRepository) CreateOrder(input *models.Order) error {
-	return orderRepo.DB.Create(input).Error
+	return orderRepo.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(input).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.OrderStatusHistory{
+			OrderID:  input.ID,
+			ToStatus: input.Status,
+			Actor:    "system",
+			Reason:   "order created",
+		}).Error
+	})
 }
 
 func (userRepo *OrderRepository) ReadOrder(id uint) (*models.Order, error) {
 	var user *models.Order
-	err := userRepo.DB.First(&user, id).Error
+	err := userRepo.DB.Preload("Items").First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -55,19 +81,96 @@ func (userRepo *OrderRepository) ListOrders(
 		return nil, 0, err
 	}
 
-	userRepo.DB.Where(query).Find(&users)
+	userRepo.DB.Preload("Items").Where(query).Find(&users)
 
 	return users, total, nil
 }
 
+// UpdateOrder updates the non-zero fields of the order. The status is never
+// written here, it only changes through TransitionOrderStatus.
 func (userRepo *OrderRepository) UpdateOrder(input *models.Order) error {
-	return userRepo.DB.Save(input).Error
+	return userRepo.DB.Omit("status", clause.Associations).Updates(input).Error
 }
 
 func (userRepo *OrderRepository) DeleteOrder(id uint) error {
 	return userRepo.DB.Delete(&models.Order{}, id).Error
 }
 
-func (userRepo *OrderRepository) UpdateOrderStatus(orderId uint, status string) error {
-	return userRepo.DB.Model(&models.Order{}).Where("id = ?", orderId).Update("status", status).Error
+// TransitionOrderStatus moves the order to status, appends the transition to
+// the status history and writes the events to the outbox, all in one
+// transaction. The order row is locked so concurrent transitions are checked
+// against the latest status. Moving an order to the status it already has is
+// a no-op.
+func (userRepo *OrderRepository) TransitionOrderStatus(orderId uint, status, actor, reason string, events ...OrderEvent) error {
+	return userRepo.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderId).Error
+		if err != nil {
+			return err
+		}
+		if order.Status == status {
+			return nil
+		}
+
+		err = models.ValidateOrderTransition(order.Status, status)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&order).Update("status", status).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&models.OrderStatusHistory{
+			OrderID:    orderId,
+			FromStatus: order.Status,
+			ToStatus:   status,
+			Actor:      actor,
+			Reason:     reason,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			err = rabbitmq.WriteOutbox(tx, event.RoutingKey, event.Message)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteOrderEvents writes events to the outbox that do not come with a status
+// transition.
+func (userRepo *OrderRepository) WriteOrderEvents(events ...OrderEvent) error {
+	return userRepo.DB.Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			err := rabbitmq.WriteOutbox(tx, event.RoutingKey, event.Message)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (userRepo *OrderRepository) ListOrdersByStatus(status string) ([]models.Order, error) {
+	var orders []models.Order
+	err := userRepo.DB.Preload("Items").Where("status = ?", status).Order("id").Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (userRepo *OrderRepository) ListOrderStatusHistory(orderId uint) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	err := userRepo.DB.Where("order_id = ?", orderId).Order("id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
 }
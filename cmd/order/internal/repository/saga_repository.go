@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"gorm.io/gorm"
+)
+
+type SagaRepository struct {
+	DB *gorm.DB
+}
+
+type ISagaRepository interface {
+	CreateSaga(input *models.OrderSaga) error
+	UpdateSaga(input *models.OrderSaga) error
+	ReadSagaByOrderId(orderId uint) (*models.OrderSaga, error)
+	ListSagasByStatus(statuses ...string) ([]models.OrderSaga, error)
+}
+
+func NewSagaRepository(db *gorm.DB) *SagaRepository {
+	return &SagaRepository{db}
+}
+
+func (sagaRepo *SagaRepository) CreateSaga(input *models.OrderSaga) error {
+	return sagaRepo.DB.Create(input).Error
+}
+
+func (sagaRepo *SagaRepository) UpdateSaga(input *models.OrderSaga) error {
+	return sagaRepo.DB.Save(input).Error
+}
+
+func (sagaRepo *SagaRepository) ReadSagaByOrderId(orderId uint) (*models.OrderSaga, error) {
+	var saga models.OrderSaga
+	err := sagaRepo.DB.Where("order_id = ?", orderId).First(&saga).Error
+	if err != nil {
+		return nil, err
+	}
+	return &saga, nil
+}
+
+func (sagaRepo *SagaRepository) ListSagasByStatus(statuses ...string) ([]models.OrderSaga, error) {
+	var sagas []models.OrderSaga
+	err := sagaRepo.DB.Where("status IN ?", statuses).Order("id").Find(&sagas).Error
+	if err != nil {
+		return nil, err
+	}
+	return sagas, nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	productGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/product/grpc"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
+	productPb "github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCartEmpty        = errors.New("cart is empty")
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrNotEnoughStock   = errors.New("product quantity is not enough")
+)
+
+type CartService struct {
+	CartRepo           repository.ICartRepository
+	ProductGrpcGateway productGrpc.IProductGateway
+	OrderService       IOrderService
+	Logger             zerolog.Logger
+}
+
+type ICartService interface {
+	GetCart(userId uint) (*models.Cart, error)
+	AddCartItem(userId, productId, quantity uint) (*models.Cart, error)
+	UpdateCartItem(userId, productId, quantity uint) (*models.Cart, error)
+	RemoveCartItem(userId, productId uint) (*models.Cart, error)
+	Checkout(userId uint, paymentMethod string) (*models.Order, error)
+}
+
+func NewCartService(
+	cartRepo repository.ICartRepository,
+	productGateway productGrpc.IProductGateway,
+	orderService IOrderService,
+	log zerolog.Logger,
+) *CartService {
+	return &CartService{cartRepo, productGateway, orderService, log}
+}
+
+// GetCart returns the cart of the user with the unit prices refreshed from the
+// product service.
+func (cs *CartService) GetCart(userId uint) (*models.Cart, error) {
+	cart, err := cs.CartRepo.GetOrCreateCart(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		product, err := cs.ProductGrpcGateway.Get(context.Background(), item.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		price := unitPrice(product.GetProduct())
+		if item.UnitPrice == price {
+			continue
+		}
+		item.UnitPrice = price
+		err = cs.CartRepo.SaveCartItem(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cart, nil
+}
+
+func (cs *CartService) AddCartItem(userId, productId, quantity uint) (*models.Cart, error) {
+	cart, err := cs.CartRepo.GetOrCreateCart(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	item := findCartItem(cart, productId)
+	if item == nil {
+		cart.Items = append(cart.Items, models.CartItem{CartID: cart.ID, ProductId: productId})
+		item = &cart.Items[len(cart.Items)-1]
+	}
+	item.Quantity += quantity
+
+	err = cs.validateCartItem(item)
+	if err != nil {
+		return nil, err
+	}
+	_, err = cart.Total()
+	if errors.Is(err, money.ErrCurrencyMismatch) {
+		return nil, ErrMixedCurrencies
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = cs.CartRepo.SaveCartItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (cs *CartService) UpdateCartItem(userId, productId, quantity uint) (*models.Cart, error) {
+	cart, err := cs.CartRepo.GetOrCreateCart(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	item := findCartItem(cart, productId)
+	if item == nil {
+		return nil, ErrCartItemNotFound
+	}
+	item.Quantity = quantity
+
+	err = cs.validateCartItem(item)
+	if err != nil {
+		return nil, err
+	}
+	err = cs.CartRepo.SaveCartItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (cs *CartService) RemoveCartItem(userId, productId uint) (*models.Cart, error) {
+	cart, err := cs.CartRepo.GetOrCreateCart(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cs.CartRepo.DeleteCartItem(cart.ID, productId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCartItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := cart.Items[:0]
+	for _, v := range cart.Items {
+		if v.ProductId != productId {
+			items = append(items, v)
+		}
+	}
+	cart.Items = items
+
+	return cart, nil
+}
+
+// Checkout re-validates every line of the cart against the product service and
+// turns it into an order paid with paymentMethod. The cart is emptied once the
+// order is placed; when the order fails the cart is kept so the user can try
+// again. Failing to empty the cart does not fail the checkout, since the order
+// is already placed and retrying would place it twice.
+func (cs *CartService) Checkout(userId uint, paymentMethod string) (*models.Order, error) {
+	cart, err := cs.CartRepo.GetOrCreateCart(userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	order := models.Order{UserId: userId, PaymentMethod: paymentMethod}
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		err = cs.validateCartItem(item)
+		if err != nil {
+			return nil, err
+		}
+		order.Items = append(order.Items, models.OrderItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	err = cs.OrderService.CreateOrder(&order)
+	if err != nil {
+		return &order, err
+	}
+
+	err = cs.CartRepo.ClearCart(cart.ID)
+	if err != nil {
+		cs.Logger.Error().Err(err).Msgf("Cannot clear cart %d after placing order %d", cart.ID, order.ID)
+	}
+
+	return &order, nil
+}
+
+// validateCartItem checks that the product exists and has enough stock for the
+// line, and refreshes the unit price of the line.
+func (cs *CartService) validateCartItem(item *models.CartItem) error {
+	product, err := cs.ProductGrpcGateway.Get(context.Background(), item.ProductId)
+	if err != nil {
+		return err
+	}
+	if uint(product.GetProduct().GetQuantity()) < item.Quantity {
+		return fmt.Errorf("product %d: %w", item.ProductId, ErrNotEnoughStock)
+	}
+	item.UnitPrice = unitPrice(product.GetProduct())
+	return nil
+}
+
+// unitPrice is the price of the product, in the default currency when the
+// product has none.
+func unitPrice(product *productPb.Product) money.Money {
+	price := money.FromProto(product.GetPrice())
+	if price.Currency == "" {
+		price.Currency = money.DefaultCurrency
+	}
+	return price
+}
+
+func findCartItem(cart *models.Cart, productId uint) *models.CartItem {
+	for i := range cart.Items {
+		if cart.Items[i].ProductId == productId {
+			return &cart.Items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+
+	paymentGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/payment/grpc"
+	productGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/product/grpc"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"gorm.io/gorm"
+)
+
+// OrderChoreography processes orders asynchronously. Start only publishes an
+// order created event; the product and payment services react to it and to
+// each other's events, and the order follows their outcome.
+type OrderChoreography struct {
+	OrderRepo repository.IOrderRepository
+	OrderSaga *OrderSaga
+}
+
+func NewOrderChoreography(
+	orderRepo repository.IOrderRepository,
+	paymentGateway paymentGrpc.IPaymentGateway,
+	productGateway productGrpc.IProductGateway,
+) *OrderChoreography {
+	return &OrderChoreography{orderRepo, NewOrderSaga(orderRepo, nil, paymentGateway, productGateway)}
+}
+
+func (c *OrderChoreography) ValidatePayment(order *models.Order) error {
+	return c.OrderSaga.ValidatePayment(order)
+}
+
+func (c *OrderChoreography) Start(order *models.Order) error {
+	return c.OrderRepo.WriteOrderEvents(orderCreatedEvent(order))
+}
+
+// Resume publishes the order created event again for the orders that have not
+// been picked up yet. The other services ignore the orders they already
+// handled.
+func (c *OrderChoreography) Resume() error {
+	orders, err := c.OrderRepo.ListOrdersByStatus(models.OrderStatusCreated)
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		err = c.Start(&orders[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cancel reverses a paid order over gRPC, like the saga does. Orders that are
+// still being processed cannot be cancelled.
+func (c *OrderChoreography) Cancel(order *models.Order, actor, reason string) error {
+	if order.Status == models.OrderStatusCreated || order.Status == models.OrderStatusAwaitingPayment {
+		return ErrOrderProcessing
+	}
+	return c.OrderSaga.reverse(order, actor, reason)
+}
+
+func (c *OrderChoreography) StockReserved(event events.StockReserved) error {
+	err := c.recordReferences(event.OrderId, 0, event.ReservationId)
+	if err != nil {
+		return err
+	}
+	return c.transition(event.OrderId, models.OrderStatusAwaitingPayment, "stock reserved")
+}
+
+func (c *OrderChoreography) StockReservationFailed(event events.StockReservationFailed) error {
+	return c.transition(event.OrderId, models.OrderStatusFailed, event.Reason)
+}
+
+func (c *OrderChoreography) PaymentSucceeded(event events.PaymentSucceeded) error {
+	return c.recordReferences(event.OrderId, event.PaymentId, event.ReservationId)
+}
+
+func (c *OrderChoreography) PaymentFailed(event events.PaymentFailed) error {
+	err := c.recordReferences(event.OrderId, event.PaymentId, event.ReservationId)
+	if err != nil {
+		return err
+	}
+	return c.transition(event.OrderId, models.OrderStatusFailed, event.Reason)
+}
+
+// StockCommitted records the references of the order, whose payment is
+// captured next. The events may arrive out of order, so the order first moves
+// to awaiting payment if the stock reserved event was not handled yet.
+func (c *OrderChoreography) StockCommitted(event events.StockCommitted) error {
+	err := c.recordReferences(event.OrderId, event.PaymentId, event.ReservationId)
+	if err != nil {
+		return err
+	}
+	return c.transition(event.OrderId, models.OrderStatusAwaitingPayment, "stock reserved")
+}
+
+// PaymentCaptured marks the order paid and writes the user point event to the
+// outbox.
+func (c *OrderChoreography) PaymentCaptured(event events.PaymentCaptured) error {
+	err := c.recordReferences(event.OrderId, event.PaymentId, event.ReservationId)
+	if err != nil {
+		return err
+	}
+
+	order, err := c.OrderRepo.ReadOrder(event.OrderId)
+	if err != nil {
+		return err
+	}
+	if order.Status == models.OrderStatusCreated {
+		err = c.transition(order.ID, models.OrderStatusAwaitingPayment, "stock reserved")
+		if err != nil {
+			return err
+		}
+	}
+
+	createUserPoint := repository.OrderEvent{
+		RoutingKey: rabbitmq.PAYMENT_ORDER_COMPLETED_ROUTING_KEY,
+		Message: dto.CreateUserPoint{
+			OrderId: order.ID,
+			UserId:  uint(order.UserId),
+			Amount:  order.Amount,
+		},
+	}
+	return c.transition(order.ID, models.OrderStatusPaid, "order completed", createUserPoint)
+}
+
+func (c *OrderChoreography) StockCommitFailed(event events.StockCommitFailed) error {
+	return c.transition(event.OrderId, models.OrderStatusFailed, event.Reason)
+}
+
+// recordReferences stores the payment and reservation ids carried by an
+// event. Zero ids are left untouched.
+func (c *OrderChoreography) recordReferences(orderId, paymentId, reservationId uint) error {
+	return c.OrderRepo.UpdateOrder(&models.Order{
+		Model:         gorm.Model{ID: orderId},
+		PaymentId:     paymentId,
+		ReservationId: reservationId,
+	})
+}
+
+// transition ignores transitions the order has already moved past, which
+// happens when an event is delivered again.
+func (c *OrderChoreography) transition(orderId uint, status, reason string, orderEvents ...repository.OrderEvent) error {
+	err := c.OrderRepo.TransitionOrderStatus(orderId, status, ActorSystem, reason, orderEvents...)
+	if errors.Is(err, models.ErrInvalidOrderTransition) {
+		return nil
+	}
+	return err
+}
+
+func orderCreatedEvent(order *models.Order) repository.OrderEvent {
+	items := make([]events.OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, events.OrderItem{ProductId: item.ProductId, Quantity: item.Quantity})
+	}
+	return repository.OrderEvent{
+		RoutingKey: rabbitmq.ORDER_CREATED_ROUTING_KEY,
+		Message: events.OrderCreated{
+			OrderId:       order.ID,
+			UserId:        order.UserId,
+			Amount:        order.Amount,
+			PaymentMethod: order.PaymentMethod,
+			Items:         items,
+		},
+	}
+}
@@ -0,0 +1,519 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	paymentGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/payment/grpc"
+	productGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/product/grpc"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
+	productPb "github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
+	"github.com/tricong1998/go-ecom/pkg/idempotency"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	SagaStepReserveStock = "reserve_stock"
+	// SagaStepCreatePayment only authorizes the payment.
+	SagaStepCreatePayment  = "create_payment"
+	SagaStepCommitStock    = "commit_stock"
+	SagaStepCapturePayment = "capture_payment"
+	SagaStepCompleteOrder  = "complete_order"
+)
+
+const (
+	SagaStatusRunning = "running"
+	// SagaStatusAwaitingPayment parks a saga whose payment the provider has
+	// not confirmed yet, until the payment confirmed event arrives.
+	SagaStatusAwaitingPayment = "awaiting_payment"
+	SagaStatusCompensating    = "compensating"
+	SagaStatusCompleted       = "completed"
+	SagaStatusCompensated     = "compensated"
+	SagaStatusCancelling      = "cancelling"
+	SagaStatusCancelled       = "cancelled"
+)
+
+// sagaSteps is the order in which the create order saga executes its steps.
+// Compensations run in the reverse order. Stock is held before the payment is
+// authorized and only committed once it went through, and the payment is only
+// captured once the stock is committed.
+var sagaSteps = []string{
+	SagaStepReserveStock,
+	SagaStepCreatePayment,
+	SagaStepCommitStock,
+	SagaStepCapturePayment,
+	SagaStepCompleteOrder,
+}
+
+var (
+	ErrPaymentFailed   = errors.New("payment failed")
+	ErrOrderSagaFailed = errors.New("order failed")
+	ErrOrderProcessing = errors.New("order is still being processed")
+
+	errPaymentPending = errors.New("payment is pending")
+)
+
+type OrderSaga struct {
+	OrderRepo          repository.IOrderRepository
+	SagaRepo           repository.ISagaRepository
+	PaymentGrpcGateway paymentGrpc.IPaymentGateway
+	ProductGrpcGateway productGrpc.IProductGateway
+}
+
+type IOrderSaga interface {
+	ValidatePayment(order *models.Order) error
+	Start(order *models.Order) error
+	Resume() error
+	Cancel(order *models.Order, actor, reason string) error
+}
+
+func NewOrderSaga(
+	orderRepo repository.IOrderRepository,
+	sagaRepo repository.ISagaRepository,
+	paymentGateway paymentGrpc.IPaymentGateway,
+	productGateway productGrpc.IProductGateway,
+) *OrderSaga {
+	return &OrderSaga{orderRepo, sagaRepo, paymentGateway, productGateway}
+}
+
+// Start persists a new saga for the order and runs it to the end. When a step
+// fails the completed steps are compensated and ErrOrderSagaFailed is returned.
+func (s *OrderSaga) Start(order *models.Order) error {
+	saga := models.OrderSaga{
+		OrderID: order.ID,
+		Step:    sagaSteps[0],
+		Status:  SagaStatusRunning,
+	}
+	err := s.SagaRepo.CreateSaga(&saga)
+	if err != nil {
+		return err
+	}
+
+	return s.run(&saga, order)
+}
+
+// Resume continues every saga that was still running, compensating or
+// cancelling when the order service stopped.
+func (s *OrderSaga) Resume() error {
+	sagas, err := s.SagaRepo.ListSagasByStatus(SagaStatusRunning, SagaStatusCompensating, SagaStatusCancelling)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range sagas {
+		order, err := s.OrderRepo.ReadOrder(sagas[i].OrderID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if sagas[i].Status == SagaStatusCancelling {
+			err = s.cancel(&sagas[i], order, ActorSystem)
+		} else {
+			err = s.run(&sagas[i], order)
+		}
+		if err != nil && !errors.Is(err, ErrOrderSagaFailed) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// PaymentConfirmed continues the saga of the order once the provider confirmed
+// its pending payment, or compensates it when the payment failed. It reports
+// false when the order has no saga waiting for its payment.
+func (s *OrderSaga) PaymentConfirmed(order *models.Order, paymentStatus, reason string) (bool, error) {
+	saga, err := s.SagaRepo.ReadSagaByOrderId(order.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if saga.Status != SagaStatusAwaitingPayment {
+		return false, nil
+	}
+
+	switch paymentStatus {
+	case paymentStatusAuthorized, paymentStatusSuccess:
+		saga.Status = SagaStatusRunning
+		// Capturing again would only replay the pending answer stored under
+		// the idempotency key of the capture, so a settled capture is done.
+		if saga.Step == SagaStepCapturePayment && paymentStatus == paymentStatusSuccess {
+			saga.Step = SagaStepCompleteOrder
+		}
+	case paymentStatusFailed:
+		saga.Status = SagaStatusCompensating
+		saga.Error = fmt.Sprintf("%s: %s", ErrPaymentFailed, reason)
+	default:
+		return false, nil
+	}
+
+	err = s.run(saga, order)
+	if err != nil && !errors.Is(err, ErrOrderSagaFailed) {
+		return true, err
+	}
+	return true, nil
+}
+
+// Cancel reverses a completed order: the order moves to cancelled, the
+// payment is refunded, the stock restored and an order cancelled event is
+// written to the outbox so that the loyalty points granted for the order are
+// taken back.
+// The saga is marked cancelling before anything is undone, so a cancellation
+// that failed halfway can be retried and is resumed on restart. Orders whose
+// saga has not completed yet cannot be cancelled.
+func (s *OrderSaga) Cancel(order *models.Order, actor, reason string) error {
+	saga, err := s.SagaRepo.ReadSagaByOrderId(order.ID)
+	if err != nil {
+		return err
+	}
+
+	switch saga.Status {
+	case SagaStatusCompleted:
+		saga.Status = SagaStatusCancelling
+		saga.Reason = reason
+		err = s.SagaRepo.UpdateSaga(saga)
+		if err != nil {
+			return err
+		}
+	case SagaStatusCancelling:
+	default:
+		return ErrOrderProcessing
+	}
+
+	return s.cancel(saga, order, actor)
+}
+
+func (s *OrderSaga) cancel(saga *models.OrderSaga, order *models.Order, actor string) error {
+	err := s.reverse(order, actor, saga.Reason)
+	if err != nil {
+		return err
+	}
+
+	saga.Status = SagaStatusCancelled
+	return s.SagaRepo.UpdateSaga(saga)
+}
+
+// reverse cancels the order, refunds its payment, gives its stock back and
+// moves it to refunded. An order that is already cancelled is a cancellation
+// that stopped halfway; the refund and the stock release are idempotent, so
+// they are simply run again.
+func (s *OrderSaga) reverse(order *models.Order, actor, reason string) error {
+	if order.Status != models.OrderStatusCancelled {
+		err := s.OrderRepo.TransitionOrderStatus(order.ID, models.OrderStatusCancelled, actor, reason)
+		if err != nil {
+			return err
+		}
+		order.Status = models.OrderStatusCancelled
+	}
+
+	err := s.refundPayment(order)
+	if err != nil {
+		return err
+	}
+	err = s.restoreStock(order)
+	if err != nil {
+		return err
+	}
+
+	orderCancelled := repository.OrderEvent{
+		RoutingKey: rabbitmq.ORDER_CANCELLED_ROUTING_KEY,
+		Message: dto.OrderCancelled{
+			OrderId: order.ID,
+			UserId:  order.UserId,
+			Amount:  order.Amount,
+			Reason:  reason,
+		},
+	}
+	return s.transition(order, models.OrderStatusRefunded, "payment refunded", orderCancelled)
+}
+
+func (s *OrderSaga) run(saga *models.OrderSaga, order *models.Order) error {
+	var cause error
+	if saga.Status == SagaStatusRunning {
+		start, err := stepIndex(saga.Step)
+		if err != nil {
+			return err
+		}
+		for i := start; i < len(sagaSteps); i++ {
+			saga.Step = sagaSteps[i]
+			err := s.SagaRepo.UpdateSaga(saga)
+			if err != nil {
+				return err
+			}
+
+			err = s.execute(saga.Step, order)
+			if errors.Is(err, errPaymentPending) {
+				saga.Status = SagaStatusAwaitingPayment
+				return s.SagaRepo.UpdateSaga(saga)
+			}
+			if err != nil {
+				saga.Status = SagaStatusCompensating
+				saga.Error = err.Error()
+				cause = err
+				break
+			}
+		}
+
+		if saga.Status == SagaStatusRunning {
+			saga.Status = SagaStatusCompleted
+			return s.SagaRepo.UpdateSaga(saga)
+		}
+
+		err = s.SagaRepo.UpdateSaga(saga)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.compensate(saga, order, cause)
+}
+
+// compensate undoes, in reverse order, every step that completed before the
+// failed step recorded in saga.Step, then marks the order as failed. The
+// returned error wraps cause, the error of the failed step, when it is known.
+func (s *OrderSaga) compensate(saga *models.OrderSaga, order *models.Order, cause error) error {
+	failed, err := stepIndex(saga.Step)
+	if err != nil {
+		return err
+	}
+	for i := failed - 1; i >= 0; i-- {
+		err = s.undo(sagaSteps[i], order)
+		if err != nil {
+			return err
+		}
+
+		saga.Step = sagaSteps[i]
+		err = s.SagaRepo.UpdateSaga(saga)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.transition(order, models.OrderStatusFailed, saga.Error)
+	if err != nil {
+		return err
+	}
+
+	saga.Status = SagaStatusCompensated
+	err = s.SagaRepo.UpdateSaga(saga)
+	if err != nil {
+		return err
+	}
+
+	if cause == nil {
+		return fmt.Errorf("%w: %s", ErrOrderSagaFailed, saga.Error)
+	}
+	return fmt.Errorf("%w: %w", ErrOrderSagaFailed, cause)
+}
+
+func (s *OrderSaga) execute(step string, order *models.Order) error {
+	switch step {
+	case SagaStepReserveStock:
+		return s.reserveStock(order)
+	case SagaStepCreatePayment:
+		return s.authorizePayment(order)
+	case SagaStepCommitStock:
+		return s.commitStock(order)
+	case SagaStepCapturePayment:
+		return s.capturePayment(order)
+	case SagaStepCompleteOrder:
+		return s.completeOrder(order)
+	}
+	return fmt.Errorf("unknown saga step: %s", step)
+}
+
+func (s *OrderSaga) undo(step string, order *models.Order) error {
+	switch step {
+	case SagaStepReserveStock:
+		return s.restoreStock(order)
+	case SagaStepCreatePayment:
+		return s.voidPayment(order)
+	case SagaStepCapturePayment:
+		return s.refundPayment(order)
+	}
+	return nil
+}
+
+// ValidatePayment asks the payment service whether the order can be paid with
+// its payment method, before anything is reserved for it.
+func (s *OrderSaga) ValidatePayment(order *models.Order) error {
+	_, err := s.PaymentGrpcGateway.ValidateMethod(context.Background(), order.PaymentMethod, order.Amount)
+	if status.Code(err) == codes.InvalidArgument {
+		return fmt.Errorf("%w: %s", ErrInvalidPaymentMethod, status.Convert(err).Message())
+	}
+	return err
+}
+
+// authorizePayment holds the order amount without taking it yet. The saga
+// only goes on once the payment is authorized; a pending authorization parks
+// it until the provider confirms the payment.
+func (s *OrderSaga) authorizePayment(order *models.Order) error {
+	// A resumed saga may have created the payment before it stopped.
+	if order.PaymentId != 0 {
+		return nil
+	}
+
+	err := s.transition(order, models.OrderStatusAwaitingPayment, "payment requested")
+	if err != nil {
+		return err
+	}
+
+	ctx := idempotency.WithOutgoingKey(context.Background(), fmt.Sprintf("order-%d-payment", order.ID))
+	payment, err := s.PaymentGrpcGateway.
+		Authorize(ctx, &pb.AuthorizePaymentRequest{
+			OrderId: uint64(order.ID),
+			Amount:  money.ToProto(order.Amount),
+			Method:  order.PaymentMethod,
+			UserId:  uint64(order.UserId),
+		})
+	if err != nil {
+		return err
+	}
+
+	paymentStatus := payment.GetPayment().GetStatus()
+	if paymentStatus == paymentStatusFailed {
+		return ErrPaymentFailed
+	}
+
+	order.PaymentId = uint(payment.GetPayment().GetId())
+	err = s.OrderRepo.UpdateOrder(order)
+	if err != nil {
+		return err
+	}
+	return checkPaymentStatus(paymentStatus, paymentStatusAuthorized)
+}
+
+// capturePayment takes the authorized amount. The order is only completed
+// once the payment succeeded; a pending capture parks the saga like a pending
+// authorization. A cash on delivery payment stays pending until the cash is
+// collected, and its order is completed with the cash due on delivery.
+func (s *OrderSaga) capturePayment(order *models.Order) error {
+	ctx := idempotency.WithOutgoingKey(context.Background(), fmt.Sprintf("order-%d-capture", order.ID))
+	payment, err := s.PaymentGrpcGateway.Capture(ctx, order.PaymentId)
+	if err != nil {
+		return err
+	}
+	if payment.GetPayment().GetCollectOnDelivery() && payment.GetPayment().GetStatus() == paymentStatusPending {
+		return nil
+	}
+	return checkPaymentStatus(payment.GetPayment().GetStatus(), paymentStatusSuccess)
+}
+
+// checkPaymentStatus returns errPaymentPending for a pending payment, and
+// ErrPaymentFailed when the payment is not in the expected status.
+func checkPaymentStatus(paymentStatus, expected string) error {
+	switch paymentStatus {
+	case expected:
+		return nil
+	case paymentStatusPending:
+		return errPaymentPending
+	}
+	return fmt.Errorf("%w: payment is %s", ErrPaymentFailed, paymentStatus)
+}
+
+// voidPayment releases the authorization of a payment that was not captured.
+// The payment service ignores the void of a payment that was refunded.
+func (s *OrderSaga) voidPayment(order *models.Order) error {
+	if order.PaymentId == 0 {
+		return nil
+	}
+	ctx := idempotency.WithOutgoingKey(context.Background(), fmt.Sprintf("order-%d-void", order.ID))
+	_, err := s.PaymentGrpcGateway.Void(ctx, order.PaymentId)
+	return err
+}
+
+func (s *OrderSaga) refundPayment(order *models.Order) error {
+	if order.PaymentId == 0 {
+		return nil
+	}
+	ctx := idempotency.WithOutgoingKey(context.Background(), fmt.Sprintf("order-%d-refund", order.ID))
+	_, err := s.PaymentGrpcGateway.Refund(ctx, order.PaymentId)
+	return err
+}
+
+// reserveStock holds the order items in the product service. The reservation
+// is keyed by the order, so a resumed saga gets the same reservation back.
+func (s *OrderSaga) reserveStock(order *models.Order) error {
+	reservation, err := s.ProductGrpcGateway.ReserveStock(
+		context.Background(),
+		fmt.Sprintf("order-%d", order.ID),
+		toProductQuantities(order.Items),
+	)
+	if err != nil {
+		return err
+	}
+
+	order.ReservationId = uint(reservation.GetId())
+	return s.OrderRepo.UpdateOrder(order)
+}
+
+func (s *OrderSaga) commitStock(order *models.Order) error {
+	_, err := s.ProductGrpcGateway.CommitReservation(context.Background(), order.ReservationId)
+	return err
+}
+
+// restoreStock gives the stock of the order back. Orders placed before stock
+// reservations were introduced have their quantities restored directly.
+func (s *OrderSaga) restoreStock(order *models.Order) error {
+	if order.ReservationId != 0 {
+		_, err := s.ProductGrpcGateway.ReleaseReservation(context.Background(), order.ReservationId)
+		return err
+	}
+	_, err := s.ProductGrpcGateway.RestoreProductQuantity(context.Background(), toProductQuantities(order.Items))
+	return err
+}
+
+// completeOrder marks the order paid once the payment went through and the
+// stock is taken, and writes the user point event to the outbox in the same
+// transaction.
+func (s *OrderSaga) completeOrder(order *models.Order) error {
+	createUserPoint := repository.OrderEvent{
+		RoutingKey: rabbitmq.PAYMENT_ORDER_COMPLETED_ROUTING_KEY,
+		Message: dto.CreateUserPoint{
+			OrderId: order.ID,
+			UserId:  uint(order.UserId),
+			Amount:  order.Amount,
+		},
+	}
+	return s.transition(order, models.OrderStatusPaid, "order completed", createUserPoint)
+}
+
+func (s *OrderSaga) transition(order *models.Order, status, reason string, events ...repository.OrderEvent) error {
+	err := s.OrderRepo.TransitionOrderStatus(order.ID, status, ActorSystem, reason, events...)
+	if err != nil {
+		return err
+	}
+	order.Status = status
+	return nil
+}
+
+func toProductQuantities(items []models.OrderItem) []*productPb.ProductQuantity {
+	quantities := make([]*productPb.ProductQuantity, 0, len(items))
+	for _, item := range items {
+		quantities = append(quantities, &productPb.ProductQuantity{
+			ProductId: uint64(item.ProductId),
+			Quantity:  uint64(item.Quantity),
+		})
+	}
+	return quantities
+}
+
+func stepIndex(step string) (int, error) {
+	for i, v := range sagaSteps {
+		if v == step {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown saga step: %s", step)
+}
@@ -3,29 +3,50 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 
-	paymentGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/payment/grpc"
 	productGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/product/grpc"
 	userGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/user/grpc"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
 	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
-	"github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
-	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
-	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	productPb "github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/token"
 )
 
-const (
-	Pending = "pending"
-	Success = "success"
-	Failed  = "failed"
+// ActorSystem is the actor recorded in the status history for transitions
+// made by the order service itself.
+const ActorSystem = "system"
+
+const adminRole = "admin"
+
+// DefaultPaymentMethod pays the orders placed without a payment method.
+const DefaultPaymentMethod = "cash"
+
+var (
+	ErrOrderForbidden      = errors.New("order does not belong to the user")
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+	ErrMixedCurrencies     = errors.New("order items must share the same currency")
+	// ErrInvalidPaymentMethod is an unknown or disabled payment method, or one
+	// that does not accept the amount of the order.
+	ErrInvalidPaymentMethod = errors.New("invalid payment method")
 )
 
+// customerCancellableStatuses are the statuses in which the owner of an order
+// may still cancel it. Admins may cancel an order in any status the state
+// machine allows.
+var customerCancellableStatuses = []string{
+	models.OrderStatusCreated,
+	models.OrderStatusAwaitingPayment,
+	models.OrderStatusPaid,
+}
+
 type OrderService struct {
-	OrderRepo            repository.IOrderRepository
-	UserGrpcGateway      userGrpc.IUserGateway
-	PaymentGrpcGateway   paymentGrpc.IPaymentGateway
-	ProductGrpcGateway   productGrpc.IProductGateway
-	CreateOrderPublisher rabbitmq.IPublisher
+	OrderRepo          repository.IOrderRepository
+	UserGrpcGateway    userGrpc.IUserGateway
+	ProductGrpcGateway productGrpc.IProductGateway
+	OrderSaga          IOrderSaga
 }
 
 type IOrderService interface {
@@ -37,16 +58,18 @@ type IOrderService interface {
 	) ([]models.Order, int64, error)
 	UpdateOrder(user *models.Order) error
 	DeleteOrder(id uint) error
+	TransitionOrderStatus(orderId uint, status, actor, reason string) error
+	ListOrderStatusHistory(orderId uint, payload *token.Payload) ([]models.OrderStatusHistory, error)
+	CancelOrder(orderId uint, payload *token.Payload, reason string) (*models.Order, error)
 }
 
 func NewOrderService(
 	userRepo repository.IOrderRepository,
 	userGateway userGrpc.IUserGateway,
-	paymentGateway paymentGrpc.IPaymentGateway,
-	createOrderPublisher rabbitmq.IPublisher,
 	productGateway productGrpc.IProductGateway,
+	orderSaga IOrderSaga,
 ) *OrderService {
-	return &OrderService{userRepo, userGateway, paymentGateway, productGateway, createOrderPublisher}
+	return &OrderService{userRepo, userGateway, productGateway, orderSaga}
 }
 
 func (us *OrderService) CreateOrder(order *models.Order) error {
@@ -54,71 +77,42 @@ func (us *OrderService) CreateOrder(order *models.Order) error {
 	if err != nil {
 		return err
 	}
-	product, err := us.ProductGrpcGateway.Get(context.Background(), uint(order.ProductId))
-	if err != nil {
-		return err
-	}
-	order.Username = user.Username
-	order.Amount = uint(product.GetProduct().GetPrice()) * uint(order.ProductCount)
-	order.Status = Pending
-	err = us.OrderRepo.CreateOrder(order)
-	if err != nil {
-		return err
-	}
-
-	err = us.PaymentOrder(order)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (us *OrderService) PaymentOrder(order *models.Order) error {
-	payment, err := us.PaymentGrpcGateway.
-		Create(context.Background(), &pb.CreatePaymentRequest{
-			OrderId: uint64(order.ID),
-			Amount:  uint64(order.Amount),
-			Method:  "cash",
-			UserId:  uint64(order.UserId),
-		})
-	if err != nil {
-		return err
-	}
-
-	if payment.GetPayment().Status == "failed" {
-		order.Status = Failed
-		err = us.OrderRepo.UpdateOrderStatus(order.ID, Failed)
+	order.Items = mergeOrderItems(order.Items)
+	order.Amount = money.Money{}
+	for i := range order.Items {
+		item := &order.Items[i]
+		product, err := us.ProductGrpcGateway.Get(context.Background(), item.ProductId)
+		if err != nil {
+			return err
+		}
+		err = snapshotProduct(item, product.GetProduct())
+		if err != nil {
+			return err
+		}
+		order.Amount, err = order.Amount.Add(item.LineTotal)
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return ErrMixedCurrencies
+		}
 		if err != nil {
 			return err
 		}
 	}
-
-	success, err := us.ProductGrpcGateway.UpdateProductQuantity(context.Background(), uint(order.ProductId), uint(order.ProductCount))
-	if err != nil {
-		return err
-	}
-	if !success {
-		return errors.New("failed to update product quantity")
+	if order.PaymentMethod == "" {
+		order.PaymentMethod = DefaultPaymentMethod
 	}
-
-	order.Status = Success
-	err = us.OrderRepo.UpdateOrderStatus(order.ID, Success)
+	err = us.OrderSaga.ValidatePayment(order)
 	if err != nil {
 		return err
 	}
 
-	createUserPoint := dto.CreateUserPoint{
-		OrderId: order.ID,
-		UserId:  uint(order.UserId),
-		Amount:  uint(order.Amount),
-	}
-	err = us.CreateOrderPublisher.PublishMessage(createUserPoint)
+	order.Username = user.Username
+	order.Status = models.OrderStatusCreated
+	err = us.OrderRepo.CreateOrder(order)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return us.OrderSaga.Start(order)
 }
 
 func (us *OrderService) ReadOrder(id uint) (*models.Order, error) {
@@ -141,3 +135,103 @@ func (us *OrderService) UpdateOrder(user *models.Order) error {
 func (us *OrderService) DeleteOrder(id uint) error {
 	return us.OrderRepo.DeleteOrder(id)
 }
+
+func (us *OrderService) TransitionOrderStatus(orderId uint, status, actor, reason string) error {
+	return us.OrderRepo.TransitionOrderStatus(orderId, status, actor, reason)
+}
+
+// ListOrderStatusHistory lists the status changes of an order. Customers can
+// only see the history of their own orders.
+func (us *OrderService) ListOrderStatusHistory(orderId uint, payload *token.Payload) ([]models.OrderStatusHistory, error) {
+	order, err := us.OrderRepo.ReadOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Role != adminRole && order.UserId != payload.UserId {
+		return nil, ErrOrderForbidden
+	}
+	return us.OrderRepo.ListOrderStatusHistory(orderId)
+}
+
+// CancelOrder cancels the order on behalf of the authenticated user. Customers
+// can only cancel their own orders before fulfillment starts.
+func (us *OrderService) CancelOrder(orderId uint, payload *token.Payload, reason string) (*models.Order, error) {
+	order, err := us.OrderRepo.ReadOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	// An order left cancelled but not refunded is a cancellation that failed
+	// halfway, which can be retried.
+	retry := order.Status == models.OrderStatusCancelled
+
+	if payload.Role != adminRole {
+		if order.UserId != payload.UserId {
+			return nil, ErrOrderForbidden
+		}
+		if !retry && !slices.Contains(customerCancellableStatuses, order.Status) {
+			return nil, ErrOrderNotCancellable
+		}
+	}
+
+	if !retry {
+		err = models.ValidateOrderTransition(order.Status, models.OrderStatusCancelled)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = us.OrderSaga.Cancel(order, Actor(payload), reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Actor identifies the authenticated user in the order status history.
+func Actor(payload *token.Payload) string {
+	return fmt.Sprintf("%s:%d", payload.Role, payload.UserId)
+}
+
+// mergeOrderItems combines the lines that refer to the same product, keeping
+// the order in which the products first appear.
+func mergeOrderItems(items []models.OrderItem) []models.OrderItem {
+	merged := make([]models.OrderItem, 0, len(items))
+	index := make(map[uint]int, len(items))
+	for _, item := range items {
+		if i, ok := index[item.ProductId]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductId] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// snapshotProduct copies the catalog data of the product into the order item
+// and prices the line. Prices without a currency are in the default one.
+func snapshotProduct(item *models.OrderItem, product *productPb.Product) error {
+	item.ProductName = product.GetName()
+	item.UnitPrice = money.FromProto(product.GetPrice())
+	if item.UnitPrice.Currency == "" {
+		item.UnitPrice.Currency = money.DefaultCurrency
+	}
+	discount := money.FromProto(product.GetDiscount())
+	if discount.Currency == "" {
+		discount.Currency = item.UnitPrice.Currency
+	}
+
+	var err error
+	item.Discount, err = discount.Min(item.UnitPrice)
+	if err != nil {
+		return err
+	}
+	unitPrice, err := item.UnitPrice.Sub(item.Discount)
+	if err != nil {
+		return err
+	}
+	item.LineTotal, err = unitPrice.Mul(int64(item.Quantity))
+	return err
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	productGrpc "github.com/tricong1998/go-ecom/cmd/order/internal/gateway/product/grpc"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/models"
+	"github.com/tricong1998/go-ecom/cmd/order/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+)
+
+// The statuses of a payment in the payment service that the order service acts
+// on.
+const (
+	paymentStatusPending    = "pending"
+	paymentStatusAuthorized = "authorized"
+	paymentStatusSuccess    = "success"
+	paymentStatusFailed     = "failed"
+)
+
+// PaymentConfirmationService updates orders whose payment was left pending and
+// later confirmed by the payment provider. It runs in both processing modes.
+type PaymentConfirmationService struct {
+	OrderRepo          repository.IOrderRepository
+	ProductGrpcGateway productGrpc.IProductGateway
+	OrderSaga          *OrderSaga
+}
+
+func NewPaymentConfirmationService(
+	orderRepo repository.IOrderRepository,
+	productGateway productGrpc.IProductGateway,
+	orderSaga *OrderSaga,
+) *PaymentConfirmationService {
+	return &PaymentConfirmationService{orderRepo, productGateway, orderSaga}
+}
+
+// PaymentConfirmed continues the saga parked on the payment of the order. An
+// order without such a saga that waits for its payment is marked paid, or
+// failed with its stock given back when the payment was declined. Orders that
+// already moved on, or that were placed with another payment, are left alone.
+func (s *PaymentConfirmationService) PaymentConfirmed(event events.PaymentConfirmed) error {
+	order, err := s.OrderRepo.ReadOrder(event.OrderId)
+	if err != nil {
+		return err
+	}
+	if order.PaymentId != 0 && order.PaymentId != event.PaymentId {
+		return nil
+	}
+
+	handled, err := s.OrderSaga.PaymentConfirmed(order, event.Status, event.Reason)
+	if handled || err != nil {
+		return err
+	}
+
+	switch event.Status {
+	case paymentStatusSuccess:
+		if order.Status != models.OrderStatusAwaitingPayment {
+			return nil
+		}
+		createUserPoint := repository.OrderEvent{
+			RoutingKey: rabbitmq.PAYMENT_ORDER_COMPLETED_ROUTING_KEY,
+			Message: dto.CreateUserPoint{
+				OrderId: order.ID,
+				UserId:  uint(order.UserId),
+				Amount:  order.Amount,
+			},
+		}
+		return s.transition(order.ID, models.OrderStatusPaid, "payment confirmed", createUserPoint)
+	case paymentStatusFailed:
+		if models.ValidateOrderTransition(order.Status, models.OrderStatusFailed) != nil {
+			return nil
+		}
+		if order.ReservationId != 0 {
+			_, err = s.ProductGrpcGateway.ReleaseReservation(context.Background(), order.ReservationId)
+			if err != nil {
+				return err
+			}
+		}
+		return s.transition(order.ID, models.OrderStatusFailed, event.Reason)
+	}
+	return nil
+}
+
+// transition ignores transitions the order has already moved past, which
+// happens when an event is delivered again.
+func (s *PaymentConfirmationService) transition(orderId uint, status, reason string, orderEvents ...repository.OrderEvent) error {
+	err := s.OrderRepo.TransitionOrderStatus(orderId, status, ActorSystem, reason, orderEvents...)
+	if errors.Is(err, models.ErrInvalidOrderTransition) {
+		return nil
+	}
+	return err
+}
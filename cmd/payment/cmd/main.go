@@ -1,19 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/api"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/database"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/grpc_handler"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/provider"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/rabbit_handler"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/logger"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/idempotency"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"gorm.io/gorm"
@@ -40,14 +49,130 @@ func main() {
 		log.Fatal().Err(err).Msg("Cannot migrate database")
 	}
 
-	go runGrpcServer(cfg, db, log)
-	runGinServer(cfg, db, log)
+	// The providers are shared so that every entry point sees the same charges.
+	paymentMethods, err := newPaymentMethods(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot create payment methods")
+	}
+
+	rabbitConfig := rabbitmq.RabbitMQConfig{
+		Host:     cfg.RabbitMQConfig.Host,
+		Port:     cfg.RabbitMQConfig.Port,
+		User:     cfg.RabbitMQConfig.User,
+		Password: cfg.RabbitMQConfig.Password,
+	}
+	rabbitConn, err := rabbitmq.NewRabbitMQConn(&rabbitConfig, context.Background())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot connect rabbit")
+	}
+
+	// Refunds are published in both modes, so the outbox is always relayed.
+	outboxRelay := rabbitmq.NewOutboxRelay(context.Background(), db, rabbitConn, log, rabbitmq.E_COM_EXCHANGE, "direct")
+	go outboxRelay.Run()
+
+	if cfg.OrderProcessingMode == events.ProcessingModeAsync {
+		runOrderEventConsumers(cfg, &rabbitConfig, rabbitConn, db, log, paymentMethods)
+	}
+	runTokenRevocationConsumer(db, log, &rabbitConfig, rabbitConn)
+	go runGrpcServer(cfg, db, log, paymentMethods)
+	runGinServer(cfg, db, log, paymentMethods)
+}
+
+// newPaymentMethods creates the configured payment methods, with one instance
+// of every provider they are charged by.
+func newPaymentMethods(cfg *config.Config) (*paymentmethod.Registry, error) {
+	var methods []paymentmethod.Method
+	var providers []provider.PaymentProvider
+	created := make(map[string]bool)
+	for _, method := range cfg.PaymentMethods {
+		methods = append(methods, paymentmethod.Method{
+			Name:      method.Name,
+			Enabled:   method.Enabled,
+			MinAmount: method.MinAmount,
+			MaxAmount: method.MaxAmount,
+			Provider:  method.Provider,
+		})
+		if !method.Enabled || created[method.Provider] {
+			continue
+		}
+
+		paymentProvider, err := provider.New(provider.Config{
+			Name:    method.Provider,
+			URL:     cfg.PaymentProvider.URL,
+			APIKey:  cfg.PaymentProvider.APIKey,
+			Timeout: cfg.PaymentProvider.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, paymentProvider)
+		created[method.Provider] = true
+	}
+	return paymentmethod.NewRegistry(methods, providers...)
+}
+
+// runOrderEventConsumers authorizes, captures and refunds orders in reaction
+// to the events of orders processed asynchronously.
+func runOrderEventConsumers(
+	cfg *config.Config,
+	rabbitConfig *rabbitmq.RabbitMQConfig,
+	rabbitConn *amqp.Connection,
+	db *gorm.DB,
+	log zerolog.Logger,
+	paymentMethods *paymentmethod.Registry,
+) {
+	paymentRepo := repository.NewPaymentRepository(db)
+	paymentService := services.NewPaymentService(paymentRepo, paymentMethods, cfg.PaymentProvider.AuthorizationTTL)
+	paymentEventService := services.NewPaymentEventService(paymentService, rabbitmq.NewOutboxWriter(db))
+	orderPaymentDependencies := rabbit_handler.OrderPaymentDependencies{
+		Logger:              log,
+		PaymentEventService: paymentEventService,
+	}
+	consumers := []struct {
+		handler    func(queue string, msg amqp.Delivery, dependencies *rabbit_handler.OrderPaymentDependencies) error
+		queue      string
+		routingKey string
+	}{
+		{rabbit_handler.PayOrder, rabbitmq.PAYMENT_STOCK_RESERVED_QUEUE, rabbitmq.STOCK_RESERVED_ROUTING_KEY},
+		{rabbit_handler.CaptureOrder, rabbitmq.PAYMENT_STOCK_COMMITTED_QUEUE, rabbitmq.STOCK_COMMITTED_ROUTING_KEY},
+		{rabbit_handler.RefundOrder, rabbitmq.PAYMENT_STOCK_COMMIT_FAILED_QUEUE, rabbitmq.STOCK_COMMIT_FAILED_ROUTING_KEY},
+	}
+	for _, c := range consumers {
+		consumer := rabbitmq.NewConsumer[*rabbit_handler.OrderPaymentDependencies](context.Background(), rabbitConfig, rabbitConn, log, c.handler, rabbitmq.E_COM_EXCHANGE, "direct", c.queue, c.routingKey)
+		go func() {
+			err := consumer.ConsumeMessage(nil, &orderPaymentDependencies)
+			if err != nil {
+				log.Error().Err(err).Msg("Consume message error")
+			}
+		}()
+	}
+}
+
+// runTokenRevocationConsumer rejects the access tokens revoked by the user
+// service.
+func runTokenRevocationConsumer(
+	db *gorm.DB,
+	log zerolog.Logger,
+	rabbitConfig *rabbitmq.RabbitMQConfig,
+	rabbitConn *amqp.Connection,
+) {
+	tokenRevocationDependencies := rabbit_handler.TokenRevocationDependencies{
+		Logger:          log,
+		RevocationStore: token.NewGormRevocationStore(db),
+	}
+	consumer := rabbitmq.NewConsumer[*rabbit_handler.TokenRevocationDependencies](context.Background(), rabbitConfig, rabbitConn, log, rabbit_handler.TokensRevoked, rabbitmq.E_COM_EXCHANGE, "direct", rabbitmq.PAYMENT_TOKENS_REVOKED_QUEUE, rabbitmq.TOKENS_REVOKED_ROUTING_KEY)
+	go func() {
+		err := consumer.ConsumeMessage(nil, &tokenRevocationDependencies)
+		if err != nil {
+			log.Error().Err(err).Msg("Consume message error")
+		}
+	}()
 }
 
-func runGinServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
+func runGinServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger, paymentMethods *paymentmethod.Registry) {
 	// Initialize router
 	routes := gin.Default()
-	api.SetupRoutes(routes, db)
+	api.SetupRoutes(routes, db, cfg, log, paymentMethods)
 
 	// Start server
 	address := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
@@ -57,12 +182,15 @@ func runGinServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
 	}
 }
 
-func runGrpcServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
+func runGrpcServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger, paymentMethods *paymentmethod.Registry) {
 	paymentRepo := repository.NewPaymentRepository(db)
-	paymentService := services.NewPaymentService(paymentRepo)
+	paymentService := services.NewPaymentService(paymentRepo, paymentMethods, cfg.PaymentProvider.AuthorizationTTL)
 	server := grpc_handler.NewServer(paymentService)
 
-	grpcServer := grpc.NewServer()
+	idempotencyStore := idempotency.NewGormStore(db)
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(idempotency.UnaryServerInterceptor(idempotencyStore, cfg.Idempotency.KeyTTL)),
+	)
 	pb.RegisterPaymentGrpcServer(grpcServer, server)
 	reflection.Register(grpcServer)
 
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/dto"
+)
+
+type LedgerHandler struct {
+	LedgerService services.ILedgerService
+}
+
+func NewLedgerHandler(ledgerService services.ILedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService}
+}
+
+// AccountBalances sums the debits and credits of every ledger account over a
+// date range.
+func (ledgerHandler *LedgerHandler) AccountBalances(ctx *gin.Context) {
+	var req dto.LedgerRangeQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	balances, err := ledgerHandler.LedgerService.AccountBalances(req.From, req.To)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.AccountBalancesResponse{
+		From:     req.From,
+		To:       req.To,
+		Accounts: balances,
+	})
+}
+
+// ListJournalEntries lists the entries posted over a date range, optionally
+// only those touching one account.
+func (ledgerHandler *LedgerHandler) ListJournalEntries(ctx *gin.Context) {
+	var req dto.ListJournalEntryQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	entries, total, err := ledgerHandler.LedgerService.ListJournalEntries(
+		req.From, req.To, req.Account, req.PerPage, req.Page,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	entriesResponse := []dto.JournalEntryResponse{}
+	for _, v := range entries {
+		entriesResponse = append(entriesResponse, *dto.ToJournalEntryResponse(&v))
+	}
+
+	ctx.JSON(http.StatusOK, dto.ListJournalEntryResponse{
+		Items: entriesResponse,
+		Metadata: dto.MetadataDto{
+			Total:   total,
+			Page:    req.Page,
+			PerPage: req.PerPage,
+		},
+	})
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/dto"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
 )
 
 type PaymentHandler struct {
@@ -26,13 +30,18 @@ func (paymentHandler *PaymentHandler) CreatePayment(ctx *gin.Context) {
 	}
 
 	payment := models.Payment{
-		OrderID: input.OrderId,
-		UserID:  input.UserId,
-		Amount:  input.Amount,
-		Method:  input.Method,
+		OrderID:   input.OrderId,
+		UserID:    input.UserId,
+		Amount:    input.Amount,
+		Method:    input.Method,
+		CardToken: input.CardToken,
 	}
 	if err := paymentHandler.PaymentService.CreatePayment(&payment); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAmount) || paymentmethod.IsValidationError(err) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, errorResponse(err))
 		return
 	}
 
@@ -127,3 +136,128 @@ func (paymentHandler *PaymentHandler) DeletePayment(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+// RefreshPaymentStatus reads the status of the payment back from the payment
+// provider, for payments that were left pending.
+func (paymentHandler *PaymentHandler) RefreshPaymentStatus(ctx *gin.Context) {
+	var readPaymentRequest dto.ReadPaymentRequest
+	if err := ctx.ShouldBindUri(&readPaymentRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payment, err := paymentHandler.PaymentService.RefreshPaymentStatus(uint(readPaymentRequest.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ToPaymentResponse(payment))
+}
+
+// CreateRefund refunds part or all of a payment.
+func (paymentHandler *PaymentHandler) CreateRefund(ctx *gin.Context) {
+	var readPaymentRequest dto.ReadPaymentRequest
+	if err := ctx.ShouldBindUri(&readPaymentRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var input dto.CreateRefundDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refund := models.Refund{
+		PaymentID: readPaymentRequest.ID,
+		Amount:    input.Amount,
+		Reason:    input.Reason,
+	}
+	payment, err := paymentHandler.PaymentService.RefundPayment(&refund)
+	if err != nil {
+		ctx.JSON(refundErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	response := dto.CreateRefundResponse{Payment: dto.ToPaymentResponse(payment)}
+	if refund.ID != 0 {
+		response.Refund = dto.ToRefundResponse(&refund)
+	}
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (paymentHandler *PaymentHandler) ListRefunds(ctx *gin.Context) {
+	var readPaymentRequest dto.ReadPaymentRequest
+	if err := ctx.ShouldBindUri(&readPaymentRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refunds, err := paymentHandler.PaymentService.ListRefunds(readPaymentRequest.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	refundsResponse := []dto.RefundResponse{}
+	for _, v := range refunds {
+		refundsResponse = append(refundsResponse, *dto.ToRefundResponse(&v))
+	}
+	ctx.JSON(http.StatusOK, refundsResponse)
+}
+
+// CollectPayment records that the cash of a cash on delivery payment was
+// collected on delivery.
+func (paymentHandler *PaymentHandler) CollectPayment(ctx *gin.Context) {
+	var readPaymentRequest dto.ReadPaymentRequest
+	if err := ctx.ShouldBindUri(&readPaymentRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payment, err := paymentHandler.PaymentService.CollectPayment(readPaymentRequest.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+	if errors.Is(err, services.ErrPaymentNotCollectable) {
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ToPaymentResponse(payment))
+}
+
+func (paymentHandler *PaymentHandler) ListPaymentMethods(ctx *gin.Context) {
+	methods := []dto.PaymentMethodResponse{}
+	for _, method := range paymentHandler.PaymentService.ListPaymentMethods() {
+		methods = append(methods, dto.PaymentMethodResponse{
+			Name:      method.Name,
+			Enabled:   method.Enabled,
+			MinAmount: method.MinAmount,
+			MaxAmount: method.MaxAmount,
+		})
+	}
+	ctx.JSON(http.StatusOK, methods)
+}
+
+func refundErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRefundExceedsPayment), errors.Is(err, money.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPaymentNotRefundable):
+		return http.StatusConflict
+	}
+	return http.StatusBadGateway
+}
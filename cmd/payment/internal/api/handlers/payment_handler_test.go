@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,10 +14,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/ledger"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/mocks"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/provider"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/dto"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"gorm.io/gorm"
 )
 
 func expectBodyPayment(t *testing.T, w *httptest.ResponseRecorder, mockResponse *models.Payment) {
@@ -31,10 +39,24 @@ func expectBodyPayment(t *testing.T, w *httptest.ResponseRecorder, mockResponse
 	assert.Equal(t, response.Error, mockResponse.Error)
 	assert.Equal(t, response.Amount, mockResponse.Amount)
 	assert.Equal(t, response.Method, mockResponse.Method)
+	assert.Equal(t, response.Status, mockResponse.Status)
+	assert.Equal(t, response.Provider, mockResponse.Provider)
+	assert.Equal(t, response.ProviderReference, mockResponse.ProviderReference)
 	assert.WithinDuration(t, response.CreatedAt, mockResponse.CreatedAt, time.Second)
 	assert.WithinDuration(t, response.UpdatedAt, mockResponse.UpdatedAt, time.Second)
 }
 
+// newFakeMethods charges cash and card payments with paymentProvider, and has
+// the wallet method disabled. Cash on delivery payments can be voided too.
+func newFakeMethods(paymentProvider provider.PaymentProvider) *paymentmethod.Registry {
+	methods, _ := paymentmethod.NewRegistry([]paymentmethod.Method{
+		{Name: paymentmethod.Cash, Enabled: true, MaxAmount: 10000, Provider: paymentProvider.Name()},
+		{Name: paymentmethod.Card, Enabled: true, Provider: paymentProvider.Name()},
+		{Name: paymentmethod.Wallet, Provider: paymentProvider.Name()},
+	}, paymentProvider, provider.NewCashOnDeliveryProvider())
+	return methods
+}
+
 func TestCreatePayment(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -47,17 +69,53 @@ func TestCreatePayment(t *testing.T) {
 			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
 				input.OrderId = 1
 				input.UserId = 1
-				input.Amount = 100
+				input.Amount = money.New(100, "USD")
 				input.Method = "cash"
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
-				mockResponse.Status = "pending"
+				mockResponse.Status = "success"
 				mockResponse.Method = "cash"
-				mockResponse.Amount = 100
+				mockResponse.Amount = money.New(100, "USD")
 				mockResponse.Error = ""
 				mockResponse.UserID = 1
 				mockResponse.OrderID = 1
+				mockResponse.Provider = provider.NameFake
+				mockResponse.ProviderReference = "fake_1"
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("CreatePayment", mock.AnythingOfType("*models.Payment")).Return(nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Payment)
+					arg.ID = mockResponse.ID
+					arg.CreatedAt = mockResponse.CreatedAt
+					arg.UpdatedAt = mockResponse.UpdatedAt
+				})
+				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment"), mock.Anything).Return(nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				expectBodyPayment(t, w, mockResponse)
+			},
+		},
+		{
+			name: "Declined",
+			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
+				input.OrderId = 1
+				input.UserId = 1
+				input.Amount = money.New(100, "USD")
+				input.Method = "card"
+				input.CardToken = provider.FakeCardDeclined
+				mockResponse.ID = 1
+				mockResponse.CreatedAt = time.Now()
+				mockResponse.UpdatedAt = mockResponse.CreatedAt
+				mockResponse.Status = "failed"
+				mockResponse.Method = "card"
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Error = "card declined"
+				mockResponse.UserID = 1
+				mockResponse.OrderID = 1
+				mockResponse.Provider = provider.NameFake
+				mockResponse.ProviderReference = "fake_1"
 			},
 			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
 				paymentRepo.On("CreatePayment", mock.AnythingOfType("*models.Payment")).Return(nil).Run(func(args mock.Arguments) {
@@ -66,7 +124,40 @@ func TestCreatePayment(t *testing.T) {
 					arg.CreatedAt = mockResponse.CreatedAt
 					arg.UpdatedAt = mockResponse.UpdatedAt
 				})
-				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment")).Return(nil)
+				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment"), mock.Anything).Return(nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				expectBodyPayment(t, w, mockResponse)
+			},
+		},
+		{
+			name: "ProviderUnavailable",
+			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
+				input.OrderId = 1
+				input.UserId = 1
+				input.Amount = money.New(100, "USD")
+				input.Method = "card"
+				input.CardToken = provider.FakeCardUnavailable
+				mockResponse.ID = 1
+				mockResponse.CreatedAt = time.Now()
+				mockResponse.UpdatedAt = mockResponse.CreatedAt
+				mockResponse.Status = "failed"
+				mockResponse.Method = "card"
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Error = provider.ErrFakeUnavailable.Error()
+				mockResponse.UserID = 1
+				mockResponse.OrderID = 1
+				mockResponse.Provider = provider.NameFake
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("CreatePayment", mock.AnythingOfType("*models.Payment")).Return(nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Payment)
+					arg.ID = mockResponse.ID
+					arg.CreatedAt = mockResponse.CreatedAt
+					arg.UpdatedAt = mockResponse.UpdatedAt
+				})
+				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment"), mock.Anything).Return(nil)
 			},
 			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
 				assert.Equal(t, http.StatusCreated, w.Code)
@@ -89,12 +180,54 @@ func TestCreatePayment(t *testing.T) {
 				assert.Equal(t, http.StatusBadRequest, w.Code)
 			},
 		},
+		{
+			name: "UnknownMethod",
+			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
+				input.OrderId = 1
+				input.UserId = 1
+				input.Amount = money.New(100, "USD")
+				input.Method = "cheque"
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+		{
+			name: "DisabledMethod",
+			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
+				input.OrderId = 1
+				input.UserId = 1
+				input.Amount = money.New(100, "USD")
+				input.Method = "wallet"
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+		{
+			name: "AmountAboveMethodMaximum",
+			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
+				input.OrderId = 1
+				input.UserId = 1
+				input.Amount = money.New(20000, "USD")
+				input.Method = "cash"
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
 		{
 			name: "CreatePaymentError",
 			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
 				input.OrderId = 1
 				input.UserId = 1
-				input.Amount = 100
+				input.Amount = money.New(100, "USD")
 				input.Method = "cash"
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
@@ -117,7 +250,7 @@ func TestCreatePayment(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Arrange
 			paymentRepo := new(mocks.MockPaymentRepository)
-			paymentService := services.NewPaymentService(paymentRepo)
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(provider.NewFakeProvider()), time.Hour)
 			paymentHandler := NewPaymentHandler(paymentService)
 			var payment dto.CreatePaymentDto
 			var mockResponse models.Payment
@@ -149,7 +282,7 @@ func TestReadPayment(t *testing.T) {
 				input.ID = 1
 				mockResponse.OrderID = 1
 				mockResponse.UserID = 1
-				mockResponse.Amount = 100
+				mockResponse.Amount = money.New(100, "USD")
 				mockResponse.Method = "cash"
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
@@ -195,7 +328,7 @@ func TestReadPayment(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Arrange
 			paymentRepo := new(mocks.MockPaymentRepository)
-			paymentService := services.NewPaymentService(paymentRepo)
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(provider.NewFakeProvider()), time.Hour)
 			paymentHandler := NewPaymentHandler(paymentService)
 			var input dto.ReadPaymentRequest
 			var mockResponse models.Payment
@@ -240,7 +373,7 @@ func TestListPayment(t *testing.T) {
 				payment1 := models.Payment{
 					OrderID: 1,
 					UserID:  userId,
-					Amount:  100,
+					Amount:  money.New(100, "USD"),
 					Method:  "cash",
 					Status:  "pending",
 					Error:   "",
@@ -253,7 +386,7 @@ func TestListPayment(t *testing.T) {
 				payment2 := models.Payment{
 					OrderID: 2,
 					UserID:  userId,
-					Amount:  100,
+					Amount:  money.New(100, "USD"),
 					Method:  "cash",
 					Status:  "pending",
 					Error:   "",
@@ -315,7 +448,7 @@ func TestListPayment(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Arrange
 			paymentRepo := new(mocks.MockPaymentRepository)
-			paymentService := services.NewPaymentService(paymentRepo)
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(provider.NewFakeProvider()), time.Hour)
 			paymentHandler := NewPaymentHandler(paymentService)
 			var input dto.ListPaymentQuery
 			var total int64
@@ -356,7 +489,7 @@ func TestListPayment(t *testing.T) {
 
 // 			},
 // 			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
-// 				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment")).Return(nil).Run(func(args mock.Arguments) {
+// 				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 // 					arg := args.Get(0).(*models.Payment)
 // 					arg.ID = mockResponse.ID
 // 					arg.CreatedAt = mockResponse.CreatedAt
@@ -373,7 +506,7 @@ func TestListPayment(t *testing.T) {
 // 			setupInputFunc: func(input *dto.CreatePaymentDto, mockResponse *models.Payment) {
 // 			},
 // 			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
-// 				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment")).Return(nil).Run(func(args mock.Arguments) {
+// 				paymentRepo.On("UpdatePayment", mock.AnythingOfType("*models.Payment"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 // 					arg := args.Get(0).(*models.Payment)
 // 					arg.ID = mockResponse.ID
 // 					arg.CreatedAt = mockResponse.CreatedAt
@@ -431,3 +564,404 @@ func TestListPayment(t *testing.T) {
 // 		})
 // 	}
 // }
+
+func TestCreateRefund(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setupInputFunc func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment)
+		mockFunc       func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment)
+		expectFunc     func(w *httptest.ResponseRecorder, mockResponse *models.Payment)
+	}{
+		{
+			name: "PartialRefund",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+				input.Amount = money.New(40, "USD")
+				input.Reason = "damaged item"
+				mockResponse.ID = 1
+				mockResponse.OrderID = 1
+				mockResponse.UserID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Method = "cash"
+				mockResponse.Status = "success"
+				mockResponse.Provider = provider.NameFake
+				mockResponse.CreatedAt = time.Now()
+				mockResponse.UpdatedAt = mockResponse.CreatedAt
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				charge, _ := paymentProvider.Charge(context.Background(), provider.ChargeRequest{Amount: mockResponse.Amount})
+				mockResponse.ProviderReference = charge.Reference
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+				paymentRepo.On("CreateRefund", mock.AnythingOfType("*models.Refund")).Return(mockResponse, nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Refund)
+					arg.ID = 1
+					arg.Status = models.RefundStatusPending
+				})
+				paymentRepo.On("CompleteRefund", mock.AnythingOfType("*models.Refund")).Return(mockResponse, nil).Run(func(args mock.Arguments) {
+					arg := args.Get(0).(*models.Refund)
+					assert.Equal(t, models.RefundStatusSucceeded, arg.Status)
+					mockResponse.RefundedAmount = arg.Amount
+					mockResponse.Status = models.PaymentStatusPartiallyRefunded
+				})
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var response dto.CreateRefundResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, money.New(40, "USD"), response.Refund.Amount)
+				assert.Equal(t, "damaged item", response.Refund.Reason)
+				assert.Equal(t, models.RefundStatusSucceeded, response.Refund.Status)
+				assert.Equal(t, models.PaymentStatusPartiallyRefunded, response.Payment.Status)
+				assert.Equal(t, money.New(40, "USD"), response.Payment.RefundedAmount)
+			},
+		},
+		{
+			name: "ExceedsPayment",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+				input.Amount = money.New(150, "USD")
+				mockResponse.ID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Status = "success"
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+				paymentRepo.On("CreateRefund", mock.AnythingOfType("*models.Refund")).Return((*models.Payment)(nil), services.ErrRefundExceedsPayment)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+		{
+			name: "NotRefundable",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+				mockResponse.ID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Status = "failed"
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+			},
+		},
+		{
+			name: "PendingNotRefundable",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+				mockResponse.ID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Status = models.PaymentStatusPending
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+			},
+		},
+		{
+			name: "UncollectedCashOnDeliveryVoided",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+				capturedAt := time.Now()
+				mockResponse.ID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Provider = provider.NameCashOnDelivery
+				mockResponse.Status = models.PaymentStatusPending
+				mockResponse.CapturedAt = &capturedAt
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+				paymentRepo.On("UpdatePayment", mockResponse, []models.JournalEntry(nil)).Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var response dto.CreateRefundResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.PaymentStatusVoided, response.Payment.Status)
+				assert.Nil(t, response.Refund)
+			},
+		},
+		{
+			name: "RefundedConcurrently",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+				mockResponse.ID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Status = models.PaymentStatusSuccess
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+				paymentRepo.On("CreateRefund", mock.AnythingOfType("*models.Refund")).Return((*models.Payment)(nil), services.ErrPaymentNotRefundable)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+			},
+		},
+		{
+			name: "PaymentNotFound",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+				*paymentId = 1
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return((*models.Payment)(nil), gorm.ErrRecordNotFound)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+			},
+		},
+		{
+			name: "BadInput",
+			setupInputFunc: func(paymentId *uint, input *dto.CreateRefundDto, mockResponse *models.Payment) {
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, paymentProvider *provider.FakeProvider, mockResponse *models.Payment) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.Payment) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			paymentRepo := new(mocks.MockPaymentRepository)
+			paymentProvider := provider.NewFakeProvider()
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(paymentProvider), time.Hour)
+			paymentHandler := NewPaymentHandler(paymentService)
+			var paymentId uint
+			var input dto.CreateRefundDto
+			var mockResponse models.Payment
+			tc.setupInputFunc(&paymentId, &input, &mockResponse)
+			tc.mockFunc(paymentRepo, paymentProvider, &mockResponse)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(paymentId)}}
+
+			jsonInput, _ := json.Marshal(input)
+			c.Request, _ = http.NewRequest(http.MethodPost, fmt.Sprintf("/payments/%d/refunds", paymentId), bytes.NewBuffer(jsonInput))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			// Act
+			paymentHandler.CreateRefund(c)
+
+			// Assert
+			tc.expectFunc(w, &mockResponse)
+		})
+	}
+}
+
+func TestCollectPayment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		setupInputFunc func(mockResponse *models.Payment)
+		mockFunc       func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment)
+		expectFunc     func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository)
+	}{
+		{
+			name: "OK",
+			setupInputFunc: func(mockResponse *models.Payment) {
+				capturedAt := time.Now()
+				mockResponse.ID = 1
+				mockResponse.OrderID = 1
+				mockResponse.Amount = money.New(100, "USD")
+				mockResponse.Provider = provider.NameCashOnDelivery
+				mockResponse.Status = models.PaymentStatusPending
+				mockResponse.AuthorizationExpiresAt = &capturedAt
+				mockResponse.CapturedAt = &capturedAt
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+				paymentRepo.On("UpdatePayment", mockResponse, mock.MatchedBy(func(entries []models.JournalEntry) bool {
+					return len(entries) == 1 && entries[0].Type == ledger.EntryTypeCapture
+				})).Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response dto.PaymentResponse
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, models.PaymentStatusSuccess, response.Status)
+				paymentRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "AlreadyCollected",
+			setupInputFunc: func(mockResponse *models.Payment) {
+				capturedAt := time.Now()
+				mockResponse.ID = 1
+				mockResponse.Provider = provider.NameCashOnDelivery
+				mockResponse.Status = models.PaymentStatusSuccess
+				mockResponse.CapturedAt = &capturedAt
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				paymentRepo.AssertNotCalled(t, "UpdatePayment", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "NotCaptured",
+			setupInputFunc: func(mockResponse *models.Payment) {
+				mockResponse.ID = 1
+				mockResponse.Provider = provider.NameCashOnDelivery
+				mockResponse.Status = models.PaymentStatusAuthorized
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+				paymentRepo.AssertNotCalled(t, "UpdatePayment", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "NotCashOnDelivery",
+			setupInputFunc: func(mockResponse *models.Payment) {
+				capturedAt := time.Now()
+				mockResponse.ID = 1
+				mockResponse.Provider = provider.NameFake
+				mockResponse.Status = models.PaymentStatusPending
+				mockResponse.CapturedAt = &capturedAt
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(mockResponse, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			setupInputFunc: func(mockResponse *models.Payment) {
+			},
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, mockResponse *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return((*models.Payment)(nil), gorm.ErrRecordNotFound)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			paymentRepo := new(mocks.MockPaymentRepository)
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(provider.NewFakeProvider()), time.Hour)
+			paymentHandler := NewPaymentHandler(paymentService)
+			var mockResponse models.Payment
+			tc.setupInputFunc(&mockResponse)
+			tc.mockFunc(paymentRepo, &mockResponse)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "1"}}
+			c.Request, _ = http.NewRequest(http.MethodPost, "/payments/1/collection", nil)
+
+			// Act
+			paymentHandler.CollectPayment(c)
+
+			// Assert
+			tc.expectFunc(w, paymentRepo)
+		})
+	}
+}
+
+func TestRefreshPaymentStatus(t *testing.T) {
+	testCases := []struct {
+		name       string
+		status     string
+		mockFunc   func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment)
+		expectFunc func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository, payment *models.Payment)
+	}{
+		{
+			name:   "Authorized",
+			status: models.PaymentStatusPending,
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(payment, nil)
+				paymentRepo.On("ApplyWebhookEvent", "refresh_1", payment, rabbitmq.PAYMENT_CONFIRMED_ROUTING_KEY, events.PaymentConfirmed{
+					OrderId:   1,
+					PaymentId: 1,
+					Status:    models.PaymentStatusAuthorized,
+				}, []models.JournalEntry(nil)).Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, models.PaymentStatusAuthorized, payment.Status)
+				assert.NotNil(t, payment.AuthorizationExpiresAt)
+				paymentRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Replayed",
+			status: models.PaymentStatusPending,
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(payment, nil).Twice()
+				paymentRepo.On("ApplyWebhookEvent", "refresh_1", payment, mock.Anything, mock.Anything, mock.Anything).
+					Return(services.ErrWebhookReplayed).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				paymentRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "NotPending",
+			status: models.PaymentStatusAuthorized,
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				paymentRepo.On("ReadPayment", uint(1)).Return(payment, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				paymentRepo.AssertNotCalled(t, "ApplyWebhookEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			paymentProvider := provider.NewFakeProvider()
+			result, err := paymentProvider.Authorize(context.Background(), provider.ChargeRequest{
+				OrderId: 1,
+				Amount:  money.New(100, "USD"),
+			})
+			assert.NoError(t, err)
+			payment := &models.Payment{
+				OrderID:           1,
+				Amount:            money.New(100, "USD"),
+				Provider:          paymentProvider.Name(),
+				ProviderReference: result.Reference,
+				Status:            tc.status,
+			}
+			payment.ID = 1
+			paymentRepo := new(mocks.MockPaymentRepository)
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(paymentProvider), time.Hour)
+			paymentHandler := NewPaymentHandler(paymentService)
+			tc.mockFunc(paymentRepo, payment)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "1"}}
+			c.Request, _ = http.NewRequest(http.MethodPost, "/payments/1/refresh", nil)
+
+			// Act
+			paymentHandler.RefreshPaymentStatus(c)
+
+			// Assert
+			tc.expectFunc(w, paymentRepo, payment)
+		})
+	}
+}
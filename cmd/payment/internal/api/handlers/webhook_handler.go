@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/webhook"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/dto"
+	"gorm.io/gorm"
+)
+
+// maxWebhookBodySize bounds the body read before the signature is checked.
+const maxWebhookBodySize = 64 << 10
+
+type WebhookHandler struct {
+	PaymentService services.IPaymentService
+	Secret         []byte
+	Tolerance      time.Duration
+}
+
+func NewWebhookHandler(paymentService services.IPaymentService, secret []byte, tolerance time.Duration) *WebhookHandler {
+	return &WebhookHandler{paymentService, secret, tolerance}
+}
+
+// ReceivePaymentWebhook applies a signed provider callback to the payment it
+// refers to. Callbacks with a bad signature, an old timestamp or an id that was
+// already received are rejected.
+func (webhookHandler *WebhookHandler) ReceivePaymentWebhook(ctx *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = webhook.Verify(
+		webhookHandler.Secret,
+		ctx.GetHeader(webhook.SignatureHeader),
+		ctx.GetHeader(webhook.TimestampHeader),
+		body,
+		webhookHandler.Tolerance,
+		time.Now(),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	var event webhook.Event
+	if err := binding.JSON.BindBody(body, &event); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payment, err := webhookHandler.PaymentService.ConfirmPayment(event.Id, event.Reference, event.Status, event.Message)
+	if err != nil {
+		ctx.JSON(webhookErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.ToPaymentResponse(payment))
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrWebhookReplayed):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUnknownWebhookStatus):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/ledger"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/mocks"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/provider"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/webhook"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+)
+
+func TestReceivePaymentWebhook(t *testing.T) {
+	secret := []byte("webhook-secret")
+	event := webhook.Event{Id: "evt_1", Reference: "http_1", Status: provider.StatusSucceeded}
+
+	testCases := []struct {
+		name       string
+		mockFunc   func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment)
+		sendFunc   func(sender *webhook.Sender) (int, error)
+		expectFunc func(t *testing.T, status int, paymentRepo *mocks.MockPaymentRepository)
+	}{
+		{
+			name: "OK",
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				paymentRepo.On("ReadPaymentByProviderReference", "http_1").Return(payment, nil)
+				paymentRepo.On("ApplyWebhookEvent", "evt_1", payment, rabbitmq.PAYMENT_CONFIRMED_ROUTING_KEY, events.PaymentConfirmed{
+					OrderId:   1,
+					PaymentId: 1,
+					Status:    models.PaymentStatusSuccess,
+				}, []models.JournalEntry{ledger.ChargeEntry(ledger.EntryTypeCharge, payment)}).Return(nil).Once()
+			},
+			sendFunc: func(sender *webhook.Sender) (int, error) {
+				return sender.Send(context.Background(), event)
+			},
+			expectFunc: func(t *testing.T, status int, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusOK, status)
+				paymentRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name:     "BadSignature",
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {},
+			sendFunc: func(sender *webhook.Sender) (int, error) {
+				sender.Secret = []byte("another-secret")
+				return sender.Send(context.Background(), event)
+			},
+			expectFunc: func(t *testing.T, status int, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusUnauthorized, status)
+				paymentRepo.AssertNotCalled(t, "ReadPaymentByProviderReference", mock.Anything)
+			},
+		},
+		{
+			name:     "OldTimestamp",
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {},
+			sendFunc: func(sender *webhook.Sender) (int, error) {
+				body, _ := json.Marshal(event)
+				req, err := sender.NewRequest(context.Background(), body, time.Now().Add(-time.Hour))
+				if err != nil {
+					return 0, err
+				}
+				resp, err := sender.Client.Do(req)
+				if err != nil {
+					return 0, err
+				}
+				defer resp.Body.Close()
+				return resp.StatusCode, nil
+			},
+			expectFunc: func(t *testing.T, status int, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusUnauthorized, status)
+				paymentRepo.AssertNotCalled(t, "ReadPaymentByProviderReference", mock.Anything)
+			},
+		},
+		{
+			name: "Replayed",
+			mockFunc: func(paymentRepo *mocks.MockPaymentRepository, payment *models.Payment) {
+				paymentRepo.On("ReadPaymentByProviderReference", "http_1").Return(payment, nil)
+				paymentRepo.On("ApplyWebhookEvent", "evt_1", payment, mock.Anything, mock.Anything, mock.Anything).
+					Return(services.ErrWebhookReplayed)
+			},
+			sendFunc: func(sender *webhook.Sender) (int, error) {
+				return sender.Send(context.Background(), event)
+			},
+			expectFunc: func(t *testing.T, status int, paymentRepo *mocks.MockPaymentRepository) {
+				assert.Equal(t, http.StatusConflict, status)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			paymentRepo := new(mocks.MockPaymentRepository)
+			payment := &models.Payment{
+				OrderID:           1,
+				Amount:            money.New(100, "USD"),
+				Status:            models.PaymentStatusPending,
+				Provider:          provider.NameHTTP,
+				ProviderReference: "http_1",
+			}
+			payment.ID = 1
+			tc.mockFunc(paymentRepo, payment)
+			paymentService := services.NewPaymentService(paymentRepo, newFakeMethods(provider.NewFakeProvider()), time.Hour)
+			webhookHandler := NewWebhookHandler(paymentService, secret, 5*time.Minute)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/webhooks/payments", webhookHandler.ReceivePaymentWebhook)
+			server := httptest.NewServer(router)
+			defer server.Close()
+			sender := webhook.NewSender(server.URL+"/webhooks/payments", secret)
+
+			// Act
+			status, err := tc.sendFunc(sender)
+
+			// Assert
+			assert.NoError(t, err)
+			tc.expectFunc(t, status, paymentRepo)
+		})
+	}
+}
@@ -2,23 +2,61 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/api/handlers"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(routes *gin.Engine, db *gorm.DB) {
+func SetupRoutes(routes *gin.Engine, db *gorm.DB, config *config.Config, log zerolog.Logger, paymentMethods *paymentmethod.Registry) {
+	tokenMaker, err := token.NewAccessTokenVerifier(token.VerifierConfig{
+		Format:       config.Auth.AccessTokenFormat,
+		Secret:       config.Auth.AccessTokenSecret,
+		JWKSURL:      config.Auth.JWKSURL,
+		JWKSCacheTTL: config.Auth.JWKSCacheTTL,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot create token maker")
+		return
+	}
+	revocationStore := token.NewGormRevocationStore(db)
 	paymentRepo := repository.NewPaymentRepository(db)
-	paymentService := services.NewPaymentService(paymentRepo)
+	paymentService := services.NewPaymentService(paymentRepo, paymentMethods, config.PaymentProvider.AuthorizationTTL)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
 
 	paymentGroup := routes.Group("payments")
+	// Payments are not filtered by their owner, so only admins manage them.
+	adminRoutes := paymentGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{"admin"}))
 	{
-		paymentGroup.POST("", paymentHandler.CreatePayment)
-		paymentGroup.GET("/:id", paymentHandler.ReadPayment)
-		paymentGroup.GET("", paymentHandler.ListPayments)
-		paymentGroup.PUT("/:id", paymentHandler.UpdatePayment)
-		paymentGroup.DELETE("/:id", paymentHandler.DeletePayment)
+		adminRoutes.POST("", paymentHandler.CreatePayment)
+		adminRoutes.GET("/:id", paymentHandler.ReadPayment)
+		adminRoutes.GET("", paymentHandler.ListPayments)
+		adminRoutes.PUT("/:id", paymentHandler.UpdatePayment)
+		adminRoutes.DELETE("/:id", paymentHandler.DeletePayment)
+		adminRoutes.POST("/:id/refresh", paymentHandler.RefreshPaymentStatus)
+		adminRoutes.POST("/:id/collection", paymentHandler.CollectPayment)
+		adminRoutes.POST("/:id/refunds", paymentHandler.CreateRefund)
+		adminRoutes.GET("/:id/refunds", paymentHandler.ListRefunds)
+	}
+	routes.GET("/payment-methods", paymentHandler.ListPaymentMethods)
+
+	ledgerRepo := repository.NewLedgerRepository(db)
+	ledgerService := services.NewLedgerService(ledgerRepo)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+
+	ledgerGroup := routes.Group("ledger").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{"admin"}))
+	{
+		ledgerGroup.GET("/balances", ledgerHandler.AccountBalances)
+		ledgerGroup.GET("/entries", ledgerHandler.ListJournalEntries)
+	}
+
+	if config.PaymentWebhook.Secret != "" {
+		webhookHandler := handlers.NewWebhookHandler(paymentService, []byte(config.PaymentWebhook.Secret), config.PaymentWebhook.Tolerance)
+		routes.POST("/webhooks/payments", webhookHandler.ReceivePaymentWebhook)
 	}
 }
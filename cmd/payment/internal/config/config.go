@@ -2,8 +2,13 @@ package config
 
 import (
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/token"
+	"github.com/tricong1998/go-ecom/pkg/util"
 )
 
 type DBConfig struct {
@@ -24,6 +29,19 @@ type GrpcServerConfig struct {
 	Port string
 }
 
+// AuthConfig verifies access tokens with the public keys published at
+// JWKSURL by the user service, or with AccessTokenSecret when it is empty.
+// AccessTokenFormat has to match the format of the user service.
+type AuthConfig struct {
+	AccessTokenFormat    string
+	AccessTokenDuration  time.Duration
+	AccessTokenSecret    string
+	RefreshTokenSecret   string
+	RefreshTokenDuration time.Duration
+	JWKSURL              string
+	JWKSCacheTTL         time.Duration
+}
+
 type RabbitMQConfig struct {
 	Host     string
 	Port     string
@@ -31,11 +49,49 @@ type RabbitMQConfig struct {
 	Password string
 }
 
+type PaymentProviderConfig struct {
+	Name    string
+	URL     string
+	APIKey  string
+	Timeout time.Duration
+	// AuthorizationTTL is how long an authorized payment can be captured.
+	AuthorizationTTL time.Duration
+}
+
+// PaymentWebhookConfig verifies the callbacks of the payment provider. The
+// webhook endpoint is disabled without a secret.
+type PaymentWebhookConfig struct {
+	Secret    string
+	Tolerance time.Duration
+}
+
+// PaymentMethodConfig is one payment method customers can choose, read from
+// the PAYMENT_METHOD_<NAME>_* variables. Amounts are in minor units, and not
+// bounded when zero.
+type PaymentMethodConfig struct {
+	Name      string
+	Enabled   bool
+	MinAmount int64
+	MaxAmount int64
+	Provider  string
+}
+
+type IdempotencyConfig struct {
+	KeyTTL time.Duration
+}
+
 type Config struct {
-	Server     HttpServerConfig
-	GrpcServer GrpcServerConfig
-	DB         DBConfig
-	Env        string
+	Server              HttpServerConfig
+	GrpcServer          GrpcServerConfig
+	DB                  DBConfig
+	RabbitMQConfig      RabbitMQConfig
+	Env                 string
+	Auth                AuthConfig
+	Idempotency         IdempotencyConfig
+	PaymentProvider     PaymentProviderConfig
+	PaymentWebhook      PaymentWebhookConfig
+	PaymentMethods      []PaymentMethodConfig
+	OrderProcessingMode string
 }
 
 func Load() (*Config, error) {
@@ -46,7 +102,8 @@ func Load() (*Config, error) {
 	}
 
 	config := &Config{
-		Env: os.Getenv("APP_ENV"),
+		Env:                 os.Getenv("APP_ENV"),
+		OrderProcessingMode: os.Getenv("ORDER_PROCESSING_MODE"),
 		Server: HttpServerConfig{
 			Port: os.Getenv("PAYMENT_SERVER_PORT"),
 			Host: os.Getenv("PAYMENT_SERVER_HOST"),
@@ -62,6 +119,53 @@ func Load() (*Config, error) {
 			DBPassword: os.Getenv("DB_PASSWORD"),
 			DBName:     os.Getenv("DB_NAME"),
 		},
+		RabbitMQConfig: RabbitMQConfig{
+			Port:     os.Getenv("AMQP_SERVER_PORT"),
+			Host:     os.Getenv("AMQP_SERVER_HOST"),
+			User:     os.Getenv("AMQP_SERVER_USER"),
+			Password: os.Getenv("AMQP_SERVER_PASSWORD"),
+		},
+		Auth: AuthConfig{
+			AccessTokenFormat:    os.Getenv("ACCESS_TOKEN_FORMAT"),
+			AccessTokenSecret:    os.Getenv("ACCESS_TOKEN_SECRET"),
+			RefreshTokenSecret:   os.Getenv("REFRESH_TOKEN_SECRET"),
+			AccessTokenDuration:  util.ParseDuration(os.Getenv("ACCESS_TOKEN_DURATION"), 15*time.Minute),
+			RefreshTokenDuration: util.ParseDuration(os.Getenv("REFRESH_TOKEN_DURATION"), 24*time.Hour),
+			JWKSURL:              os.Getenv("JWKS_URL"),
+			JWKSCacheTTL:         util.ParseDuration(os.Getenv("JWKS_CACHE_TTL"), 10*time.Minute),
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL: util.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"), 24*time.Hour),
+		},
+		PaymentProvider: PaymentProviderConfig{
+			Name:             os.Getenv("PAYMENT_PROVIDER"),
+			URL:              os.Getenv("PAYMENT_PROVIDER_URL"),
+			APIKey:           os.Getenv("PAYMENT_PROVIDER_API_KEY"),
+			Timeout:          util.ParseDuration(os.Getenv("PAYMENT_PROVIDER_TIMEOUT"), 10*time.Second),
+			AuthorizationTTL: util.ParseDuration(os.Getenv("PAYMENT_AUTHORIZATION_TTL"), 7*24*time.Hour),
+		},
+		PaymentWebhook: PaymentWebhookConfig{
+			Secret:    os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+			Tolerance: util.ParseDuration(os.Getenv("PAYMENT_WEBHOOK_TOLERANCE"), 5*time.Minute),
+		},
+	}
+
+	if config.PaymentProvider.Name == "" {
+		config.PaymentProvider.Name = "fake"
+	}
+	config.PaymentMethods = []PaymentMethodConfig{
+		loadPaymentMethod("cash", true, "cod"),
+		loadPaymentMethod("card", true, config.PaymentProvider.Name),
+		loadPaymentMethod("wallet", true, config.PaymentProvider.Name),
+		loadPaymentMethod("points", false, config.PaymentProvider.Name),
+	}
+
+	if config.OrderProcessingMode == "" {
+		config.OrderProcessingMode = events.ProcessingModeSync
+	}
+
+	if config.Auth.AccessTokenFormat == "" {
+		config.Auth.AccessTokenFormat = token.FormatJWT
 	}
 
 	if config.Server.Port == "" {
@@ -87,3 +191,20 @@ func Load() (*Config, error) {
 
 	return config, nil
 }
+
+// loadPaymentMethod reads the configuration of the payment method name, with
+// the given defaults.
+func loadPaymentMethod(name string, enabled bool, provider string) PaymentMethodConfig {
+	prefix := "PAYMENT_METHOD_" + strings.ToUpper(name) + "_"
+	method := PaymentMethodConfig{
+		Name:      name,
+		Enabled:   util.ParseBool(os.Getenv(prefix+"ENABLED"), enabled),
+		MinAmount: util.ParseInt64(os.Getenv(prefix+"MIN_AMOUNT"), 0),
+		MaxAmount: util.ParseInt64(os.Getenv(prefix+"MAX_AMOUNT"), 0),
+		Provider:  os.Getenv(prefix + "PROVIDER"),
+	}
+	if method.Provider == "" {
+		method.Provider = provider
+	}
+	return method
+}
@@ -5,6 +5,10 @@ import (
 
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/idempotency"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -32,9 +36,48 @@ func Initialize(dbConfig *config.DBConfig) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.Payment{},
+		&models.Refund{},
+		&models.WebhookEvent{},
+		&models.JournalEntry{},
+		&models.JournalLine{},
+		&idempotency.Record{},
+		&rabbitmq.OutboxMessage{},
+		&token.RevokedToken{},
+		&token.RevokedUserTokens{},
 		// &models.Order{},
 		// Add other models here as needed
 	)
+	if err != nil {
+		return err
+	}
+
+	return migrateMoneyColumns(db)
+}
+
+// migrateMoneyColumns moves the amounts that used to be plain integers to
+// their money columns. They never had a currency, so they get the default one,
+// and so do the journal lines posted before lines had a currency.
+func migrateMoneyColumns(db *gorm.DB) error {
+	columns := []struct {
+		model  interface{}
+		column string
+		prefix string
+	}{
+		{&models.Payment{}, "amount", "amount_"},
+		{&models.Payment{}, "fee", "fee_"},
+		{&models.Payment{}, "refunded_amount", "refunded_amount_"},
+		{&models.Refund{}, "amount", "amount_"},
+	}
+	for _, c := range columns {
+		err := money.MigrateColumn(db, c.model, c.column, "", c.prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.Model(&models.JournalLine{}).
+		Where("currency IS NULL OR currency = ''").
+		Update("currency", money.DefaultCurrency).Error
 }
@@ -2,10 +2,16 @@ package grpc_handler
 
 import (
 	"context"
+	"errors"
 
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/provider"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
@@ -25,37 +31,142 @@ func (server *Server) ReadPayment(_ context.Context, input *pb.ReadPaymentReques
 	if err != nil {
 		return nil, err
 	}
-	return &pb.Payment{
-		Id:        uint64(payment.ID),
-		OrderId:   uint64(payment.OrderID),
-		UserId:    uint64(payment.UserID),
-		Amount:    uint64(payment.Amount),
-		Status:    payment.Status,
-		CreatedAt: payment.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt: payment.UpdatedAt.Format("2006-01-02 15:04:05"),
-	}, nil
+	return toPbPayment(payment), nil
 }
 
 func (server *Server) CreatePayment(ctx context.Context, input *pb.CreatePaymentRequest) (*pb.CreatePaymentResponse, error) {
 	payment := models.Payment{
 		OrderID: (uint)(input.GetOrderId()),
 		UserID:  (uint)(input.GetUserId()),
-		Amount:  (uint)(input.GetAmount()),
+		Amount:  money.FromProto(input.GetAmount()),
 		Method:  input.GetMethod(),
 	}
 	err := server.PaymentService.CreatePayment(&payment)
 	if err != nil {
-		return nil, err
+		return nil, toGrpcError(err)
 	}
 	return &pb.CreatePaymentResponse{
-		Payment: &pb.Payment{
-			Id:        uint64(payment.ID),
-			OrderId:   uint64(payment.OrderID),
-			UserId:    uint64(payment.UserID),
-			Amount:    uint64(payment.Amount),
-			Status:    payment.Status,
-			CreatedAt: payment.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: payment.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		Payment: toPbPayment(&payment),
+	}, nil
+}
+
+func (server *Server) RefundPayment(ctx context.Context, input *pb.RefundPaymentRequest) (*pb.RefundPaymentResponse, error) {
+	refund := models.Refund{
+		PaymentID: (uint)(input.GetPaymentId()),
+		Amount:    money.FromProto(input.GetAmount()),
+		Reason:    input.GetReason(),
+	}
+	payment, err := server.PaymentService.RefundPayment(&refund)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.RefundPaymentResponse{
+		Payment: toPbPayment(payment),
+	}
+	// A no-op refund of a fully refunded payment, or the void of an uncollected
+	// cash on delivery payment, does not create a refund.
+	if refund.ID != 0 {
+		response.Refund = &pb.Refund{
+			Id:        uint64(refund.ID),
+			PaymentId: uint64(refund.PaymentID),
+			Amount:    money.ToProto(refund.Amount),
+			Reason:    refund.Reason,
+			Status:    refund.Status,
+			CreatedAt: refund.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+	}
+	return response, nil
+}
+
+func (server *Server) AuthorizePayment(ctx context.Context, input *pb.AuthorizePaymentRequest) (*pb.AuthorizePaymentResponse, error) {
+	payment := models.Payment{
+		OrderID: (uint)(input.GetOrderId()),
+		UserID:  (uint)(input.GetUserId()),
+		Amount:  money.FromProto(input.GetAmount()),
+		Method:  input.GetMethod(),
+	}
+	err := server.PaymentService.AuthorizePayment(&payment)
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+	return &pb.AuthorizePaymentResponse{
+		Payment: toPbPayment(&payment),
+	}, nil
+}
+
+func (server *Server) CapturePayment(ctx context.Context, input *pb.CapturePaymentRequest) (*pb.CapturePaymentResponse, error) {
+	payment, err := server.PaymentService.CapturePayment((uint)(input.GetPaymentId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CapturePaymentResponse{
+		Payment: toPbPayment(payment),
 	}, nil
 }
+
+func (server *Server) VoidPayment(ctx context.Context, input *pb.VoidPaymentRequest) (*pb.VoidPaymentResponse, error) {
+	payment, err := server.PaymentService.VoidPayment((uint)(input.GetPaymentId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.VoidPaymentResponse{
+		Payment: toPbPayment(payment),
+	}, nil
+}
+
+func (server *Server) ListPaymentMethods(_ context.Context, _ *pb.ListPaymentMethodsRequest) (*pb.ListPaymentMethodsResponse, error) {
+	var methods []*pb.PaymentMethod
+	for _, method := range server.PaymentService.ListPaymentMethods() {
+		methods = append(methods, toPbPaymentMethod(&method))
+	}
+	return &pb.ListPaymentMethodsResponse{Methods: methods}, nil
+}
+
+// ValidatePaymentMethod answers InvalidArgument when the method cannot pay
+// the amount.
+func (server *Server) ValidatePaymentMethod(_ context.Context, input *pb.ValidatePaymentMethodRequest) (*pb.ValidatePaymentMethodResponse, error) {
+	method, err := server.PaymentService.ValidatePaymentMethod(input.GetMethod(), money.FromProto(input.GetAmount()))
+	if err != nil {
+		return nil, toGrpcError(err)
+	}
+	return &pb.ValidatePaymentMethodResponse{Method: toPbPaymentMethod(method)}, nil
+}
+
+// toGrpcError answers InvalidArgument for a payment the service refuses to
+// create, and leaves the other errors as they are.
+func toGrpcError(err error) error {
+	if paymentmethod.IsValidationError(err) || errors.Is(err, services.ErrInvalidAmount) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
+func toPbPaymentMethod(method *paymentmethod.Method) *pb.PaymentMethod {
+	return &pb.PaymentMethod{
+		Name:      method.Name,
+		Enabled:   method.Enabled,
+		MinAmount: method.MinAmount,
+		MaxAmount: method.MaxAmount,
+	}
+}
+
+func toPbPayment(payment *models.Payment) *pb.Payment {
+	var authorizationExpiresAt string
+	if payment.AuthorizationExpiresAt != nil {
+		authorizationExpiresAt = payment.AuthorizationExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	return &pb.Payment{
+		Id:                     uint64(payment.ID),
+		OrderId:                uint64(payment.OrderID),
+		UserId:                 uint64(payment.UserID),
+		Amount:                 money.ToProto(payment.Amount),
+		Method:                 payment.Method,
+		Status:                 payment.Status,
+		RefundedAmount:         money.ToProto(payment.RefundedAmount),
+		AuthorizationExpiresAt: authorizationExpiresAt,
+		CollectOnDelivery:      payment.Provider == provider.NameCashOnDelivery,
+		CreatedAt:              payment.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:              payment.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,113 @@
+// Package ledger builds the double-entry journal entries of the payment
+// service. Money paid by a customer is debited to the customer account and
+// credited to the merchant, the processor fee is then moved from the merchant
+// to the fees account, and refunds are debited to the refunds account and
+// credited back to the customer.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+const (
+	AccountCustomer = "customer"
+	AccountMerchant = "merchant"
+	AccountRefunds  = "refunds"
+	AccountFees     = "fees"
+)
+
+// Accounts lists every account of the ledger.
+var Accounts = []string{AccountCustomer, AccountMerchant, AccountRefunds, AccountFees}
+
+const (
+	EntryTypeCharge  = "charge"
+	EntryTypeCapture = "capture"
+	EntryTypeRefund  = "refund"
+)
+
+var (
+	ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+	ErrUnknownAccount  = errors.New("unknown ledger account")
+)
+
+// ChargeEntry records the money taken for a payment, by an immediate charge or
+// by the capture of an authorization.
+func ChargeEntry(entryType string, payment *models.Payment) models.JournalEntry {
+	entry := models.JournalEntry{
+		PaymentID:   payment.ID,
+		Type:        entryType,
+		Description: fmt.Sprintf("%s of payment %d for order %d", entryType, payment.ID, payment.OrderID),
+		Lines: []models.JournalLine{
+			debit(AccountCustomer, payment.Amount),
+			credit(AccountMerchant, payment.Amount),
+		},
+	}
+	if payment.Fee.Amount > 0 {
+		entry.Lines = append(entry.Lines,
+			debit(AccountMerchant, payment.Fee),
+			credit(AccountFees, payment.Fee),
+		)
+	}
+	return entry
+}
+
+// RefundEntry records money given back to the customer. The processor fee is
+// not returned.
+func RefundEntry(payment *models.Payment, refund *models.Refund) models.JournalEntry {
+	return models.JournalEntry{
+		PaymentID:   payment.ID,
+		RefundID:    refund.ID,
+		Type:        EntryTypeRefund,
+		Description: fmt.Sprintf("refund %d of payment %d for order %d", refund.ID, payment.ID, payment.OrderID),
+		Lines: []models.JournalLine{
+			debit(AccountRefunds, refund.Amount),
+			credit(AccountCustomer, refund.Amount),
+		},
+	}
+}
+
+func debit(account string, amount money.Money) models.JournalLine {
+	return models.JournalLine{Account: account, Currency: amount.Currency, Debit: amount.Amount}
+}
+
+func credit(account string, amount money.Money) models.JournalLine {
+	return models.JournalLine{Account: account, Currency: amount.Currency, Credit: amount.Amount}
+}
+
+// Validate checks that every line uses a known account, sets exactly one
+// positive side and is in the currency of the other lines, and that the
+// debits of the entry equal its credits.
+func Validate(entry *models.JournalEntry) error {
+	if len(entry.Lines) == 0 {
+		return fmt.Errorf("%w: entry has no lines", ErrUnbalancedEntry)
+	}
+	currency := entry.Lines[0].Currency
+	debits, credits := money.Zero(currency), money.Zero(currency)
+	for _, line := range entry.Lines {
+		if !slices.Contains(Accounts, line.Account) {
+			return fmt.Errorf("%w: %s", ErrUnknownAccount, line.Account)
+		}
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit == 0) == (line.Credit == 0) {
+			return fmt.Errorf("%w: line of %s must either debit or credit", ErrUnbalancedEntry, line.Account)
+		}
+
+		var err error
+		debits, err = debits.Add(money.New(line.Debit, line.Currency))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnbalancedEntry, err)
+		}
+		credits, err = credits.Add(money.New(line.Credit, line.Currency))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnbalancedEntry, err)
+		}
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: %s debited, %s credited", ErrUnbalancedEntry, debits, credits)
+	}
+	return nil
+}
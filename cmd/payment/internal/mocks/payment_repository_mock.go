@@ -19,6 +19,11 @@ func (m *MockPaymentRepository) ReadPayment(id uint) (*models.Payment, error) {
 	return args.Get(0).(*models.Payment), args.Error(1)
 }
 
+func (m *MockPaymentRepository) ReadPaymentByOrderId(orderId uint) (*models.Payment, error) {
+	args := m.Called(orderId)
+	return args.Get(0).(*models.Payment), args.Error(1)
+}
+
 func (m *MockPaymentRepository) ListPayments(
 	perPage, page int32,
 	userId *uint,
@@ -27,8 +32,8 @@ func (m *MockPaymentRepository) ListPayments(
 	return args.Get(0).([]models.Payment), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockPaymentRepository) UpdatePayment(payment *models.Payment) error {
-	args := m.Called(payment)
+func (m *MockPaymentRepository) UpdatePayment(payment *models.Payment, entries ...models.JournalEntry) error {
+	args := m.Called(payment, entries)
 	return args.Error(0)
 }
 
@@ -36,3 +41,34 @@ func (m *MockPaymentRepository) DeletePayment(id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+
+func (m *MockPaymentRepository) CreateRefund(refund *models.Refund) (*models.Payment, error) {
+	args := m.Called(refund)
+	return args.Get(0).(*models.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CompleteRefund(refund *models.Refund) (*models.Payment, error) {
+	args := m.Called(refund)
+	return args.Get(0).(*models.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ListRefunds(paymentId uint) ([]models.Refund, error) {
+	args := m.Called(paymentId)
+	return args.Get(0).([]models.Refund), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ReadPaymentByProviderReference(reference string) (*models.Payment, error) {
+	args := m.Called(reference)
+	return args.Get(0).(*models.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) ApplyWebhookEvent(
+	eventId string,
+	payment *models.Payment,
+	routingKey string,
+	msg interface{},
+	entries ...models.JournalEntry,
+) error {
+	args := m.Called(eventId, payment, routingKey, msg, entries)
+	return args.Error(0)
+}
@@ -0,0 +1,111 @@
+// Package paymentmethod keeps the ways customers can pay, the amounts each of
+// them accepts and the provider that charges it.
+package paymentmethod
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/provider"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+const (
+	Cash   = "cash"
+	Card   = "card"
+	Wallet = "wallet"
+	Points = "points"
+)
+
+var (
+	ErrUnknownMethod    = errors.New("unknown payment method")
+	ErrMethodDisabled   = errors.New("payment method is disabled")
+	ErrAmountOutOfRange = errors.New("amount is out of the range of the payment method")
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+)
+
+// Method is a way to pay. MinAmount and MaxAmount bound the amounts it
+// accepts, in minor units of the payment currency, and are not checked when
+// zero. Provider names the provider that charges it.
+type Method struct {
+	Name      string `json:"name"`
+	Enabled   bool   `json:"enabled"`
+	MinAmount int64  `json:"min_amount"`
+	MaxAmount int64  `json:"max_amount"`
+	Provider  string `json:"provider"`
+}
+
+// Registry resolves payment methods to their provider.
+type Registry struct {
+	methods   []Method
+	providers map[string]provider.PaymentProvider
+}
+
+// NewRegistry checks methods and returns their registry. Every enabled method
+// has to be charged by one of providers.
+func NewRegistry(methods []Method, providers ...provider.PaymentProvider) (*Registry, error) {
+	registry := Registry{providers: make(map[string]provider.PaymentProvider, len(providers))}
+	for _, p := range providers {
+		registry.providers[p.Name()] = p
+	}
+
+	seen := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		if seen[method.Name] {
+			return nil, fmt.Errorf("payment method %s is configured twice", method.Name)
+		}
+		seen[method.Name] = true
+
+		if method.MinAmount < 0 || method.MaxAmount < 0 ||
+			(method.MaxAmount > 0 && method.MinAmount > method.MaxAmount) {
+			return nil, fmt.Errorf("payment method %s has an invalid amount range", method.Name)
+		}
+		if _, ok := registry.providers[method.Provider]; method.Enabled && !ok {
+			return nil, fmt.Errorf("%w: %s for payment method %s", ErrUnknownProvider, method.Provider, method.Name)
+		}
+		registry.methods = append(registry.methods, method)
+	}
+	return &registry, nil
+}
+
+// Methods returns every method, enabled or not, in configuration order.
+func (r *Registry) Methods() []Method {
+	return append([]Method(nil), r.methods...)
+}
+
+// Resolve checks that the method exists, is enabled and accepts amount, and
+// returns it with its provider.
+func (r *Registry) Resolve(name string, amount money.Money) (*Method, provider.PaymentProvider, error) {
+	for i := range r.methods {
+		method := r.methods[i]
+		if method.Name != name {
+			continue
+		}
+		if !method.Enabled {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMethodDisabled, name)
+		}
+		if amount.Amount < method.MinAmount || (method.MaxAmount > 0 && amount.Amount > method.MaxAmount) {
+			return nil, nil, fmt.Errorf("%w: %s does not accept %s", ErrAmountOutOfRange, name, amount)
+		}
+		return &method, r.providers[method.Provider], nil
+	}
+	return nil, nil, fmt.Errorf("%w: %q", ErrUnknownMethod, name)
+}
+
+// Provider returns the provider called name. Payments keep the provider that
+// charged them, which is used for every later call about them.
+func (r *Registry) Provider(name string) (provider.PaymentProvider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// IsValidationError reports whether err rejects the method or amount of a
+// payment, as opposed to a failure of the service.
+func IsValidationError(err error) bool {
+	return errors.Is(err, ErrUnknownMethod) ||
+		errors.Is(err, ErrMethodDisabled) ||
+		errors.Is(err, ErrAmountOutOfRange)
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+// CashOnDeliveryProvider accepts every order. Nothing is held by an
+// authorization, so capturing it leaves the charge pending: the order is
+// complete, with the cash due on delivery, and the payment service records
+// when it is collected. Only collected charges can be refunded, and the cash
+// is handed back outside of the provider.
+type CashOnDeliveryProvider struct{}
+
+func NewCashOnDeliveryProvider() *CashOnDeliveryProvider {
+	return &CashOnDeliveryProvider{}
+}
+
+func (p *CashOnDeliveryProvider) Name() string {
+	return NameCashOnDelivery
+}
+
+func (p *CashOnDeliveryProvider) Charge(_ context.Context, _ ChargeRequest) (*Result, error) {
+	return &Result{
+		Reference: "cod_" + uuid.NewV4().String(),
+		Status:    StatusPending,
+		Message:   "to be paid on delivery",
+	}, nil
+}
+
+func (p *CashOnDeliveryProvider) Authorize(_ context.Context, _ ChargeRequest) (*Result, error) {
+	return &Result{
+		Reference: "cod_" + uuid.NewV4().String(),
+		Status:    StatusAuthorized,
+	}, nil
+}
+
+func (p *CashOnDeliveryProvider) Capture(_ context.Context, reference string, _ money.Money) (*Result, error) {
+	return &Result{Reference: reference, Status: StatusPending, Message: "to be paid on delivery"}, nil
+}
+
+func (p *CashOnDeliveryProvider) Void(_ context.Context, reference string) (*Result, error) {
+	return &Result{Reference: reference, Status: StatusVoided}, nil
+}
+
+func (p *CashOnDeliveryProvider) Refund(_ context.Context, reference string, _ money.Money) (*Result, error) {
+	return &Result{Reference: reference, Status: StatusRefunded}, nil
+}
+
+func (p *CashOnDeliveryProvider) Status(_ context.Context, reference string) (*Result, error) {
+	return &Result{Reference: reference, Status: StatusPending}, nil
+}
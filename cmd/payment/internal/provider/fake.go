@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+const (
+	// FakeCardDeclined is a card token the fake provider always declines.
+	FakeCardDeclined = "tok_declined"
+	// FakeCardUnavailable makes the fake provider fail with an error, as if
+	// the processor were unavailable.
+	FakeCardUnavailable = "tok_unavailable"
+	// FakeAmountLimit is the smallest amount, in minor units, the fake
+	// provider declines.
+	FakeAmountLimit = 1_000_000
+	// FakeFeeBasisPoints is the share of a captured amount the fake provider
+	// keeps as its fee.
+	FakeFeeBasisPoints = 290
+)
+
+var (
+	ErrFakeUnavailable    = errors.New("fake provider is unavailable")
+	ErrFakeRefundTooLarge = errors.New("refund exceeds the charged amount")
+	ErrFakeCurrency       = errors.New("currency differs from the charge")
+)
+
+// FakeProvider is a deterministic provider for tests and local development.
+// The outcome of a charge only depends on its amount and card token.
+type FakeProvider struct {
+	mu      sync.Mutex
+	charges map[string]*fakeCharge
+	next    int
+}
+
+type fakeCharge struct {
+	result   Result
+	amount   money.Money
+	refunded money.Money
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{charges: make(map[string]*fakeCharge)}
+}
+
+func (p *FakeProvider) Name() string {
+	return NameFake
+}
+
+func (p *FakeProvider) Charge(_ context.Context, req ChargeRequest) (*Result, error) {
+	return p.create(req, StatusSucceeded)
+}
+
+// Authorize holds the amount like Charge would take it, with the same
+// outcome.
+func (p *FakeProvider) Authorize(_ context.Context, req ChargeRequest) (*Result, error) {
+	return p.create(req, StatusAuthorized)
+}
+
+func (p *FakeProvider) Capture(_ context.Context, reference string, amount money.Money) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	switch charge.result.Status {
+	case StatusSucceeded:
+	case StatusAuthorized:
+		cmp, err := amount.Cmp(charge.amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFakeCurrency, err)
+		}
+		if cmp > 0 {
+			return nil, fmt.Errorf("cannot capture more than the authorized %s", charge.amount)
+		}
+		fee, err := fakeFee(amount)
+		if err != nil {
+			return nil, err
+		}
+		charge.amount = amount
+		charge.result.Status = StatusSucceeded
+		charge.result.Fee = fee
+	default:
+		return nil, fmt.Errorf("cannot capture a %s charge", charge.result.Status)
+	}
+
+	copied := charge.result
+	return &copied, nil
+}
+
+func (p *FakeProvider) Void(_ context.Context, reference string) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	switch charge.result.Status {
+	case StatusVoided:
+	case StatusAuthorized:
+		charge.result.Status = StatusVoided
+	default:
+		return nil, fmt.Errorf("cannot void a %s charge", charge.result.Status)
+	}
+
+	copied := charge.result
+	return &copied, nil
+}
+
+// create records a new charge with the given status, unless the request is
+// one the fake provider declines.
+func (p *FakeProvider) create(req ChargeRequest, status string) (*Result, error) {
+	if req.CardToken == FakeCardUnavailable {
+		return nil, ErrFakeUnavailable
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.next++
+	result := &Result{
+		Reference: fmt.Sprintf("fake_%d", p.next),
+		Status:    status,
+	}
+	switch {
+	case req.CardToken == FakeCardDeclined:
+		result.Status = StatusDeclined
+		result.Message = "card declined"
+	case req.Amount.Amount >= FakeAmountLimit:
+		result.Status = StatusDeclined
+		result.Message = "amount exceeds limit"
+	}
+	if result.Status == StatusSucceeded {
+		fee, err := fakeFee(req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		result.Fee = fee
+	}
+	p.charges[result.Reference] = &fakeCharge{
+		result:   *result,
+		amount:   req.Amount,
+		refunded: money.Zero(req.Amount.Currency),
+	}
+
+	return result, nil
+}
+
+// Refund refunds part of a charge. The charge itself only turns refunded once
+// its whole amount was refunded.
+func (p *FakeProvider) Refund(_ context.Context, reference string, amount money.Money) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	if charge.result.Status != StatusSucceeded {
+		return nil, fmt.Errorf("cannot refund a %s charge", charge.result.Status)
+	}
+	refunded, err := charge.refunded.Add(amount)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFakeCurrency, err)
+	}
+	if refunded.Amount > charge.amount.Amount {
+		return nil, ErrFakeRefundTooLarge
+	}
+	charge.refunded = refunded
+	if charge.refunded == charge.amount {
+		charge.result.Status = StatusRefunded
+	}
+
+	return &Result{Reference: reference, Status: StatusRefunded}, nil
+}
+
+func (p *FakeProvider) Status(_ context.Context, reference string) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[reference]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+
+	copied := charge.result
+	return &copied, nil
+}
+
+func fakeFee(amount money.Money) (money.Money, error) {
+	return amount.MulRate(FakeFeeBasisPoints, 10000, money.RoundHalfEven)
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// HTTPProvider calls a processor exposing a small JSON API:
+//
+//	POST {url}/charges                     creates a charge, or only
+//	                                       authorizes it when capture is false
+//	POST {url}/charges/{reference}/capture captures an authorized charge
+//	POST {url}/charges/{reference}/void    voids an authorized charge
+//	POST {url}/charges/{reference}/refund  refunds it
+//	GET  {url}/charges/{reference}         reads it
+//
+// Every call answers with {"id", "status", "message", "fee"}. Amounts and
+// fees are sent as {"amount", "currency"}, in minor units.
+type HTTPProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+type httpChargeRequest struct {
+	OrderId   uint        `json:"order_id"`
+	UserId    uint        `json:"user_id"`
+	Amount    money.Money `json:"amount"`
+	Method    string      `json:"method"`
+	CardToken string      `json:"card_token,omitempty"`
+	Capture   bool        `json:"capture"`
+}
+
+// httpAmountRequest is the body of the capture and refund calls.
+type httpAmountRequest struct {
+	Amount money.Money `json:"amount"`
+}
+
+type httpChargeResponse struct {
+	Id      string      `json:"id"`
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	Fee     money.Money `json:"fee"`
+}
+
+func NewHTTPProvider(baseURL, apiKey string, timeout time.Duration) *HTTPProvider {
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &HTTPProvider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPProvider) Name() string {
+	return NameHTTP
+}
+
+func (p *HTTPProvider) Charge(ctx context.Context, req ChargeRequest) (*Result, error) {
+	return p.createCharge(ctx, req, true)
+}
+
+func (p *HTTPProvider) Authorize(ctx context.Context, req ChargeRequest) (*Result, error) {
+	return p.createCharge(ctx, req, false)
+}
+
+func (p *HTTPProvider) Capture(ctx context.Context, reference string, amount money.Money) (*Result, error) {
+	return p.do(ctx, http.MethodPost, "/charges/"+url.PathEscape(reference)+"/capture", httpAmountRequest{Amount: amount})
+}
+
+func (p *HTTPProvider) Void(ctx context.Context, reference string) (*Result, error) {
+	return p.do(ctx, http.MethodPost, "/charges/"+url.PathEscape(reference)+"/void", nil)
+}
+
+func (p *HTTPProvider) Refund(ctx context.Context, reference string, amount money.Money) (*Result, error) {
+	return p.do(ctx, http.MethodPost, "/charges/"+url.PathEscape(reference)+"/refund", httpAmountRequest{Amount: amount})
+}
+
+func (p *HTTPProvider) createCharge(ctx context.Context, req ChargeRequest, capture bool) (*Result, error) {
+	return p.do(ctx, http.MethodPost, "/charges", httpChargeRequest{
+		OrderId:   req.OrderId,
+		UserId:    req.UserId,
+		Amount:    req.Amount,
+		Method:    req.Method,
+		CardToken: req.CardToken,
+		Capture:   capture,
+	})
+}
+
+func (p *HTTPProvider) Status(ctx context.Context, reference string) (*Result, error) {
+	return p.do(ctx, http.MethodGet, "/charges/"+url.PathEscape(reference), nil)
+}
+
+func (p *HTTPProvider) do(ctx context.Context, method, path string, body interface{}) (*Result, error) {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrChargeNotFound
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("payment provider responded with status %d", resp.StatusCode)
+	}
+
+	var charge httpChargeResponse
+	err = json.NewDecoder(resp.Body).Decode(&charge)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest && charge.Status == "" {
+		return nil, fmt.Errorf("payment provider responded with status %d: %s", resp.StatusCode, charge.Message)
+	}
+
+	return &Result{
+		Reference: charge.Id,
+		Status:    charge.Status,
+		Message:   charge.Message,
+		Fee:       charge.Fee,
+	}, nil
+}
@@ -0,0 +1,83 @@
+// Package provider talks to the payment processors. The processor used by the
+// payment service is chosen by configuration.
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+const (
+	NameFake           = "fake"
+	NameCashOnDelivery = "cod"
+	NameHTTP           = "http"
+)
+
+const (
+	StatusSucceeded  = "succeeded"
+	StatusPending    = "pending"
+	StatusDeclined   = "declined"
+	StatusFailed     = "failed"
+	StatusRefunded   = "refunded"
+	StatusAuthorized = "authorized"
+	StatusVoided     = "voided"
+)
+
+var ErrChargeNotFound = errors.New("charge not found")
+
+type ChargeRequest struct {
+	OrderId   uint
+	UserId    uint
+	Amount    money.Money
+	Method    string
+	CardToken string
+}
+
+// Result is the state of a charge at the processor. Reference identifies the
+// charge in later calls, and Fee is what the processor keeps of a charge that
+// succeeded.
+type Result struct {
+	Reference string
+	Status    string
+	Message   string
+	Fee       money.Money
+}
+
+// PaymentProvider charges payments in one step with Charge, or in two steps
+// with Authorize followed by Capture or Void.
+type PaymentProvider interface {
+	Name() string
+	Charge(ctx context.Context, req ChargeRequest) (*Result, error)
+	Authorize(ctx context.Context, req ChargeRequest) (*Result, error)
+	Capture(ctx context.Context, reference string, amount money.Money) (*Result, error)
+	Void(ctx context.Context, reference string) (*Result, error)
+	Refund(ctx context.Context, reference string, amount money.Money) (*Result, error)
+	Status(ctx context.Context, reference string) (*Result, error)
+}
+
+type Config struct {
+	Name    string
+	URL     string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// New returns the provider named in cfg.
+func New(cfg Config) (PaymentProvider, error) {
+	switch cfg.Name {
+	case NameFake, "":
+		return NewFakeProvider(), nil
+	case NameCashOnDelivery:
+		return NewCashOnDeliveryProvider(), nil
+	case NameHTTP:
+		if cfg.URL == "" {
+			return nil, errors.New("payment provider url is required")
+		}
+		return NewHTTPProvider(cfg.URL, cfg.APIKey, cfg.Timeout), nil
+	}
+	return nil, fmt.Errorf("unknown payment provider: %s", cfg.Name)
+}
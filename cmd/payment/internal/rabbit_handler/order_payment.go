@@ -0,0 +1,54 @@
+package rabbit_handler
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/events"
+)
+
+type OrderPaymentDependencies struct {
+	PaymentEventService *services.PaymentEventService
+	Logger              zerolog.Logger
+}
+
+func PayOrder(queue string, msg amqp.Delivery, dependencies *OrderPaymentDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockReserved events.StockReserved
+
+	err := json.Unmarshal(msg.Body, &stockReserved)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.PaymentEventService.PayOrder(stockReserved)
+}
+
+func RefundOrder(queue string, msg amqp.Delivery, dependencies *OrderPaymentDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockCommitFailed events.StockCommitFailed
+
+	err := json.Unmarshal(msg.Body, &stockCommitFailed)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.PaymentEventService.RefundOrder(stockCommitFailed)
+}
+
+func CaptureOrder(queue string, msg amqp.Delivery, dependencies *OrderPaymentDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var stockCommitted events.StockCommitted
+
+	err := json.Unmarshal(msg.Body, &stockCommitted)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.PaymentEventService.CaptureOrder(stockCommitted)
+}
@@ -0,0 +1,34 @@
+package rabbit_handler
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/token"
+)
+
+type TokenRevocationDependencies struct {
+	RevocationStore token.RevocationStore
+	Logger          zerolog.Logger
+}
+
+// TokensRevoked copies the revocations of the user service into the store
+// used by the auth middleware.
+func TokensRevoked(queue string, msg amqp.Delivery, dependencies *TokenRevocationDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var tokensRevoked events.TokensRevoked
+
+	err := json.Unmarshal(msg.Body, &tokensRevoked)
+	if err != nil {
+		return err
+	}
+
+	if tokensRevoked.TokenId != uuid.Nil {
+		return dependencies.RevocationStore.RevokeToken(tokensRevoked.TokenId, tokensRevoked.ExpiredAt)
+	}
+	return dependencies.RevocationStore.RevokeUserTokens(tokensRevoked.UserId, tokensRevoked.RevokedBefore)
+}
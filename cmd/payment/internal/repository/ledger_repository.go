@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/ledger"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+type ILedgerRepository interface {
+	AccountBalances(from, to time.Time) ([]models.AccountBalance, error)
+	ListJournalEntries(
+		from, to time.Time,
+		account *string,
+		perPage, page int32,
+	) ([]models.JournalEntry, int64, error)
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{db}
+}
+
+// accountSum is a row of the sums of the lines of an account in a currency.
+type accountSum struct {
+	Account  string
+	Currency string
+	Debit    int64
+	Credit   int64
+}
+
+// AccountBalances sums the lines posted to every account in [from, to), with
+// one balance per account and currency. Accounts without lines in the range
+// are returned with a zero balance in the default currency.
+func (ledgerRepo *LedgerRepository) AccountBalances(from, to time.Time) ([]models.AccountBalance, error) {
+	var sums []accountSum
+	err := ledgerRepo.db.Model(&models.JournalLine{}).
+		Select("account, currency, COALESCE(SUM(debit), 0) AS debit, COALESCE(SUM(credit), 0) AS credit").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("account, currency").
+		Order("currency").
+		Scan(&sums).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byAccount := make(map[string][]accountSum, len(ledger.Accounts))
+	for _, sum := range sums {
+		byAccount[sum.Account] = append(byAccount[sum.Account], sum)
+	}
+
+	balances := make([]models.AccountBalance, 0, len(ledger.Accounts))
+	for _, account := range ledger.Accounts {
+		accountSums := byAccount[account]
+		if len(accountSums) == 0 {
+			accountSums = []accountSum{{Account: account, Currency: money.DefaultCurrency}}
+		}
+		for _, sum := range accountSums {
+			debit := money.New(sum.Debit, sum.Currency)
+			credit := money.New(sum.Credit, sum.Currency)
+			balance, err := debit.Sub(credit)
+			if err != nil {
+				return nil, err
+			}
+			balances = append(balances, models.AccountBalance{
+				Account: account,
+				Debit:   debit,
+				Credit:  credit,
+				Balance: balance,
+			})
+		}
+	}
+	return balances, nil
+}
+
+// ListJournalEntries pages through the entries posted in [from, to), oldest
+// first. A non-nil account only keeps the entries with a line on it.
+func (ledgerRepo *LedgerRepository) ListJournalEntries(
+	from, to time.Time,
+	account *string,
+	perPage, page int32,
+) ([]models.JournalEntry, int64, error) {
+	var entries []models.JournalEntry
+	var total int64
+
+	query := ledgerRepo.db.Model(&models.JournalEntry{}).
+		Where("created_at >= ? AND created_at < ?", from, to)
+	if account != nil {
+		query = query.Where(
+			"id IN (?)",
+			ledgerRepo.db.Model(&models.JournalLine{}).Select("journal_entry_id").Where("account = ?", *account),
+		)
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Preload("Lines").
+		Order("created_at, id").
+		Limit(int(perPage)).
+		Offset(int((page - 1) * perPage)).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
@@ -1,8 +1,21 @@
 package repository
 
 import (
+	"errors"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/ledger"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentNotRefundable = errors.New("only successful or partially refunded payments can be refunded")
+	ErrRefundExceedsPayment = errors.New("refunds cannot exceed the captured amount")
+	ErrWebhookReplayed      = errors.New("webhook event was already received")
 )
 
 type PaymentRepository struct {
@@ -12,12 +25,24 @@ type PaymentRepository struct {
 type IPaymentRepository interface {
 	CreatePayment(input *models.Payment) error
 	ReadPayment(id uint) (*models.Payment, error)
+	ReadPaymentByOrderId(orderId uint) (*models.Payment, error)
+	ReadPaymentByProviderReference(reference string) (*models.Payment, error)
 	ListPayments(
 		perPage, page int32,
 		userId *uint,
 	) ([]models.Payment, int64, error)
-	UpdatePayment(input *models.Payment) error
+	UpdatePayment(input *models.Payment, entries ...models.JournalEntry) error
 	DeletePayment(id uint) error
+	CreateRefund(refund *models.Refund) (*models.Payment, error)
+	CompleteRefund(refund *models.Refund) (*models.Payment, error)
+	ListRefunds(paymentId uint) ([]models.Refund, error)
+	ApplyWebhookEvent(
+		eventId string,
+		payment *models.Payment,
+		routingKey string,
+		msg interface{},
+		entries ...models.JournalEntry,
+	) error
 }
 
 func NewPaymentRepository(db *gorm.DB) *PaymentRepository {
@@ -37,6 +62,24 @@ func (paymentRepo *PaymentRepository) ReadPayment(id uint) (*models.Payment, err
 	return payment, nil
 }
 
+func (paymentRepo *PaymentRepository) ReadPaymentByOrderId(orderId uint) (*models.Payment, error) {
+	var payment *models.Payment
+	err := paymentRepo.db.Where("order_id = ?", orderId).Order("id desc").First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+func (paymentRepo *PaymentRepository) ReadPaymentByProviderReference(reference string) (*models.Payment, error) {
+	var payment *models.Payment
+	err := paymentRepo.db.Where("provider_reference = ?", reference).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
 func (paymentRepo *PaymentRepository) ListPayments(
 	perPage, page int32,
 	userId *uint,
@@ -59,10 +102,198 @@ func (paymentRepo *PaymentRepository) ListPayments(
 	return payments, total, nil
 }
 
-func (paymentRepo *PaymentRepository) UpdatePayment(input *models.Payment) error {
-	return paymentRepo.db.Save(input).Error
+// UpdatePayment saves the payment together with the journal entries of the
+// money it moved, in a single transaction.
+func (paymentRepo *PaymentRepository) UpdatePayment(input *models.Payment, entries ...models.JournalEntry) error {
+	if len(entries) == 0 {
+		return paymentRepo.db.Save(input).Error
+	}
+
+	return paymentRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(input).Error
+		if err != nil {
+			return err
+		}
+		return writeJournalEntries(tx, entries)
+	})
 }
 
 func (paymentRepo *PaymentRepository) DeletePayment(id uint) error {
 	return paymentRepo.db.Delete(&models.Payment{}, id).Error
 }
+
+// CreateRefund records a pending refund of the payment. The payment row is
+// locked while the refunds are summed, so that concurrent refunds cannot
+// together exceed the payment amount. A zero amount refunds what is left.
+func (paymentRepo *PaymentRepository) CreateRefund(refund *models.Refund) (*models.Payment, error) {
+	var payment models.Payment
+	err := paymentRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error
+		if err != nil {
+			return err
+		}
+
+		if !payment.Refundable() {
+			return ErrPaymentNotRefundable
+		}
+
+		// Refunds are always in the currency of their payment.
+		var reserved int64
+		err = tx.Model(&models.Refund{}).
+			Where("payment_id = ? AND status <> ?", payment.ID, models.RefundStatusFailed).
+			Select("COALESCE(SUM(amount_minor), 0)").
+			Scan(&reserved).Error
+		if err != nil {
+			return err
+		}
+
+		remaining, err := payment.Amount.Sub(money.New(reserved, payment.Amount.Currency))
+		if err != nil {
+			return err
+		}
+		if remaining.IsNegative() {
+			remaining = money.Zero(payment.Amount.Currency)
+		}
+		if refund.Amount.IsZero() {
+			refund.Amount = remaining
+		}
+		cmp, err := refund.Amount.Cmp(remaining)
+		if err != nil {
+			return err
+		}
+		if refund.Amount.Amount <= 0 || cmp > 0 {
+			return ErrRefundExceedsPayment
+		}
+
+		refund.Status = models.RefundStatusPending
+		return tx.Create(refund).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// CompleteRefund stores the outcome of a refund. A succeeded refund is added
+// to the refunded amount of the payment and posted to the ledger, and a
+// payment.refunded event is written to the outbox in the same transaction.
+func (paymentRepo *PaymentRepository) CompleteRefund(refund *models.Refund) (*models.Payment, error) {
+	var payment models.Payment
+	err := paymentRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Save(refund).Error
+		if err != nil {
+			return err
+		}
+		if refund.Status != models.RefundStatusSucceeded {
+			return nil
+		}
+
+		payment.RefundedAmount, err = payment.RefundedAmount.Add(refund.Amount)
+		if err != nil {
+			return err
+		}
+		payment.Status = models.PaymentStatusPartiallyRefunded
+		if payment.RefundedAmount.Amount >= payment.Amount.Amount {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		err = tx.Save(&payment).Error
+		if err != nil {
+			return err
+		}
+		err = writeJournalEntries(tx, []models.JournalEntry{ledger.RefundEntry(&payment, refund)})
+		if err != nil {
+			return err
+		}
+
+		return rabbitmq.WriteOutbox(tx, rabbitmq.PAYMENT_REFUNDED_ROUTING_KEY, events.PaymentRefunded{
+			OrderId:        payment.OrderID,
+			PaymentId:      payment.ID,
+			RefundId:       refund.ID,
+			Amount:         refund.Amount,
+			RefundedAmount: payment.RefundedAmount,
+			Status:         payment.Status,
+			Reason:         refund.Reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+func (paymentRepo *PaymentRepository) ListRefunds(paymentId uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := paymentRepo.db.Where("payment_id = ?", paymentId).Order("id").Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// ApplyWebhookEvent records the callback eventId, saves the payment with its
+// journal entries and writes msg to the outbox in a single transaction. A callback that was already
+// recorded returns ErrWebhookReplayed and changes nothing. A nil msg saves the
+// payment without publishing anything.
+func (paymentRepo *PaymentRepository) ApplyWebhookEvent(
+	eventId string,
+	payment *models.Payment,
+	routingKey string,
+	msg interface{},
+	entries ...models.JournalEntry,
+) error {
+	return paymentRepo.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.WebhookEvent{}).Where("event_id = ?", eventId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrWebhookReplayed
+		}
+
+		err = tx.Create(&models.WebhookEvent{
+			EventId:   eventId,
+			PaymentID: payment.ID,
+			Status:    payment.Status,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Save(payment).Error
+		if err != nil {
+			return err
+		}
+		err = writeJournalEntries(tx, entries)
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			return nil
+		}
+		return rabbitmq.WriteOutbox(tx, routingKey, msg)
+	})
+}
+
+// writeJournalEntries adds balanced entries to the ledger. An unbalanced
+// entry fails the surrounding transaction.
+func writeJournalEntries(tx *gorm.DB, entries []models.JournalEntry) error {
+	for i := range entries {
+		err := ledger.Validate(&entries[i])
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&entries[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
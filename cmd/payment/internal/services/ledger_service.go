@@ -0,0 +1,37 @@
+package services
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+)
+
+type LedgerService struct {
+	LedgerRepo repository.ILedgerRepository
+}
+
+type ILedgerService interface {
+	AccountBalances(from, to time.Time) ([]models.AccountBalance, error)
+	ListJournalEntries(
+		from, to time.Time,
+		account *string,
+		perPage, page int32,
+	) ([]models.JournalEntry, int64, error)
+}
+
+func NewLedgerService(ledgerRepo repository.ILedgerRepository) *LedgerService {
+	return &LedgerService{ledgerRepo}
+}
+
+func (ls *LedgerService) AccountBalances(from, to time.Time) ([]models.AccountBalance, error) {
+	return ls.LedgerRepo.AccountBalances(from, to)
+}
+
+func (ls *LedgerService) ListJournalEntries(
+	from, to time.Time,
+	account *string,
+	perPage, page int32,
+) ([]models.JournalEntry, int64, error) {
+	return ls.LedgerRepo.ListJournalEntries(from, to, account, perPage, page)
+}
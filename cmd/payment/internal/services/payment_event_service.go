@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"gorm.io/gorm"
+)
+
+// PaymentEventService authorizes, captures and refunds orders processed
+// asynchronously, and writes the outcome to the outbox for the other services.
+type PaymentEventService struct {
+	PaymentService *PaymentService
+	Outbox         rabbitmq.IOutboxWriter
+}
+
+func NewPaymentEventService(paymentService *PaymentService, outbox rabbitmq.IOutboxWriter) *PaymentEventService {
+	return &PaymentEventService{paymentService, outbox}
+}
+
+// PayOrder authorizes the payment of an order once its stock is reserved. An
+// order is only authorized once: a redelivered event publishes the outcome of
+// the existing payment again.
+func (s *PaymentEventService) PayOrder(event events.StockReserved) error {
+	payment, err := s.PaymentService.PaymentRepo.ReadPaymentByOrderId(event.OrderId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		payment = &models.Payment{
+			OrderID: event.OrderId,
+			UserID:  event.UserId,
+			Amount:  event.Amount,
+			Method:  event.PaymentMethod,
+		}
+		// Orders placed before they had a payment method are paid in cash.
+		if payment.Method == "" {
+			payment.Method = paymentmethod.Cash
+		}
+		err = s.PaymentService.AuthorizePayment(payment)
+		if paymentmethod.IsValidationError(err) || errors.Is(err, ErrInvalidAmount) {
+			return s.Outbox.Write(rabbitmq.PAYMENT_FAILED_ROUTING_KEY, events.PaymentFailed{
+				OrderId:       event.OrderId,
+				ReservationId: event.ReservationId,
+				Reason:        err.Error(),
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if payment.Status == PaymentStatusFailed {
+		return s.Outbox.Write(rabbitmq.PAYMENT_FAILED_ROUTING_KEY, events.PaymentFailed{
+			OrderId:       event.OrderId,
+			PaymentId:     payment.ID,
+			ReservationId: event.ReservationId,
+			Reason:        ErrPaymentDeclined.Error(),
+		})
+	}
+
+	return s.Outbox.Write(rabbitmq.PAYMENT_SUCCEEDED_ROUTING_KEY, events.PaymentSucceeded{
+		OrderId:       event.OrderId,
+		PaymentId:     payment.ID,
+		ReservationId: event.ReservationId,
+	})
+}
+
+// CaptureOrder captures the payment of an order once its stock is committed.
+// A payment that cannot be captured is voided, and reported as failed so the
+// stock is released again.
+func (s *PaymentEventService) CaptureOrder(event events.StockCommitted) error {
+	payment, err := s.PaymentService.CapturePayment(event.PaymentId)
+	if err == nil {
+		return s.Outbox.Write(rabbitmq.PAYMENT_CAPTURED_ROUTING_KEY, events.PaymentCaptured{
+			OrderId:       event.OrderId,
+			PaymentId:     payment.ID,
+			ReservationId: event.ReservationId,
+		})
+	}
+	if !errors.Is(err, ErrPaymentNotAuthorized) && !errors.Is(err, ErrAuthorizationExpired) {
+		return err
+	}
+
+	_, voidErr := s.PaymentService.VoidPayment(event.PaymentId)
+	if voidErr != nil && !errors.Is(voidErr, ErrPaymentNotAuthorized) {
+		return voidErr
+	}
+	return s.Outbox.Write(rabbitmq.PAYMENT_FAILED_ROUTING_KEY, events.PaymentFailed{
+		OrderId:       event.OrderId,
+		PaymentId:     event.PaymentId,
+		ReservationId: event.ReservationId,
+		Reason:        err.Error(),
+	})
+}
+
+// RefundOrder gives the payment of an order whose stock could not be
+// committed back: an authorization is voided and a captured payment refunded.
+func (s *PaymentEventService) RefundOrder(event events.StockCommitFailed) error {
+	payment, err := s.PaymentService.ReadPayment(event.PaymentId)
+	if err != nil {
+		return err
+	}
+	if payment.Status == PaymentStatusAuthorized {
+		_, err = s.PaymentService.VoidPayment(payment.ID)
+		return err
+	}
+
+	_, err = s.PaymentService.RefundPayment(&models.Refund{
+		PaymentID: event.PaymentId,
+		Reason:    event.Reason,
+	})
+	return err
+}
@@ -1,19 +1,50 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/ledger"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/paymentmethod"
+	"github.com/tricong1998/go-ecom/cmd/payment/internal/provider"
 	"github.com/tricong1998/go-ecom/cmd/payment/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
-	"golang.org/x/exp/rand"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
 )
 
 const (
-	PaymentStatusPending = "pending"
-	PaymentStatusSuccess = "success"
-	PaymentStatusFailed  = "failed"
+	PaymentStatusPending           = models.PaymentStatusPending
+	PaymentStatusAuthorized        = models.PaymentStatusAuthorized
+	PaymentStatusVoided            = models.PaymentStatusVoided
+	PaymentStatusSuccess           = models.PaymentStatusSuccess
+	PaymentStatusFailed            = models.PaymentStatusFailed
+	PaymentStatusPartiallyRefunded = models.PaymentStatusPartiallyRefunded
+	PaymentStatusRefunded          = models.PaymentStatusRefunded
+)
+
+var (
+	ErrPaymentNotRefundable  = repository.ErrPaymentNotRefundable
+	ErrRefundExceedsPayment  = repository.ErrRefundExceedsPayment
+	ErrPaymentDeclined       = errors.New("payment was declined")
+	ErrPaymentNotAuthorized  = errors.New("only authorized payments can be captured or voided")
+	ErrAuthorizationExpired  = errors.New("payment authorization is expired")
+	ErrPaymentNotCollectable = errors.New("only captured cash on delivery payments can be collected")
+	ErrWebhookReplayed       = repository.ErrWebhookReplayed
+	ErrUnknownWebhookStatus  = errors.New("unknown webhook payment status")
+	ErrInvalidAmount         = errors.New("payment amount must be positive, in a supported currency")
+	ErrUnknownMethod         = paymentmethod.ErrUnknownMethod
+	ErrMethodDisabled        = paymentmethod.ErrMethodDisabled
+	ErrAmountOutOfRange      = paymentmethod.ErrAmountOutOfRange
 )
 
 type PaymentService struct {
-	PaymentRepo repository.IPaymentRepository
+	PaymentRepo      repository.IPaymentRepository
+	Methods          *paymentmethod.Registry
+	AuthorizationTTL time.Duration
 }
 
 type IPaymentService interface {
@@ -25,39 +56,435 @@ type IPaymentService interface {
 	) ([]models.Payment, int64, error)
 	UpdatePayment(payment *models.Payment) error
 	DeletePayment(id uint) error
+	RefundPayment(refund *models.Refund) (*models.Payment, error)
+	ListRefunds(paymentId uint) ([]models.Refund, error)
+	RefreshPaymentStatus(id uint) (*models.Payment, error)
+	AuthorizePayment(input *models.Payment) error
+	CapturePayment(id uint) (*models.Payment, error)
+	VoidPayment(id uint) (*models.Payment, error)
+	ConfirmPayment(eventId, reference, status, message string) (*models.Payment, error)
+	CollectPayment(id uint) (*models.Payment, error)
+	ListPaymentMethods() []paymentmethod.Method
+	ValidatePaymentMethod(method string, amount money.Money) (*paymentmethod.Method, error)
 }
 
-func NewPaymentService(paymentRepo repository.IPaymentRepository) *PaymentService {
-	return &PaymentService{paymentRepo}
+func NewPaymentService(
+	paymentRepo repository.IPaymentRepository,
+	methods *paymentmethod.Registry,
+	authorizationTTL time.Duration,
+) *PaymentService {
+	return &PaymentService{paymentRepo, methods, authorizationTTL}
 }
 
+// CreatePayment records the payment as pending and charges it with the
+// provider of its method. A declined charge or a provider error leaves the
+// payment failed.
 func (us *PaymentService) CreatePayment(payment *models.Payment) error {
-	payment.Status = PaymentStatusPending
-	err := us.PaymentRepo.CreatePayment(payment)
+	paymentProvider, err := us.preparePayment(payment)
+	if err != nil {
+		return err
+	}
+	err = us.PaymentRepo.CreatePayment(payment)
 	if err != nil {
 		return err
 	}
-	res, err := us.ExecutePayment(payment)
+
+	result, err := paymentProvider.Charge(context.Background(), provider.ChargeRequest{
+		OrderId:   payment.OrderID,
+		UserId:    payment.UserID,
+		Amount:    payment.Amount,
+		Method:    payment.Method,
+		CardToken: payment.CardToken,
+	})
 	if err != nil {
 		payment.Status = PaymentStatusFailed
 		payment.Error = err.Error()
-	}
-	if res {
-		payment.Status = PaymentStatusSuccess
 	} else {
+		applyProviderResult(payment, result)
+	}
+	return us.PaymentRepo.UpdatePayment(payment, journalEntries(payment)...)
+}
+
+// AuthorizePayment holds the amount of the payment at the provider without
+// taking it. The payment is left authorized until it is captured or voided, or
+// failed when the provider declines it.
+func (us *PaymentService) AuthorizePayment(payment *models.Payment) error {
+	paymentProvider, err := us.preparePayment(payment)
+	if err != nil {
+		return err
+	}
+	err = us.PaymentRepo.CreatePayment(payment)
+	if err != nil {
+		return err
+	}
+
+	result, err := paymentProvider.Authorize(context.Background(), provider.ChargeRequest{
+		OrderId:   payment.OrderID,
+		UserId:    payment.UserID,
+		Amount:    payment.Amount,
+		Method:    payment.Method,
+		CardToken: payment.CardToken,
+	})
+	if err != nil {
 		payment.Status = PaymentStatusFailed
+		payment.Error = err.Error()
+	} else {
+		applyProviderResult(payment, result)
+	}
+	if payment.Status == PaymentStatusAuthorized {
+		expiresAt := time.Now().Add(us.AuthorizationTTL)
+		payment.AuthorizationExpiresAt = &expiresAt
+	}
+	return us.PaymentRepo.UpdatePayment(payment)
+}
+
+// CapturePayment takes the authorized amount. Capturing a payment twice is a
+// no-op, and an expired authorization cannot be captured. A captured cash on
+// delivery payment stays pending until CollectPayment records the cash.
+func (us *PaymentService) CapturePayment(id uint) (*models.Payment, error) {
+	payment, err := us.PaymentRepo.ReadPayment(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.CapturedAt != nil {
+		return payment, nil
+	}
+	if payment.Status != PaymentStatusAuthorized {
+		return nil, ErrPaymentNotAuthorized
+	}
+	if payment.AuthorizationExpiresAt != nil && time.Now().After(*payment.AuthorizationExpiresAt) {
+		return nil, ErrAuthorizationExpired
+	}
+
+	paymentProvider, err := us.Methods.Provider(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+	result, err := paymentProvider.Capture(context.Background(), payment.ProviderReference, payment.Amount)
+	if err != nil {
+		return nil, err
 	}
+	applyProviderResult(payment, result)
+	if payment.Status != PaymentStatusSuccess && payment.Status != PaymentStatusPending {
+		return nil, fmt.Errorf("%w: provider answered %s", ErrPaymentNotAuthorized, result.Status)
+	}
+
+	now := time.Now()
+	payment.CapturedAt = &now
+	err = us.PaymentRepo.UpdatePayment(payment, journalEntries(payment)...)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// VoidPayment releases an authorization, or one still pending at the
+// provider, without taking the amount. Voiding a payment that was already
+// voided, refunded or failed is a no-op.
+func (us *PaymentService) VoidPayment(id uint) (*models.Payment, error) {
+	payment, err := us.PaymentRepo.ReadPayment(id)
+	if err != nil {
+		return nil, err
+	}
+	switch payment.Status {
+	case PaymentStatusVoided, PaymentStatusRefunded, PaymentStatusFailed:
+		return payment, nil
+	case PaymentStatusAuthorized, PaymentStatusPending:
+	default:
+		return nil, ErrPaymentNotAuthorized
+	}
+
+	paymentProvider, err := us.Methods.Provider(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+	result, err := paymentProvider.Void(context.Background(), payment.ProviderReference)
+	if err != nil {
+		return nil, err
+	}
+	applyProviderResult(payment, result)
+	if payment.Status != PaymentStatusVoided {
+		return nil, fmt.Errorf("%w: provider answered %s", ErrPaymentNotAuthorized, result.Status)
+	}
+
 	err = us.PaymentRepo.UpdatePayment(payment)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// RefundPayment refunds refund.Amount of the payment, or what is left of it
+// when the amount is zero. The refund is recorded as pending before the
+// provider is called, and completed with the answer of the provider. Refunding
+// the rest of a fully refunded payment is a no-op, and refunding all of an
+// uncollected cash on delivery payment voids it.
+func (us *PaymentService) RefundPayment(refund *models.Refund) (*models.Payment, error) {
+	payment, err := us.PaymentRepo.ReadPayment(refund.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status == PaymentStatusRefunded && refund.Amount.IsZero() {
+		return payment, nil
+	}
+	// Nothing was collected yet for a cash on delivery payment, so giving all
+	// of it back voids it.
+	if payment.Provider == provider.NameCashOnDelivery && payment.Status == PaymentStatusPending && refund.Amount.IsZero() {
+		return us.VoidPayment(payment.ID)
+	}
+	if !payment.Refundable() {
+		return nil, ErrPaymentNotRefundable
+	}
+	if refund.Amount.Currency == "" {
+		refund.Amount.Currency = payment.Amount.Currency
+	}
+	if refund.Amount.Currency != payment.Amount.Currency {
+		return nil, fmt.Errorf("%w: refund in %s of a payment in %s",
+			money.ErrCurrencyMismatch, refund.Amount.Currency, payment.Amount.Currency)
+	}
+
+	payment, err = us.PaymentRepo.CreateRefund(refund)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *provider.Result
+	paymentProvider, err := us.Methods.Provider(payment.Provider)
+	if err == nil {
+		result, err = paymentProvider.Refund(context.Background(), payment.ProviderReference, refund.Amount)
+	}
+	switch {
+	case err != nil:
+		refund.Status = models.RefundStatusFailed
+		refund.Error = err.Error()
+	case result.Status != provider.StatusRefunded:
+		refund.Status = models.RefundStatusFailed
+		refund.Error = result.Message
+		err = fmt.Errorf("%w: provider answered %s", ErrPaymentNotRefundable, result.Status)
+	default:
+		refund.Status = models.RefundStatusSucceeded
+		refund.ProviderReference = result.Reference
+	}
+
+	payment, completeErr := us.PaymentRepo.CompleteRefund(refund)
+	if err != nil {
+		return nil, err
+	}
+	if completeErr != nil {
+		return nil, completeErr
+	}
+	return payment, nil
+}
+
+func (us *PaymentService) ListRefunds(paymentId uint) ([]models.Refund, error) {
+	return us.PaymentRepo.ListRefunds(paymentId)
 }
 
-// ExecutePayment is a mock function to simulate payment execution, failure rate is 10%
-func (us *PaymentService) ExecutePayment(payment *models.Payment) (bool, error) {
-	random := rand.Intn(9)
-	if random > 0 {
-		return true, nil
+// RefreshPaymentStatus reads the state of a pending payment back from the
+// provider and applies it like a provider callback would, so the order of the
+// payment hears about it too. Payments in any other status are returned as
+// they are.
+func (us *PaymentService) RefreshPaymentStatus(id uint) (*models.Payment, error) {
+	payment, err := us.PaymentRepo.ReadPayment(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != PaymentStatusPending || payment.ProviderReference == "" {
+		return payment, nil
+	}
+	paymentProvider, err := us.Methods.Provider(payment.Provider)
+	if err != nil {
+		return payment, nil
+	}
+
+	result, err := paymentProvider.Status(context.Background(), payment.ProviderReference)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status == provider.StatusPending {
+		return payment, nil
+	}
+	if result.Fee.Amount > 0 {
+		payment.Fee = result.Fee
+	}
+
+	// A payment only leaves pending once, so a refresh racing the callback of
+	// the provider, or another refresh, is a replay of the same event.
+	eventId := fmt.Sprintf("refresh_%d", payment.ID)
+	payment, err = us.confirmPayment(eventId, payment, result.Status, result.Message)
+	if errors.Is(err, ErrWebhookReplayed) {
+		return us.PaymentRepo.ReadPayment(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// ConfirmPayment applies a provider callback to a pending payment. The payment
+// moves to success, authorized or failed, and a payment.confirmed event is
+// written to the outbox. A callback about a payment that already left pending
+// is recorded without changing it.
+func (us *PaymentService) ConfirmPayment(eventId, reference, status, message string) (*models.Payment, error) {
+	payment, err := us.PaymentRepo.ReadPaymentByProviderReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != PaymentStatusPending {
+		err = us.PaymentRepo.ApplyWebhookEvent(eventId, payment, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		return payment, nil
+	}
+	return us.confirmPayment(eventId, payment, status, message)
+}
+
+// confirmPayment moves a pending payment to the status the provider reported
+// and writes a payment.confirmed event to the outbox.
+func (us *PaymentService) confirmPayment(eventId string, payment *models.Payment, status, message string) (*models.Payment, error) {
+	now := time.Now()
+	switch status {
+	case provider.StatusSucceeded:
+		payment.Status = PaymentStatusSuccess
+		payment.CapturedAt = &now
+	case provider.StatusAuthorized:
+		expiresAt := now.Add(us.AuthorizationTTL)
+		payment.Status = PaymentStatusAuthorized
+		payment.AuthorizationExpiresAt = &expiresAt
+	case provider.StatusDeclined, provider.StatusFailed:
+		payment.Status = PaymentStatusFailed
+		payment.Error = message
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownWebhookStatus, status)
+	}
+
+	err := us.PaymentRepo.ApplyWebhookEvent(eventId, payment, rabbitmq.PAYMENT_CONFIRMED_ROUTING_KEY, events.PaymentConfirmed{
+		OrderId:   payment.OrderID,
+		PaymentId: payment.ID,
+		Status:    payment.Status,
+		Reason:    payment.Error,
+	}, journalEntries(payment)...)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// CollectPayment records that the cash of a captured cash on delivery payment
+// was collected: the payment succeeds and is posted to the ledger. Its order
+// was already completed when the payment was captured. Collecting a payment
+// twice is a no-op.
+func (us *PaymentService) CollectPayment(id uint) (*models.Payment, error) {
+	payment, err := us.PaymentRepo.ReadPayment(id)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Provider != provider.NameCashOnDelivery {
+		return nil, ErrPaymentNotCollectable
+	}
+	if payment.Status == PaymentStatusSuccess {
+		return payment, nil
+	}
+	if payment.Status != PaymentStatusPending || payment.CapturedAt == nil {
+		return nil, ErrPaymentNotCollectable
+	}
+
+	now := time.Now()
+	payment.Status = PaymentStatusSuccess
+	payment.CapturedAt = &now
+	err = us.PaymentRepo.UpdatePayment(payment, journalEntries(payment)...)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// ListPaymentMethods returns every payment method, enabled or not.
+func (us *PaymentService) ListPaymentMethods() []paymentmethod.Method {
+	return us.Methods.Methods()
+}
+
+// ValidatePaymentMethod checks that method can pay amount, without charging
+// anything. An amount without a currency is in the default one.
+func (us *PaymentService) ValidatePaymentMethod(method string, amount money.Money) (*paymentmethod.Method, error) {
+	payment := models.Payment{Method: method, Amount: amount}
+	err := normalizeAmount(&payment)
+	if err != nil {
+		return nil, err
+	}
+	paymentMethod, _, err := us.Methods.Resolve(payment.Method, payment.Amount)
+	return paymentMethod, err
+}
+
+// preparePayment validates the amount and method of a new payment, and
+// returns the provider that charges it.
+func (us *PaymentService) preparePayment(payment *models.Payment) (provider.PaymentProvider, error) {
+	err := normalizeAmount(payment)
+	if err != nil {
+		return nil, err
+	}
+	_, paymentProvider, err := us.Methods.Resolve(payment.Method, payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+	payment.Status = PaymentStatusPending
+	payment.Provider = paymentProvider.Name()
+	return paymentProvider, nil
+}
+
+// normalizeAmount gives an amount without a currency the default one, and
+// checks that it can be charged.
+func normalizeAmount(payment *models.Payment) error {
+	if payment.Amount.Currency == "" {
+		payment.Amount.Currency = money.DefaultCurrency
+	}
+	err := payment.Amount.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAmount, err)
+	}
+	if payment.Amount.IsZero() {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// applyProviderResult copies the outcome of a provider call to the payment.
+func applyProviderResult(payment *models.Payment, result *provider.Result) {
+	if result.Reference != "" {
+		payment.ProviderReference = result.Reference
+	}
+	if result.Fee.Amount > 0 {
+		payment.Fee = result.Fee
+	}
+	switch result.Status {
+	case provider.StatusSucceeded:
+		payment.Status = PaymentStatusSuccess
+	case provider.StatusPending:
+		payment.Status = PaymentStatusPending
+	case provider.StatusRefunded:
+		payment.Status = PaymentStatusRefunded
+	case provider.StatusAuthorized:
+		payment.Status = PaymentStatusAuthorized
+	case provider.StatusVoided:
+		payment.Status = PaymentStatusVoided
+	default:
+		payment.Status = PaymentStatusFailed
+		payment.Error = result.Message
+	}
+}
+
+// journalEntries returns the ledger entry of a payment whose money was just
+// taken, and nothing for a payment in any other status. Payments that were
+// authorized first are posted as captures.
+func journalEntries(payment *models.Payment) []models.JournalEntry {
+	if payment.Status != PaymentStatusSuccess {
+		return nil
+	}
+	if payment.AuthorizationExpiresAt != nil {
+		return []models.JournalEntry{ledger.ChargeEntry(ledger.EntryTypeCapture, payment)}
 	}
-	return false, nil
+	return []models.JournalEntry{ledger.ChargeEntry(ledger.EntryTypeCharge, payment)}
 }
 
 func (us *PaymentService) ReadPayment(id uint) (*models.Payment, error) {
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sender signs and posts callbacks the way a processor does. It stands in for
+// the processor in tests and local development.
+type Sender struct {
+	URL    string
+	Secret []byte
+	Client *http.Client
+}
+
+func NewSender(url string, secret []byte) *Sender {
+	return &Sender{url, secret, http.DefaultClient}
+}
+
+// Send posts event signed with the current time and returns the status code
+// of the response.
+func (s *Sender) Send(ctx context.Context, event Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := s.NewRequest(ctx, body, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// NewRequest builds the signed request for body as if it was sent at sentAt.
+func (s *Sender) NewRequest(ctx context.Context, body []byte, sentAt time.Time) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build webhook request: %w", err)
+	}
+	timestamp := sentAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(s.Secret, timestamp, body))
+	return req, nil
+}
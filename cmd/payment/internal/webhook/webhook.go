@@ -0,0 +1,67 @@
+// Package webhook verifies the callbacks payment processors send when a
+// payment is confirmed after the fact.
+//
+// A callback is signed with HMAC-SHA256 over "{timestamp}.{body}" using the
+// secret shared with the processor. The hex encoded signature is sent in
+// SignatureHeader and the unix timestamp in TimestampHeader.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidTimestamp = errors.New("webhook timestamp is missing or too old")
+)
+
+// Event is the body of a callback. Id is unique per callback and is used to
+// reject replays, Reference is the provider reference of the payment.
+type Event struct {
+	Id        string `json:"id" binding:"required"`
+	Reference string `json:"reference" binding:"required"`
+	Status    string `json:"status" binding:"required"`
+	Message   string `json:"message"`
+}
+
+// Sign returns the signature of body sent at timestamp.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that body was signed with secret and sent less than tolerance
+// before now.
+func Verify(secret []byte, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	age := now.Sub(time.Unix(sentAt, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrInvalidTimestamp
+	}
+
+	expected, err := hex.DecodeString(Sign(secret, sentAt, body))
+	if err != nil {
+		return err
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, actual) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
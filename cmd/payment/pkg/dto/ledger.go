@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+// LedgerRangeQuery selects the lines posted in [From, To).
+type LedgerRangeQuery struct {
+	From time.Time `form:"from" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+	To   time.Time `form:"to" binding:"required,gtfield=From" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type ListJournalEntryQuery struct {
+	LedgerRangeQuery
+	Account *string `form:"account" binding:"omitempty,oneof=customer merchant refunds fees"`
+	Page    int32   `form:"page" binding:"required,min=1"`
+	PerPage int32   `form:"per_page" binding:"required,min=5,max=100"`
+}
+
+type AccountBalancesResponse struct {
+	From     time.Time               `json:"from"`
+	To       time.Time               `json:"to"`
+	Accounts []models.AccountBalance `json:"accounts"`
+}
+
+type JournalLineResponse struct {
+	Account string      `json:"account"`
+	Debit   money.Money `json:"debit"`
+	Credit  money.Money `json:"credit"`
+}
+
+type JournalEntryResponse struct {
+	ID          uint                  `json:"id"`
+	PaymentId   uint                  `json:"payment_id"`
+	RefundId    uint                  `json:"refund_id,omitempty"`
+	Type        string                `json:"type"`
+	Description string                `json:"description"`
+	Lines       []JournalLineResponse `json:"lines"`
+	CreatedAt   time.Time             `json:"created_at"`
+}
+
+type ListJournalEntryResponse struct {
+	Items    []JournalEntryResponse `json:"items"`
+	Metadata MetadataDto            `json:"metadata"`
+}
+
+func ToJournalEntryResponse(entry *models.JournalEntry) *JournalEntryResponse {
+	lines := make([]JournalLineResponse, 0, len(entry.Lines))
+	for _, line := range entry.Lines {
+		lines = append(lines, JournalLineResponse{
+			Account: line.Account,
+			Debit:   money.New(line.Debit, line.Currency),
+			Credit:  money.New(line.Credit, line.Currency),
+		})
+	}
+
+	return &JournalEntryResponse{
+		ID:          entry.ID,
+		PaymentId:   entry.PaymentID,
+		RefundId:    entry.RefundID,
+		Type:        entry.Type,
+		Description: entry.Description,
+		Lines:       lines,
+		CreatedAt:   entry.CreatedAt,
+	}
+}
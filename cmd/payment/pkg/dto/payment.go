@@ -4,13 +4,16 @@ import (
 	"time"
 
 	"github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
 type CreatePaymentDto struct {
-	OrderId uint   `json:"order_id" binding:"required"`
-	UserId  uint   `json:"user_id" binding:"required"`
-	Amount  uint   `json:"amount" binding:"required"`
-	Method  string `json:"method" binding:"required"`
+	OrderId uint        `json:"order_id" binding:"required"`
+	UserId  uint        `json:"user_id" binding:"required"`
+	Amount  money.Money `json:"amount"`
+	Method  string      `json:"method" binding:"required"`
+	// CardToken is passed to the payment provider and never stored.
+	CardToken string `json:"card_token"`
 }
 
 type ReadPaymentRequest struct {
@@ -18,15 +21,53 @@ type ReadPaymentRequest struct {
 }
 
 type PaymentResponse struct {
-	ID        uint      `json:"id"`
-	OrderId   uint      `json:"order_id"`
-	UserId    uint      `json:"user_id"`
-	Amount    uint      `json:"amount"`
-	Method    string    `json:"method"`
-	Status    string    `json:"status"`
-	Error     string    `json:"error"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uint        `json:"id"`
+	OrderId  uint        `json:"order_id"`
+	UserId   uint        `json:"user_id"`
+	Amount   money.Money `json:"amount"`
+	Method   string      `json:"method"`
+	Status   string      `json:"status"`
+	Error    string      `json:"error"`
+	Provider string      `json:"provider"`
+	// ProviderReference is the id of the charge at the provider.
+	ProviderReference string      `json:"provider_reference"`
+	RefundedAmount    money.Money `json:"refunded_amount"`
+	// AuthorizationExpiresAt is set on payments that are only authorized.
+	AuthorizationExpiresAt *time.Time `json:"authorization_expires_at,omitempty"`
+	CapturedAt             *time.Time `json:"captured_at,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+}
+
+// CreateRefundDto refunds Amount of a payment, or what is left of it when
+// Amount is omitted. Amount is in the currency of the payment.
+type CreateRefundDto struct {
+	Amount money.Money `json:"amount"`
+	Reason string      `json:"reason" binding:"max=255"`
+}
+
+type RefundResponse struct {
+	ID        uint        `json:"id"`
+	PaymentId uint        `json:"payment_id"`
+	Amount    money.Money `json:"amount"`
+	Reason    string      `json:"reason"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type CreateRefundResponse struct {
+	Payment *PaymentResponse `json:"payment"`
+	Refund  *RefundResponse  `json:"refund"`
+}
+
+// PaymentMethodResponse bounds the amounts of a method in minor units, without
+// a bound when zero.
+type PaymentMethodResponse struct {
+	Name      string `json:"name"`
+	Enabled   bool   `json:"enabled"`
+	MinAmount int64  `json:"min_amount"`
+	MaxAmount int64  `json:"max_amount"`
 }
 
 type ListPaymentQuery struct {
@@ -42,14 +83,31 @@ type ListPaymentResponse struct {
 
 func ToPaymentResponse(payment *models.Payment) *PaymentResponse {
 	return &PaymentResponse{
-		ID:        payment.ID,
-		OrderId:   payment.OrderID,
-		UserId:    payment.UserID,
-		Amount:    payment.Amount,
-		Method:    payment.Method,
-		Status:    payment.Status,
-		CreatedAt: payment.CreatedAt,
-		UpdatedAt: payment.UpdatedAt,
-		Error:     payment.Error,
+		ID:                     payment.ID,
+		OrderId:                payment.OrderID,
+		UserId:                 payment.UserID,
+		Amount:                 payment.Amount,
+		Method:                 payment.Method,
+		Status:                 payment.Status,
+		CreatedAt:              payment.CreatedAt,
+		UpdatedAt:              payment.UpdatedAt,
+		Error:                  payment.Error,
+		Provider:               payment.Provider,
+		ProviderReference:      payment.ProviderReference,
+		RefundedAmount:         payment.RefundedAmount,
+		AuthorizationExpiresAt: payment.AuthorizationExpiresAt,
+		CapturedAt:             payment.CapturedAt,
+	}
+}
+
+func ToRefundResponse(refund *models.Refund) *RefundResponse {
+	return &RefundResponse{
+		ID:        refund.ID,
+		PaymentId: refund.PaymentID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+		Error:     refund.Error,
+		CreatedAt: refund.CreatedAt,
 	}
 }
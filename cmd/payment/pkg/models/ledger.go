@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+// JournalEntry is a balanced double-entry posting: the debits of its lines
+// add up to their credits. Entries are only ever added, never changed.
+type JournalEntry struct {
+	ID          uint          `json:"id" gorm:"primarykey"`
+	PaymentID   uint          `json:"payment_id" gorm:"index"`
+	RefundID    uint          `json:"refund_id"`
+	Type        string        `json:"type"`
+	Description string        `json:"description"`
+	Lines       []JournalLine `json:"lines"`
+	CreatedAt   time.Time     `json:"created_at" gorm:"index"`
+}
+
+// JournalLine debits or credits one account, in minor units of Currency.
+// Exactly one of Debit and Credit is set.
+type JournalLine struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	JournalEntryID uint      `json:"journal_entry_id" gorm:"index"`
+	Account        string    `json:"account" gorm:"index"`
+	Currency       string    `json:"currency" gorm:"size:3"`
+	Debit          int64     `json:"debit"`
+	Credit         int64     `json:"credit"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`
+}
+
+// AccountBalance sums the lines of an account in one currency. Balance is
+// debits minus credits.
+type AccountBalance struct {
+	Account string      `json:"account"`
+	Debit   money.Money `json:"debit"`
+	Credit  money.Money `json:"credit"`
+	Balance money.Money `json:"balance"`
+}
@@ -1,15 +1,48 @@
 package models
 
 import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/pkg/money"
 	"gorm.io/gorm"
 )
 
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusAuthorized        = "authorized"
+	PaymentStatusVoided            = "voided"
+	PaymentStatusSuccess           = "success"
+	PaymentStatusFailed            = "failed"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+)
+
 type Payment struct {
 	gorm.Model
-	OrderID uint   `json:"order_id"`
-	UserID  uint   `json:"user_id"`
-	Amount  uint   `json:"amount"`
-	Method  string `json:"method"`
-	Status  string `json:"status"`
-	Error   string `json:"error"`
+	OrderID uint        `json:"order_id"`
+	UserID  uint        `json:"user_id"`
+	Amount  money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Method  string      `json:"method"`
+	Status  string      `json:"status"`
+	Error   string      `json:"error"`
+	// Provider is the processor that handled the payment, and
+	// ProviderReference the id of the charge at that processor.
+	Provider          string `json:"provider"`
+	ProviderReference string `json:"provider_reference" gorm:"index"`
+	// AuthorizationExpiresAt is set when the payment is only authorized: it
+	// has to be captured before then. CapturedAt is set once it is captured.
+	AuthorizationExpiresAt *time.Time `json:"authorization_expires_at"`
+	CapturedAt             *time.Time `json:"captured_at"`
+	// Fee is what the provider kept of the amount.
+	Fee money.Money `json:"fee" gorm:"embedded;embeddedPrefix:fee_"`
+	// RefundedAmount is the sum of the succeeded refunds of the payment.
+	RefundedAmount money.Money `json:"refunded_amount" gorm:"embedded;embeddedPrefix:refunded_amount_"`
+	// CardToken is handed to the provider and never stored.
+	CardToken string `json:"-" gorm:"-"`
+}
+
+// Refundable reports whether money was taken for the payment and not all of
+// it was given back yet.
+func (p *Payment) Refundable() bool {
+	return p.Status == PaymentStatusSuccess || p.Status == PaymentStatusPartiallyRefunded
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
+
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+// Refund returns part or all of a payment to the customer. A pending refund
+// already counts against the amount left to refund, so that concurrent
+// refunds cannot exceed the payment.
+type Refund struct {
+	gorm.Model
+	PaymentID         uint        `json:"payment_id" gorm:"index"`
+	Amount            money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Reason            string      `json:"reason"`
+	Status            string      `json:"status"`
+	Error             string      `json:"error"`
+	ProviderReference string      `json:"provider_reference"`
+}
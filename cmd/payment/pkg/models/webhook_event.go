@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// WebhookEvent records a provider callback that was applied, so that the same
+// callback sent again is rejected.
+type WebhookEvent struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	EventId   string    `json:"event_id" gorm:"uniqueIndex"`
+	PaymentID uint      `json:"payment_id" gorm:"index"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,230 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.3
+// source: rpc_validate_payment_method.proto
+
+package pb
+
+import (
+	moneypb "github.com/tricong1998/go-ecom/pkg/money/moneypb"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ValidatePaymentMethodRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Method string         `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Amount *moneypb.Money `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+}
+
+func (x *ValidatePaymentMethodRequest) Reset() {
+	*x = ValidatePaymentMethodRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_validate_payment_method_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidatePaymentMethodRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidatePaymentMethodRequest) ProtoMessage() {}
+
+func (x *ValidatePaymentMethodRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_validate_payment_method_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidatePaymentMethodRequest.ProtoReflect.Descriptor instead.
+func (*ValidatePaymentMethodRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_validate_payment_method_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ValidatePaymentMethodRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *ValidatePaymentMethodRequest) GetAmount() *moneypb.Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+type ValidatePaymentMethodResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Method *PaymentMethod `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+}
+
+func (x *ValidatePaymentMethodResponse) Reset() {
+	*x = ValidatePaymentMethodResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_validate_payment_method_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidatePaymentMethodResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidatePaymentMethodResponse) ProtoMessage() {}
+
+func (x *ValidatePaymentMethodResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_validate_payment_method_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidatePaymentMethodResponse.ProtoReflect.Descriptor instead.
+func (*ValidatePaymentMethodResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_validate_payment_method_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ValidatePaymentMethodResponse) GetMethod() *PaymentMethod {
+	if x != nil {
+		return x.Method
+	}
+	return nil
+}
+
+var File_rpc_validate_payment_method_proto protoreflect.FileDescriptor
+
+var file_rpc_validate_payment_method_proto_rawDesc = []byte{
+	0x0a, 0x21, 0x72, 0x70, 0x63, 0x5f, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x5f, 0x70,
+	0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x11, 0x6d, 0x6f, 0x6e, 0x65, 0x79, 0x2f, 0x6d,
+	0x6f, 0x6e, 0x65, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x14, 0x70, 0x61, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x5f, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x22, 0x5c, 0x0a, 0x1c, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50, 0x61, 0x79, 0x6d,
+	0x65, 0x6e, 0x74, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x24, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x6d, 0x6f, 0x6e, 0x65, 0x79,
+	0x2e, 0x4d, 0x6f, 0x6e, 0x65, 0x79, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x4a,
+	0x0a, 0x1d, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e,
+	0x74, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x29, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x11, 0x2e, 0x70, 0x62, 0x2e, 0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x4d, 0x65, 0x74, 0x68,
+	0x6f, 0x64, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x72, 0x69, 0x63, 0x6f, 0x6e, 0x67,
+	0x31, 0x39, 0x39, 0x38, 0x2f, 0x67, 0x6f, 0x2d, 0x65, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6d, 0x64,
+	0x2f, 0x70, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rpc_validate_payment_method_proto_rawDescOnce sync.Once
+	file_rpc_validate_payment_method_proto_rawDescData = file_rpc_validate_payment_method_proto_rawDesc
+)
+
+func file_rpc_validate_payment_method_proto_rawDescGZIP() []byte {
+	file_rpc_validate_payment_method_proto_rawDescOnce.Do(func() {
+		file_rpc_validate_payment_method_proto_rawDescData = protoimpl.X.CompressGZIP(file_rpc_validate_payment_method_proto_rawDescData)
+	})
+	return file_rpc_validate_payment_method_proto_rawDescData
+}
+
+var file_rpc_validate_payment_method_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_rpc_validate_payment_method_proto_goTypes = []any{
+	(*ValidatePaymentMethodRequest)(nil),  // 0: pb.ValidatePaymentMethodRequest
+	(*ValidatePaymentMethodResponse)(nil), // 1: pb.ValidatePaymentMethodResponse
+	(*moneypb.Money)(nil),                 // 2: money.Money
+	(*PaymentMethod)(nil),                 // 3: pb.PaymentMethod
+}
+var file_rpc_validate_payment_method_proto_depIdxs = []int32{
+	2, // 0: pb.ValidatePaymentMethodRequest.amount:type_name -> money.Money
+	3, // 1: pb.ValidatePaymentMethodResponse.method:type_name -> pb.PaymentMethod
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_rpc_validate_payment_method_proto_init() }
+func file_rpc_validate_payment_method_proto_init() {
+	if File_rpc_validate_payment_method_proto != nil {
+		return
+	}
+	file_payment_method_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_rpc_validate_payment_method_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ValidatePaymentMethodRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_validate_payment_method_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ValidatePaymentMethodResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rpc_validate_payment_method_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_rpc_validate_payment_method_proto_goTypes,
+		DependencyIndexes: file_rpc_validate_payment_method_proto_depIdxs,
+		MessageInfos:      file_rpc_validate_payment_method_proto_msgTypes,
+	}.Build()
+	File_rpc_validate_payment_method_proto = out.File
+	file_rpc_validate_payment_method_proto_rawDesc = nil
+	file_rpc_validate_payment_method_proto_goTypes = nil
+	file_rpc_validate_payment_method_proto_depIdxs = nil
+}
@@ -1,24 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
 	"github.com/tricong1998/go-ecom/cmd/product/grpc_handler"
 	"github.com/tricong1998/go-ecom/cmd/product/internal/api"
 	"github.com/tricong1998/go-ecom/cmd/product/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/product/internal/database"
+	"github.com/tricong1998/go-ecom/cmd/product/internal/rabbit_handler"
 	"github.com/tricong1998/go-ecom/cmd/product/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/product/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/logger"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"gorm.io/gorm"
 )
 
+const sweepBatchSize = 100
+
 func main() {
 	log := logger.NewLogger()
 
@@ -40,6 +49,22 @@ func main() {
 		log.Fatal().Err(err).Msg("Cannot migrate database")
 	}
 
+	rabbitConfig := rabbitmq.RabbitMQConfig{
+		Host:     cfg.RabbitMQConfig.Host,
+		Port:     cfg.RabbitMQConfig.Port,
+		User:     cfg.RabbitMQConfig.User,
+		Password: cfg.RabbitMQConfig.Password,
+	}
+	rabbitConn, err := rabbitmq.NewRabbitMQConn(&rabbitConfig, context.Background())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot connect rabbit")
+	}
+
+	runTokenRevocationConsumer(db, log, &rabbitConfig, rabbitConn)
+	if cfg.OrderProcessingMode == events.ProcessingModeAsync {
+		runOrderEventConsumers(cfg, db, log, &rabbitConfig, rabbitConn)
+	}
+	go runReservationSweeper(cfg, db, log)
 	go runGrpcServer(cfg, db, log)
 	runGinServer(cfg, db, log)
 }
@@ -57,10 +82,92 @@ func runGinServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
 	}
 }
 
+// runOrderEventConsumers reserves, commits and releases stock in reaction to
+// the events of orders processed asynchronously.
+func runOrderEventConsumers(
+	cfg *config.Config,
+	db *gorm.DB,
+	log zerolog.Logger,
+	rabbitConfig *rabbitmq.RabbitMQConfig,
+	rabbitConn *amqp.Connection,
+) {
+	outboxRelay := rabbitmq.NewOutboxRelay(context.Background(), db, rabbitConn, log, rabbitmq.E_COM_EXCHANGE, "direct")
+	go outboxRelay.Run()
+
+	reservationRepo := repository.NewReservationRepository(db)
+	reservationService := services.NewReservationService(reservationRepo, cfg.Reservation.TTL)
+	stockEventService := services.NewStockEventService(reservationService, rabbitmq.NewOutboxWriter(db))
+	orderStockDependencies := rabbit_handler.OrderStockDependencies{
+		Logger:            log,
+		StockEventService: stockEventService,
+	}
+	consumers := []struct {
+		handler    func(queue string, msg amqp.Delivery, dependencies *rabbit_handler.OrderStockDependencies) error
+		queue      string
+		routingKey string
+	}{
+		{rabbit_handler.ReserveOrderStock, rabbitmq.PRODUCT_ORDER_CREATED_QUEUE, rabbitmq.ORDER_CREATED_ROUTING_KEY},
+		{rabbit_handler.CommitOrderStock, rabbitmq.PRODUCT_PAYMENT_SUCCEEDED_QUEUE, rabbitmq.PAYMENT_SUCCEEDED_ROUTING_KEY},
+		{rabbit_handler.ReleaseOrderStock, rabbitmq.PRODUCT_PAYMENT_FAILED_QUEUE, rabbitmq.PAYMENT_FAILED_ROUTING_KEY},
+	}
+	for _, c := range consumers {
+		consumer := rabbitmq.NewConsumer[*rabbit_handler.OrderStockDependencies](context.Background(), rabbitConfig, rabbitConn, log, c.handler, rabbitmq.E_COM_EXCHANGE, "direct", c.queue, c.routingKey)
+		go func() {
+			err := consumer.ConsumeMessage(nil, &orderStockDependencies)
+			if err != nil {
+				log.Error().Err(err).Msg("Consume message error")
+			}
+		}()
+	}
+}
+
+// runTokenRevocationConsumer rejects the access tokens revoked by the user
+// service.
+func runTokenRevocationConsumer(
+	db *gorm.DB,
+	log zerolog.Logger,
+	rabbitConfig *rabbitmq.RabbitMQConfig,
+	rabbitConn *amqp.Connection,
+) {
+	tokenRevocationDependencies := rabbit_handler.TokenRevocationDependencies{
+		Logger:          log,
+		RevocationStore: token.NewGormRevocationStore(db),
+	}
+	consumer := rabbitmq.NewConsumer[*rabbit_handler.TokenRevocationDependencies](context.Background(), rabbitConfig, rabbitConn, log, rabbit_handler.TokensRevoked, rabbitmq.E_COM_EXCHANGE, "direct", rabbitmq.PRODUCT_TOKENS_REVOKED_QUEUE, rabbitmq.TOKENS_REVOKED_ROUTING_KEY)
+	go func() {
+		err := consumer.ConsumeMessage(nil, &tokenRevocationDependencies)
+		if err != nil {
+			log.Error().Err(err).Msg("Consume message error")
+		}
+	}()
+}
+
+// runReservationSweeper periodically gives the stock of expired reservations
+// back to the products.
+func runReservationSweeper(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
+	reservationRepo := repository.NewReservationRepository(db)
+	reservationService := services.NewReservationService(reservationRepo, cfg.Reservation.TTL)
+
+	ticker := time.NewTicker(cfg.Reservation.SweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		released, err := reservationService.ReleaseExpiredReservations(sweepBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot release expired reservations")
+			continue
+		}
+		if released > 0 {
+			log.Info().Msgf("Released %d expired reservations", released)
+		}
+	}
+}
+
 func runGrpcServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
 	productRepo := repository.NewProductRepository(db)
 	productService := services.NewProductService(productRepo)
-	server := grpc_handler.NewServer(productService)
+	reservationRepo := repository.NewReservationRepository(db)
+	reservationService := services.NewReservationService(reservationRepo, cfg.Reservation.TTL)
+	server := grpc_handler.NewServer(productService, reservationService)
 
 	grpcServer := grpc.NewServer()
 	pb.RegisterProductGrpcServer(grpcServer, server)
@@ -2,19 +2,24 @@ package grpc_handler
 
 import (
 	"context"
+	"time"
 
 	"github.com/tricong1998/go-ecom/cmd/product/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/pb"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
 type Server struct {
-	ProductService services.IProductService
+	ProductService     services.IProductService
+	ReservationService services.IReservationService
 	pb.UnimplementedProductGrpcServer
 }
 
-func NewServer(ProductService services.IProductService) *Server {
+func NewServer(ProductService services.IProductService, ReservationService services.IReservationService) *Server {
 	server := Server{
-		ProductService: ProductService,
+		ProductService:     ProductService,
+		ReservationService: ReservationService,
 	}
 	return &server
 }
@@ -27,15 +32,16 @@ func (server *Server) ReadProduct(_ context.Context, input *pb.ReadProductReques
 	return &pb.ReadProductResponse{
 		Product: &pb.Product{
 			Name:     product.Name,
-			Price:    uint64(product.Price),
+			Price:    money.ToProto(product.Price),
 			Quantity: uint64(product.Quantity),
 			Id:       uint64(product.ID),
+			Discount: money.ToProto(product.Discount),
 		},
 	}, nil
 }
 
 func (server *Server) UpdateProductQuantity(_ context.Context, input *pb.UpdateProductQuantityRequest) (*pb.UpdateProductQuantityResponse, error) {
-	success, err := server.ProductService.UpdateProductQuantity((uint)(input.GetProductId()), (uint)(input.GetQuantity()))
+	success, err := server.ProductService.UpdateProductQuantity(toProductQuantities(input.GetItems()))
 	if err != nil {
 		return nil, err
 	}
@@ -43,3 +49,75 @@ func (server *Server) UpdateProductQuantity(_ context.Context, input *pb.UpdateP
 		Success: success,
 	}, nil
 }
+
+func (server *Server) RestoreProductQuantity(_ context.Context, input *pb.RestoreProductQuantityRequest) (*pb.RestoreProductQuantityResponse, error) {
+	success, err := server.ProductService.RestoreProductQuantity(toProductQuantities(input.GetItems()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RestoreProductQuantityResponse{
+		Success: success,
+	}, nil
+}
+
+func (server *Server) ReserveStock(_ context.Context, input *pb.ReserveStockRequest) (*pb.ReserveStockResponse, error) {
+	reservation, err := server.ReservationService.ReserveStock(
+		input.GetReference(),
+		toProductQuantities(input.GetItems()),
+		time.Duration(input.GetTtlSeconds())*time.Second,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ReserveStockResponse{
+		Reservation: toPbReservation(reservation),
+	}, nil
+}
+
+func (server *Server) CommitReservation(_ context.Context, input *pb.CommitReservationRequest) (*pb.CommitReservationResponse, error) {
+	reservation, err := server.ReservationService.CommitReservation((uint)(input.GetReservationId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CommitReservationResponse{
+		Reservation: toPbReservation(reservation),
+	}, nil
+}
+
+func (server *Server) ReleaseReservation(_ context.Context, input *pb.ReleaseReservationRequest) (*pb.ReleaseReservationResponse, error) {
+	reservation, err := server.ReservationService.ReleaseReservation((uint)(input.GetReservationId()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ReleaseReservationResponse{
+		Reservation: toPbReservation(reservation),
+	}, nil
+}
+
+func toPbReservation(reservation *models.Reservation) *pb.Reservation {
+	items := make([]*pb.ProductQuantity, 0, len(reservation.Items))
+	for _, item := range reservation.Items {
+		items = append(items, &pb.ProductQuantity{
+			ProductId: uint64(item.ProductId),
+			Quantity:  uint64(item.Quantity),
+		})
+	}
+	return &pb.Reservation{
+		Id:        uint64(reservation.ID),
+		Reference: reservation.Reference,
+		Status:    reservation.Status,
+		ExpiresAt: reservation.ExpiresAt.Unix(),
+		Items:     items,
+	}
+}
+
+func toProductQuantities(items []*pb.ProductQuantity) []models.ProductQuantity {
+	quantities := make([]models.ProductQuantity, 0, len(items))
+	for _, item := range items {
+		quantities = append(quantities, models.ProductQuantity{
+			ProductId: (uint)(item.GetProductId()),
+			Quantity:  (uint)(item.GetQuantity()),
+		})
+	}
+	return quantities
+}
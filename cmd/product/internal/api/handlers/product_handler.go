@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -29,9 +30,10 @@ func (userHandler *ProductHandler) CreateProduct(ctx *gin.Context) {
 		Name:     input.Name,
 		Price:    input.Price,
 		Quantity: input.Quantity,
+		Discount: input.Discount,
 	}
 	if err := userHandler.ProductService.CreateProduct(&user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.JSON(productErrorStatus(err), errorResponse(err))
 		return
 	}
 
@@ -69,12 +71,13 @@ func (userHandler *ProductHandler) UpdateProduct(ctx *gin.Context) {
 	}
 
 	user := models.Product{
-		Name:  input.Name,
-		Price: input.Price,
+		Name:     input.Name,
+		Price:    input.Price,
+		Discount: input.Discount,
 	}
 	user.ID = readProductRequest.ID
 	if err := userHandler.ProductService.UpdateProduct(&user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.JSON(productErrorStatus(err), errorResponse(err))
 		return
 	}
 
@@ -124,3 +127,10 @@ func (userHandler *ProductHandler) DeleteProduct(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
+
+func productErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidPrice) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/tricong1998/go-ecom/cmd/product/internal/services"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/dto"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
 func expectBodyProduct(t *testing.T, w *httptest.ResponseRecorder, mockResponse *models.Product) {
@@ -41,7 +42,7 @@ func TestCreateProduct(t *testing.T) {
 			name: "OK",
 			setupInputFunc: func(input *dto.CreateProductDto, mockResponse *models.Product) {
 				input.Name = "Full name"
-				input.Price = 1
+				input.Price = money.New(1, "USD")
 				input.Quantity = 1
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
@@ -83,7 +84,7 @@ func TestCreateProduct(t *testing.T) {
 			name: "CreateProductError",
 			setupInputFunc: func(input *dto.CreateProductDto, mockResponse *models.Product) {
 				input.Name = "Full name"
-				input.Price = 1
+				input.Price = money.New(1, "USD")
 				input.Quantity = 1
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
@@ -135,7 +136,7 @@ func TestReadProduct(t *testing.T) {
 			setupInputFunc: func(input *dto.ReadProductRequest, mockResponse *models.Product) {
 				input.ID = 1
 				mockResponse.Name = "Full name"
-				mockResponse.Price = 1
+				mockResponse.Price = money.New(1, "USD")
 				mockResponse.ID = input.ID
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
@@ -222,7 +223,7 @@ func TestListProduct(t *testing.T) {
 				now := time.Now()
 				user1 := models.Product{
 					Name:  "Full name 1",
-					Price: 1,
+					Price: money.New(1, "USD"),
 				}
 				user1.CreatedAt = now
 				user1.UpdatedAt = now
@@ -231,7 +232,7 @@ func TestListProduct(t *testing.T) {
 
 				user2 := models.Product{
 					Name:  "Full name 2",
-					Price: 1,
+					Price: money.New(1, "USD"),
 				}
 				user2.CreatedAt = now
 				user2.UpdatedAt = now
@@ -322,7 +323,7 @@ func TestUpdateProduct(t *testing.T) {
 			name: "OK",
 			setupInputFunc: func(input *dto.CreateProductDto, mockResponse *models.Product) {
 				input.Name = "New full name"
-				input.Price = 1
+				input.Price = money.New(1, "USD")
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
@@ -363,7 +364,7 @@ func TestUpdateProduct(t *testing.T) {
 			name: "UpdateProductError",
 			setupInputFunc: func(input *dto.CreateProductDto, mockResponse *models.Product) {
 				input.Name = "Full name"
-				input.Price = 1
+				input.Price = money.New(1, "USD")
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
 				mockResponse.UpdatedAt = mockResponse.CreatedAt
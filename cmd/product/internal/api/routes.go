@@ -12,23 +12,29 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(routes *gin.Engine, db *gorm.DB, config *config.Config, log *zerolog.Logger) {
-	tokenMaker, err := token.NewJWTMaker(config.Auth.AccessTokenSecret)
+func SetupRoutes(routes *gin.Engine, db *gorm.DB, cfg *config.Config, log *zerolog.Logger) {
+	tokenMaker, err := token.NewAccessTokenVerifier(token.VerifierConfig{
+		Format:       cfg.Auth.AccessTokenFormat,
+		Secret:       cfg.Auth.AccessTokenSecret,
+		JWKSURL:      cfg.Auth.JWKSURL,
+		JWKSCacheTTL: cfg.Auth.JWKSCacheTTL,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("Cannot create token maker")
 		return
 	}
+	revocationStore := token.NewGormRevocationStore(db)
 	userRepo := repository.NewProductRepository(db)
 	userService := services.NewProductService(userRepo)
 	userHandler := handlers.NewProductHandler(userService)
 
 	userGroup := routes.Group("products")
-	authRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, []string{}))
+	authRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{}))
 	{
 		authRoutes.GET("/:id", userHandler.ReadProduct)
 		authRoutes.GET("", userHandler.ListProducts)
 	}
-	adminRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, []string{"admin"}))
+	adminRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{"admin"}))
 	{
 		adminRoutes.POST("", userHandler.CreateProduct)
 		adminRoutes.PUT("/:id", userHandler.UpdateProduct)
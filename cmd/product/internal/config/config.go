@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"github.com/tricong1998/go-ecom/pkg/util"
 )
 
@@ -26,18 +28,39 @@ type GrpcServerConfig struct {
 	Port string
 }
 
+// AuthConfig verifies access tokens with the public keys published at
+// JWKSURL by the user service, or with AccessTokenSecret when it is empty.
+// AccessTokenFormat has to match the format of the user service.
 type AuthConfig struct {
+	AccessTokenFormat    string
 	AccessTokenDuration  time.Duration
 	AccessTokenSecret    string
 	RefreshTokenSecret   string
 	RefreshTokenDuration time.Duration
+	JWKSURL              string
+	JWKSCacheTTL         time.Duration
+}
+
+type RabbitMQConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+}
+
+type ReservationConfig struct {
+	TTL           time.Duration
+	SweepInterval time.Duration
 }
 
 type Config struct {
-	Server     HttpServerConfig
-	GrpcServer GrpcServerConfig
-	DB         DBConfig
-	Auth       AuthConfig
+	Server              HttpServerConfig
+	GrpcServer          GrpcServerConfig
+	DB                  DBConfig
+	RabbitMQConfig      RabbitMQConfig
+	Auth                AuthConfig
+	Reservation         ReservationConfig
+	OrderProcessingMode string
 }
 
 func Load() (*Config, error) {
@@ -48,6 +71,7 @@ func Load() (*Config, error) {
 	}
 
 	config := &Config{
+		OrderProcessingMode: os.Getenv("ORDER_PROCESSING_MODE"),
 		Server: HttpServerConfig{
 			Port: os.Getenv("PRODUCT_SERVER_PORT"),
 			Host: os.Getenv("PRODUCT_SERVER_HOST"),
@@ -63,12 +87,33 @@ func Load() (*Config, error) {
 			DBPassword: os.Getenv("DB_PASSWORD"),
 			DBName:     os.Getenv("PRODUCT_DB_NAME"),
 		},
+		RabbitMQConfig: RabbitMQConfig{
+			Port:     os.Getenv("AMQP_SERVER_PORT"),
+			Host:     os.Getenv("AMQP_SERVER_HOST"),
+			User:     os.Getenv("AMQP_SERVER_USER"),
+			Password: os.Getenv("AMQP_SERVER_PASSWORD"),
+		},
 		Auth: AuthConfig{
+			AccessTokenFormat:    os.Getenv("ACCESS_TOKEN_FORMAT"),
 			AccessTokenSecret:    os.Getenv("ACCESS_TOKEN_SECRET"),
 			RefreshTokenSecret:   os.Getenv("REFRESH_TOKEN_SECRET"),
 			AccessTokenDuration:  util.ParseDuration(os.Getenv("ACCESS_TOKEN_DURATION"), 15*time.Minute),
 			RefreshTokenDuration: util.ParseDuration(os.Getenv("REFRESH_TOKEN_DURATION"), 24*time.Hour),
+			JWKSURL:              os.Getenv("JWKS_URL"),
+			JWKSCacheTTL:         util.ParseDuration(os.Getenv("JWKS_CACHE_TTL"), 10*time.Minute),
 		},
+		Reservation: ReservationConfig{
+			TTL:           util.ParseDuration(os.Getenv("RESERVATION_TTL"), 15*time.Minute),
+			SweepInterval: util.ParseDuration(os.Getenv("RESERVATION_SWEEP_INTERVAL"), 30*time.Second),
+		},
+	}
+
+	if config.OrderProcessingMode == "" {
+		config.OrderProcessingMode = events.ProcessingModeSync
+	}
+
+	if config.Auth.AccessTokenFormat == "" {
+		config.Auth.AccessTokenFormat = token.FormatJWT
 	}
 
 	if config.Server.Port == "" {
@@ -5,6 +5,9 @@ import (
 
 	"github.com/tricong1998/go-ecom/cmd/product/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -32,7 +35,22 @@ func Initialize(dbConfig *config.DBConfig) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.Product{},
+		&models.Reservation{},
+		&models.ReservationItem{},
+		&rabbitmq.OutboxMessage{},
+		&token.RevokedToken{},
+		&token.RevokedUserTokens{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// Prices used to be plain integers next to a single currency column.
+	err = money.MigrateColumn(db, &models.Product{}, "price", "currency", "price_")
+	if err != nil {
+		return err
+	}
+	return money.MigrateColumn(db, &models.Product{}, "discount", "currency", "discount_")
 }
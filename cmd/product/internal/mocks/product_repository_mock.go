@@ -37,7 +37,12 @@ func (m *MockProductRepository) DeleteProduct(id uint) error {
 	return args.Error(0)
 }
 
-func (m *MockProductRepository) UpdateProductQuantity(productId, quantity uint) (bool, error) {
-	args := m.Called(productId, quantity)
+func (m *MockProductRepository) UpdateProductQuantity(items []models.ProductQuantity) (bool, error) {
+	args := m.Called(items)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *MockProductRepository) RestoreProductQuantity(items []models.ProductQuantity) (bool, error) {
+	args := m.Called(items)
 	return args.Get(0).(bool), args.Error(1)
 }
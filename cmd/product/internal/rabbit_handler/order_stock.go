@@ -0,0 +1,54 @@
+package rabbit_handler
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"github.com/tricong1998/go-ecom/cmd/product/internal/services"
+	"github.com/tricong1998/go-ecom/pkg/events"
+)
+
+type OrderStockDependencies struct {
+	StockEventService *services.StockEventService
+	Logger            zerolog.Logger
+}
+
+func ReserveOrderStock(queue string, msg amqp.Delivery, dependencies *OrderStockDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var orderCreated events.OrderCreated
+
+	err := json.Unmarshal(msg.Body, &orderCreated)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.StockEventService.ReserveOrderStock(orderCreated)
+}
+
+func CommitOrderStock(queue string, msg amqp.Delivery, dependencies *OrderStockDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var paymentSucceeded events.PaymentSucceeded
+
+	err := json.Unmarshal(msg.Body, &paymentSucceeded)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.StockEventService.CommitOrderStock(paymentSucceeded)
+}
+
+func ReleaseOrderStock(queue string, msg amqp.Delivery, dependencies *OrderStockDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var paymentFailed events.PaymentFailed
+
+	err := json.Unmarshal(msg.Body, &paymentFailed)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.StockEventService.ReleaseOrderStock(paymentFailed)
+}
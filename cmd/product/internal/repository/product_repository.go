@@ -2,11 +2,15 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
 	"gorm.io/gorm"
 )
 
+var ErrNotEnoughQuantity = errors.New("product quantity is not enough")
+
 type ProductRepository struct {
 	db *gorm.DB
 }
@@ -20,7 +24,8 @@ type IProductRepository interface {
 	) ([]models.Product, int64, error)
 	UpdateProduct(input *models.Product) error
 	DeleteProduct(id uint) error
-	UpdateProductQuantity(productId, quantity uint) (bool, error)
+	UpdateProductQuantity(items []models.ProductQuantity) (bool, error)
+	RestoreProductQuantity(items []models.ProductQuantity) (bool, error)
 }
 
 func NewProductRepository(db *gorm.DB) *ProductRepository {
@@ -70,37 +75,70 @@ func (userRepo *ProductRepository) DeleteProduct(id uint) error {
 	return userRepo.db.Delete(&models.Product{}, id).Error
 }
 
-func (userRepo *ProductRepository) UpdateProductQuantity(productId, quantity uint) (bool, error) {
-	tx := userRepo.db.Begin()
-	if tx.Error != nil {
-		return false, tx.Error
-	}
-
-	var product models.Product
-	err := tx.First(&product, productId).Error
+// UpdateProductQuantity decrements the stock of every item in a single
+// transaction. Nothing is decremented if any product does not have enough stock.
+func (userRepo *ProductRepository) UpdateProductQuantity(items []models.ProductQuantity) (bool, error) {
+	err := userRepo.db.Transaction(func(tx *gorm.DB) error {
+		return decrementStock(tx, items)
+	})
 	if err != nil {
 		return false, err
 	}
-	if product.Quantity < quantity {
-		return false, errors.New("product quantity is not enough")
-	}
 
-	oldQuantity := product.Quantity
-	product.Quantity = product.Quantity - quantity
-	err = tx.Model(&product).
-		Where("id = ?", productId).
-		Where("quantity = ?", oldQuantity).
-		Update("quantity", product.Quantity).Error
-	if err != nil {
-		tx.Rollback()
-		return false, err
-	}
+	return true, nil
+}
 
-	err = tx.Commit().Error
+// RestoreProductQuantity gives the quantity of every item back to stock in a
+// single transaction.
+func (userRepo *ProductRepository) RestoreProductQuantity(items []models.ProductQuantity) (bool, error) {
+	err := userRepo.db.Transaction(func(tx *gorm.DB) error {
+		return incrementStock(tx, items)
+	})
 	if err != nil {
-		tx.Rollback()
 		return false, err
 	}
 
 	return true, nil
 }
+
+func decrementStock(tx *gorm.DB, items []models.ProductQuantity) error {
+	for _, item := range sortProductQuantities(items) {
+		result := tx.Model(&models.Product{}).
+			Where("id = ?", item.ProductId).
+			Where("quantity >= ?", item.Quantity).
+			Update("quantity", gorm.Expr("quantity - ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("product %d: %w", item.ProductId, ErrNotEnoughQuantity)
+		}
+	}
+	return nil
+}
+
+func incrementStock(tx *gorm.DB, items []models.ProductQuantity) error {
+	for _, item := range sortProductQuantities(items) {
+		result := tx.Model(&models.Product{}).
+			Where("id = ?", item.ProductId).
+			Update("quantity", gorm.Expr("quantity + ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("product %d: %w", item.ProductId, gorm.ErrRecordNotFound)
+		}
+	}
+	return nil
+}
+
+// sortProductQuantities orders the items by product id, so that concurrent
+// transactions lock the product rows in the same order.
+func sortProductQuantities(items []models.ProductQuantity) []models.ProductQuantity {
+	sorted := make([]models.ProductQuantity, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ProductId < sorted[j].ProductId
+	})
+	return sorted
+}
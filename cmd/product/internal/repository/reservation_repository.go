@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReservationExpired = errors.New("reservation is expired")
+	ErrReservationNotHeld = errors.New("reservation is not held")
+)
+
+type ReservationRepository struct {
+	db *gorm.DB
+}
+
+type IReservationRepository interface {
+	ReserveStock(reference string, items []models.ProductQuantity, expiresAt time.Time) (*models.Reservation, error)
+	CommitReservation(id uint) (*models.Reservation, error)
+	ReleaseReservation(id uint) (*models.Reservation, error)
+	ReleaseExpiredReservations(limit int) (int, error)
+}
+
+func NewReservationRepository(db *gorm.DB) *ReservationRepository {
+	return &ReservationRepository{db}
+}
+
+// ReserveStock takes the items out of stock and records them as held until
+// expiresAt. A reference can only be reserved once: reserving it again returns
+// the existing reservation.
+func (reservationRepo *ReservationRepository) ReserveStock(
+	reference string,
+	items []models.ProductQuantity,
+	expiresAt time.Time,
+) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := reservationRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Preload("Items").Where("reference = ?", reference).First(&reservation).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = decrementStock(tx, items)
+		if err != nil {
+			return err
+		}
+
+		reservation = models.Reservation{
+			Reference: reference,
+			Status:    models.ReservationStatusHeld,
+			ExpiresAt: expiresAt,
+		}
+		for _, item := range items {
+			reservation.Items = append(reservation.Items, models.ReservationItem{
+				ProductId: item.ProductId,
+				Quantity:  item.Quantity,
+			})
+		}
+		return tx.Create(&reservation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// CommitReservation makes a held reservation permanent. Committing a
+// reservation twice is a no-op, and an expired hold cannot be committed.
+func (reservationRepo *ReservationRepository) CommitReservation(id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := reservationRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := lockReservation(tx, id, &reservation)
+		if err != nil {
+			return err
+		}
+
+		switch reservation.Status {
+		case models.ReservationStatusCommitted:
+			return nil
+		case models.ReservationStatusHeld:
+		default:
+			return ErrReservationNotHeld
+		}
+		if time.Now().After(reservation.ExpiresAt) {
+			return ErrReservationExpired
+		}
+
+		reservation.Status = models.ReservationStatusCommitted
+		return tx.Model(&reservation).Update("status", reservation.Status).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// ReleaseReservation gives the reserved stock back, whether it is still held
+// or was already committed. Releasing a reservation that was already released
+// or expired is a no-op.
+func (reservationRepo *ReservationRepository) ReleaseReservation(id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := reservationRepo.db.Transaction(func(tx *gorm.DB) error {
+		err := lockReservation(tx, id, &reservation)
+		if err != nil {
+			return err
+		}
+
+		if reservation.Status == models.ReservationStatusReleased ||
+			reservation.Status == models.ReservationStatusExpired {
+			return nil
+		}
+
+		return releaseStock(tx, &reservation, models.ReservationStatusReleased)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// ReleaseExpiredReservations returns the stock of up to limit expired holds
+// and reports how many were released. Rows locked by another sweeper are
+// skipped.
+func (reservationRepo *ReservationRepository) ReleaseExpiredReservations(limit int) (int, error) {
+	released := 0
+	err := reservationRepo.db.Transaction(func(tx *gorm.DB) error {
+		var reservations []models.Reservation
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Preload("Items").
+			Where("status = ? AND expires_at < ?", models.ReservationStatusHeld, time.Now()).
+			Order("id").
+			Limit(limit).
+			Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range reservations {
+			err = releaseStock(tx, &reservations[i], models.ReservationStatusExpired)
+			if err != nil {
+				return err
+			}
+			released++
+		}
+		return nil
+	})
+
+	return released, err
+}
+
+func lockReservation(tx *gorm.DB, id uint, reservation *models.Reservation) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(reservation, id).Error
+}
+
+func releaseStock(tx *gorm.DB, reservation *models.Reservation, status string) error {
+	err := incrementStock(tx, reservation.ProductQuantities())
+	if err != nil {
+		return err
+	}
+
+	reservation.Status = status
+	return tx.Model(reservation).Update("status", status).Error
+}
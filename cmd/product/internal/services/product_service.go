@@ -1,10 +1,16 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/tricong1998/go-ecom/cmd/product/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
+var ErrInvalidPrice = errors.New("invalid product price")
+
 type ProductService struct {
 	ProductRepo repository.IProductRepository
 }
@@ -12,7 +18,8 @@ type ProductService struct {
 type IProductService interface {
 	CreateProduct(input *models.Product) error
 	ReadProduct(id uint) (*models.Product, error)
-	UpdateProductQuantity(productId, quantity uint) (bool, error)
+	UpdateProductQuantity(items []models.ProductQuantity) (bool, error)
+	RestoreProductQuantity(items []models.ProductQuantity) (bool, error)
 	ListProducts(
 		perPage, page int32,
 		username *string,
@@ -26,8 +33,11 @@ func NewProductService(userRepo repository.IProductRepository) *ProductService {
 }
 
 func (us *ProductService) CreateProduct(user *models.Product) error {
-	err := us.ProductRepo.CreateProduct(user)
-	return err
+	err := normalizePrice(user)
+	if err != nil {
+		return err
+	}
+	return us.ProductRepo.CreateProduct(user)
 }
 
 func (us *ProductService) ReadProduct(id uint) (*models.Product, error) {
@@ -43,14 +53,52 @@ func (us *ProductService) ListProducts(
 }
 
 func (us *ProductService) UpdateProduct(user *models.Product) error {
-	err := us.ProductRepo.UpdateProduct(user)
-	return err
+	err := normalizePrice(user)
+	if err != nil {
+		return err
+	}
+	return us.ProductRepo.UpdateProduct(user)
+}
+
+// normalizePrice defaults the currency of the price, and checks that the price
+// is positive and the discount is below it, in the same currency.
+func normalizePrice(product *models.Product) error {
+	if product.Price.Currency == "" {
+		product.Price.Currency = money.DefaultCurrency
+	}
+	if product.Discount.Currency == "" {
+		product.Discount.Currency = product.Price.Currency
+	}
+
+	err := product.Price.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	if product.Price.IsZero() {
+		return fmt.Errorf("%w: price must be positive", ErrInvalidPrice)
+	}
+	err = product.Discount.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	cmp, err := product.Discount.Cmp(product.Price)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	if cmp >= 0 {
+		return fmt.Errorf("%w: discount must be below the price", ErrInvalidPrice)
+	}
+	return nil
 }
 
 func (us *ProductService) DeleteProduct(id uint) error {
 	return us.ProductRepo.DeleteProduct(id)
 }
 
-func (us *ProductService) UpdateProductQuantity(productId, quantity uint) (bool, error) {
-	return us.ProductRepo.UpdateProductQuantity(productId, quantity)
+func (us *ProductService) UpdateProductQuantity(items []models.ProductQuantity) (bool, error) {
+	return us.ProductRepo.UpdateProductQuantity(items)
+}
+
+func (us *ProductService) RestoreProductQuantity(items []models.ProductQuantity) (bool, error) {
+	return us.ProductRepo.RestoreProductQuantity(items)
 }
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/product/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+)
+
+type ReservationService struct {
+	ReservationRepo repository.IReservationRepository
+	DefaultTTL      time.Duration
+}
+
+type IReservationService interface {
+	ReserveStock(reference string, items []models.ProductQuantity, ttl time.Duration) (*models.Reservation, error)
+	CommitReservation(id uint) (*models.Reservation, error)
+	ReleaseReservation(id uint) (*models.Reservation, error)
+	ReleaseExpiredReservations(limit int) (int, error)
+}
+
+func NewReservationService(reservationRepo repository.IReservationRepository, defaultTTL time.Duration) *ReservationService {
+	return &ReservationService{reservationRepo, defaultTTL}
+}
+
+// ReserveStock holds the items for ttl, or for DefaultTTL when ttl is zero.
+func (rs *ReservationService) ReserveStock(
+	reference string,
+	items []models.ProductQuantity,
+	ttl time.Duration,
+) (*models.Reservation, error) {
+	if ttl <= 0 {
+		ttl = rs.DefaultTTL
+	}
+	return rs.ReservationRepo.ReserveStock(reference, items, time.Now().Add(ttl))
+}
+
+func (rs *ReservationService) CommitReservation(id uint) (*models.Reservation, error) {
+	return rs.ReservationRepo.CommitReservation(id)
+}
+
+func (rs *ReservationService) ReleaseReservation(id uint) (*models.Reservation, error) {
+	return rs.ReservationRepo.ReleaseReservation(id)
+}
+
+func (rs *ReservationService) ReleaseExpiredReservations(limit int) (int, error) {
+	return rs.ReservationRepo.ReleaseExpiredReservations(limit)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tricong1998/go-ecom/cmd/product/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"gorm.io/gorm"
+)
+
+// StockEventService holds and commits the stock of orders processed
+// asynchronously, and writes the outcome to the outbox for the other
+// services.
+type StockEventService struct {
+	ReservationService IReservationService
+	Outbox             rabbitmq.IOutboxWriter
+}
+
+func NewStockEventService(reservationService IReservationService, outbox rabbitmq.IOutboxWriter) *StockEventService {
+	return &StockEventService{reservationService, outbox}
+}
+
+// ReserveOrderStock reserves the items of a new order. Reserving the same
+// order again returns the existing reservation, so a redelivered event
+// publishes the same outcome.
+func (s *StockEventService) ReserveOrderStock(event events.OrderCreated) error {
+	items := make([]models.ProductQuantity, 0, len(event.Items))
+	for _, item := range event.Items {
+		items = append(items, models.ProductQuantity{ProductId: item.ProductId, Quantity: item.Quantity})
+	}
+
+	reservation, err := s.ReservationService.ReserveStock(fmt.Sprintf("order-%d", event.OrderId), items, 0)
+	if errors.Is(err, repository.ErrNotEnoughQuantity) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.Outbox.Write(rabbitmq.STOCK_RESERVATION_FAILED_ROUTING_KEY, events.StockReservationFailed{
+			OrderId: event.OrderId,
+			Reason:  err.Error(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.Outbox.Write(rabbitmq.STOCK_RESERVED_ROUTING_KEY, events.StockReserved{
+		OrderId:       event.OrderId,
+		UserId:        event.UserId,
+		Amount:        event.Amount,
+		PaymentMethod: event.PaymentMethod,
+		ReservationId: reservation.ID,
+	})
+}
+
+// CommitOrderStock commits the reservation of a paid order. When the hold has
+// expired in the meantime the payment has to be refunded.
+func (s *StockEventService) CommitOrderStock(event events.PaymentSucceeded) error {
+	_, err := s.ReservationService.CommitReservation(event.ReservationId)
+	if errors.Is(err, repository.ErrReservationExpired) || errors.Is(err, repository.ErrReservationNotHeld) {
+		return s.Outbox.Write(rabbitmq.STOCK_COMMIT_FAILED_ROUTING_KEY, events.StockCommitFailed{
+			OrderId:       event.OrderId,
+			PaymentId:     event.PaymentId,
+			ReservationId: event.ReservationId,
+			Reason:        err.Error(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.Outbox.Write(rabbitmq.STOCK_COMMITTED_ROUTING_KEY, events.StockCommitted{
+		OrderId:       event.OrderId,
+		PaymentId:     event.PaymentId,
+		ReservationId: event.ReservationId,
+	})
+}
+
+// ReleaseOrderStock gives back the stock held for an order whose payment
+// failed.
+func (s *StockEventService) ReleaseOrderStock(event events.PaymentFailed) error {
+	_, err := s.ReservationService.ReleaseReservation(event.ReservationId)
+	return err
+}
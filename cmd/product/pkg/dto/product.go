@@ -4,12 +4,16 @@ import (
 	"time"
 
 	"github.com/tricong1998/go-ecom/cmd/product/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
 )
 
 type CreateProductDto struct {
-	Name     string `json:"name" binding:"required"`
-	Price    uint   `json:"price" binding:"required,min=1"`
-	Quantity uint   `json:"quantity" binding:"required,min=1"`
+	Name string `json:"name" binding:"required"`
+	// Price is in minor units, its currency defaults to USD.
+	Price    money.Money `json:"price"`
+	Quantity uint        `json:"quantity" binding:"required,min=1"`
+	// Discount is in the currency of the price when its own is omitted.
+	Discount money.Money `json:"discount"`
 }
 
 type ReadProductRequest struct {
@@ -17,12 +21,13 @@ type ReadProductRequest struct {
 }
 
 type ProductResponse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Price     uint      `json:"price"`
-	Quantity  uint      `json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint        `json:"id"`
+	Name      string      `json:"name"`
+	Price     money.Money `json:"price"`
+	Discount  money.Money `json:"discount"`
+	Quantity  uint        `json:"quantity"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
 
 type ListProductQuery struct {
@@ -41,6 +46,7 @@ func ToProductResponse(user *models.Product) *ProductResponse {
 		ID:        user.ID,
 		Name:      user.Name,
 		Price:     user.Price,
+		Discount:  user.Discount,
 		Quantity:  user.Quantity,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
@@ -1,10 +1,23 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"github.com/tricong1998/go-ecom/pkg/money"
+	"gorm.io/gorm"
+)
 
 type Product struct {
 	gorm.Model
-	Name     string `json:"name"`
-	Quantity uint   `json:"quantity"`
-	Price    uint   `json:"price"`
+	Name     string      `json:"name"`
+	Quantity uint        `json:"quantity"`
+	Price    money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	// Discount is taken off the price of every unit sold, in the currency of
+	// the price.
+	Discount money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+}
+
+// ProductQuantity is a quantity of a single product, used to change the stock
+// of several products at once.
+type ProductQuantity struct {
+	ProductId uint `json:"product_id"`
+	Quantity  uint `json:"quantity"`
 }
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReservationStatusHeld      = "held"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+	ReservationStatusExpired   = "expired"
+)
+
+// Reservation holds stock for an order until it is committed or released.
+// The held quantities are taken out of Product.Quantity when the reservation
+// is made, and given back when it is released or expires.
+type Reservation struct {
+	gorm.Model
+	Reference string            `json:"reference" gorm:"uniqueIndex"`
+	Status    string            `json:"status" gorm:"index"`
+	ExpiresAt time.Time         `json:"expires_at" gorm:"index"`
+	Items     []ReservationItem `json:"items"`
+}
+
+type ReservationItem struct {
+	gorm.Model
+	ReservationID uint `json:"reservation_id" gorm:"index"`
+	ProductId     uint `json:"product_id"`
+	Quantity      uint `json:"quantity"`
+}
+
+func (r *Reservation) ProductQuantities() []ProductQuantity {
+	items := make([]ProductQuantity, 0, len(r.Items))
+	for _, v := range r.Items {
+		items = append(items, ProductQuantity{ProductId: v.ProductId, Quantity: v.Quantity})
+	}
+	return items
+}
@@ -0,0 +1,216 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.3
+// source: rpc_restore_product_quantity.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RestoreProductQuantityRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Items []*ProductQuantity `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"`
+}
+
+func (x *RestoreProductQuantityRequest) Reset() {
+	*x = RestoreProductQuantityRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_restore_product_quantity_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RestoreProductQuantityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreProductQuantityRequest) ProtoMessage() {}
+
+func (x *RestoreProductQuantityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_restore_product_quantity_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreProductQuantityRequest.ProtoReflect.Descriptor instead.
+func (*RestoreProductQuantityRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_restore_product_quantity_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RestoreProductQuantityRequest) GetItems() []*ProductQuantity {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type RestoreProductQuantityResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+}
+
+func (x *RestoreProductQuantityResponse) Reset() {
+	*x = RestoreProductQuantityResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_restore_product_quantity_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RestoreProductQuantityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreProductQuantityResponse) ProtoMessage() {}
+
+func (x *RestoreProductQuantityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_restore_product_quantity_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreProductQuantityResponse.ProtoReflect.Descriptor instead.
+func (*RestoreProductQuantityResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_restore_product_quantity_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RestoreProductQuantityResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+var File_rpc_restore_product_quantity_proto protoreflect.FileDescriptor
+
+var file_rpc_restore_product_quantity_proto_rawDesc = []byte{
+	0x0a, 0x22, 0x72, 0x70, 0x63, 0x5f, 0x72, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x5f, 0x70, 0x72,
+	0x6f, 0x64, 0x75, 0x63, 0x74, 0x5f, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x0d, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63,
+	0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x56, 0x0a, 0x1d, 0x52, 0x65, 0x73, 0x74, 0x6f,
+	0x72, 0x65, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x51, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x74,
+	0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x05, 0x69, 0x74, 0x65, 0x6d,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x50, 0x72, 0x6f,
+	0x64, 0x75, 0x63, 0x74, 0x51, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x52, 0x05, 0x69, 0x74,
+	0x65, 0x6d, 0x73, 0x4a, 0x04, 0x08, 0x01, 0x10, 0x02, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x03, 0x22,
+	0x3a, 0x0a, 0x1e, 0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63,
+	0x74, 0x51, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x42, 0x2f, 0x5a, 0x2d, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x72, 0x69, 0x63, 0x6f, 0x6e,
+	0x67, 0x31, 0x39, 0x39, 0x38, 0x2f, 0x67, 0x6f, 0x2d, 0x65, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x6d,
+	0x64, 0x2f, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rpc_restore_product_quantity_proto_rawDescOnce sync.Once
+	file_rpc_restore_product_quantity_proto_rawDescData = file_rpc_restore_product_quantity_proto_rawDesc
+)
+
+func file_rpc_restore_product_quantity_proto_rawDescGZIP() []byte {
+	file_rpc_restore_product_quantity_proto_rawDescOnce.Do(func() {
+		file_rpc_restore_product_quantity_proto_rawDescData = protoimpl.X.CompressGZIP(file_rpc_restore_product_quantity_proto_rawDescData)
+	})
+	return file_rpc_restore_product_quantity_proto_rawDescData
+}
+
+var file_rpc_restore_product_quantity_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_rpc_restore_product_quantity_proto_goTypes = []any{
+	(*RestoreProductQuantityRequest)(nil),  // 0: pb.RestoreProductQuantityRequest
+	(*RestoreProductQuantityResponse)(nil), // 1: pb.RestoreProductQuantityResponse
+	(*ProductQuantity)(nil),                // 2: pb.ProductQuantity
+}
+var file_rpc_restore_product_quantity_proto_depIdxs = []int32{
+	2, // 0: pb.RestoreProductQuantityRequest.items:type_name -> pb.ProductQuantity
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_rpc_restore_product_quantity_proto_init() }
+func file_rpc_restore_product_quantity_proto_init() {
+	if File_rpc_restore_product_quantity_proto != nil {
+		return
+	}
+	file_product_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_rpc_restore_product_quantity_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*RestoreProductQuantityRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_restore_product_quantity_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*RestoreProductQuantityResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rpc_restore_product_quantity_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_rpc_restore_product_quantity_proto_goTypes,
+		DependencyIndexes: file_rpc_restore_product_quantity_proto_depIdxs,
+		MessageInfos:      file_rpc_restore_product_quantity_proto_msgTypes,
+	}.Build()
+	File_rpc_restore_product_quantity_proto = out.File
+	file_rpc_restore_product_quantity_proto_rawDesc = nil
+	file_rpc_restore_product_quantity_proto_goTypes = nil
+	file_rpc_restore_product_quantity_proto_depIdxs = nil
+}
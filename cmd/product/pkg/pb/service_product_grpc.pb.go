@@ -19,8 +19,12 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ProductGrpc_ReadProduct_FullMethodName           = "/pb.ProductGrpc/ReadProduct"
-	ProductGrpc_UpdateProductQuantity_FullMethodName = "/pb.ProductGrpc/UpdateProductQuantity"
+	ProductGrpc_ReadProduct_FullMethodName            = "/pb.ProductGrpc/ReadProduct"
+	ProductGrpc_UpdateProductQuantity_FullMethodName  = "/pb.ProductGrpc/UpdateProductQuantity"
+	ProductGrpc_RestoreProductQuantity_FullMethodName = "/pb.ProductGrpc/RestoreProductQuantity"
+	ProductGrpc_ReserveStock_FullMethodName           = "/pb.ProductGrpc/ReserveStock"
+	ProductGrpc_CommitReservation_FullMethodName      = "/pb.ProductGrpc/CommitReservation"
+	ProductGrpc_ReleaseReservation_FullMethodName     = "/pb.ProductGrpc/ReleaseReservation"
 )
 
 // ProductGrpcClient is the client API for ProductGrpc service.
@@ -29,6 +33,10 @@ const (
 type ProductGrpcClient interface {
 	ReadProduct(ctx context.Context, in *ReadProductRequest, opts ...grpc.CallOption) (*ReadProductResponse, error)
 	UpdateProductQuantity(ctx context.Context, in *UpdateProductQuantityRequest, opts ...grpc.CallOption) (*UpdateProductQuantityResponse, error)
+	RestoreProductQuantity(ctx context.Context, in *RestoreProductQuantityRequest, opts ...grpc.CallOption) (*RestoreProductQuantityResponse, error)
+	ReserveStock(ctx context.Context, in *ReserveStockRequest, opts ...grpc.CallOption) (*ReserveStockResponse, error)
+	CommitReservation(ctx context.Context, in *CommitReservationRequest, opts ...grpc.CallOption) (*CommitReservationResponse, error)
+	ReleaseReservation(ctx context.Context, in *ReleaseReservationRequest, opts ...grpc.CallOption) (*ReleaseReservationResponse, error)
 }
 
 type productGrpcClient struct {
@@ -59,12 +67,56 @@ func (c *productGrpcClient) UpdateProductQuantity(ctx context.Context, in *Updat
 	return out, nil
 }
 
+func (c *productGrpcClient) RestoreProductQuantity(ctx context.Context, in *RestoreProductQuantityRequest, opts ...grpc.CallOption) (*RestoreProductQuantityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreProductQuantityResponse)
+	err := c.cc.Invoke(ctx, ProductGrpc_RestoreProductQuantity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *productGrpcClient) ReserveStock(ctx context.Context, in *ReserveStockRequest, opts ...grpc.CallOption) (*ReserveStockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReserveStockResponse)
+	err := c.cc.Invoke(ctx, ProductGrpc_ReserveStock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *productGrpcClient) CommitReservation(ctx context.Context, in *CommitReservationRequest, opts ...grpc.CallOption) (*CommitReservationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommitReservationResponse)
+	err := c.cc.Invoke(ctx, ProductGrpc_CommitReservation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *productGrpcClient) ReleaseReservation(ctx context.Context, in *ReleaseReservationRequest, opts ...grpc.CallOption) (*ReleaseReservationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseReservationResponse)
+	err := c.cc.Invoke(ctx, ProductGrpc_ReleaseReservation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProductGrpcServer is the server API for ProductGrpc service.
 // All implementations must embed UnimplementedProductGrpcServer
 // for forward compatibility.
 type ProductGrpcServer interface {
 	ReadProduct(context.Context, *ReadProductRequest) (*ReadProductResponse, error)
 	UpdateProductQuantity(context.Context, *UpdateProductQuantityRequest) (*UpdateProductQuantityResponse, error)
+	RestoreProductQuantity(context.Context, *RestoreProductQuantityRequest) (*RestoreProductQuantityResponse, error)
+	ReserveStock(context.Context, *ReserveStockRequest) (*ReserveStockResponse, error)
+	CommitReservation(context.Context, *CommitReservationRequest) (*CommitReservationResponse, error)
+	ReleaseReservation(context.Context, *ReleaseReservationRequest) (*ReleaseReservationResponse, error)
 	mustEmbedUnimplementedProductGrpcServer()
 }
 
@@ -81,6 +133,18 @@ func (UnimplementedProductGrpcServer) ReadProduct(context.Context, *ReadProductR
 func (UnimplementedProductGrpcServer) UpdateProductQuantity(context.Context, *UpdateProductQuantityRequest) (*UpdateProductQuantityResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateProductQuantity not implemented")
 }
+func (UnimplementedProductGrpcServer) RestoreProductQuantity(context.Context, *RestoreProductQuantityRequest) (*RestoreProductQuantityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreProductQuantity not implemented")
+}
+func (UnimplementedProductGrpcServer) ReserveStock(context.Context, *ReserveStockRequest) (*ReserveStockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveStock not implemented")
+}
+func (UnimplementedProductGrpcServer) CommitReservation(context.Context, *CommitReservationRequest) (*CommitReservationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitReservation not implemented")
+}
+func (UnimplementedProductGrpcServer) ReleaseReservation(context.Context, *ReleaseReservationRequest) (*ReleaseReservationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseReservation not implemented")
+}
 func (UnimplementedProductGrpcServer) mustEmbedUnimplementedProductGrpcServer() {}
 func (UnimplementedProductGrpcServer) testEmbeddedByValue()                     {}
 
@@ -138,6 +202,78 @@ func _ProductGrpc_UpdateProductQuantity_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProductGrpc_RestoreProductQuantity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreProductQuantityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductGrpcServer).RestoreProductQuantity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProductGrpc_RestoreProductQuantity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductGrpcServer).RestoreProductQuantity(ctx, req.(*RestoreProductQuantityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProductGrpc_ReserveStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductGrpcServer).ReserveStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProductGrpc_ReserveStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductGrpcServer).ReserveStock(ctx, req.(*ReserveStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProductGrpc_CommitReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommitReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductGrpcServer).CommitReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProductGrpc_CommitReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductGrpcServer).CommitReservation(ctx, req.(*CommitReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProductGrpc_ReleaseReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProductGrpcServer).ReleaseReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProductGrpc_ReleaseReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProductGrpcServer).ReleaseReservation(ctx, req.(*ReleaseReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ProductGrpc_ServiceDesc is the grpc.ServiceDesc for ProductGrpc service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +289,22 @@ var ProductGrpc_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateProductQuantity",
 			Handler:    _ProductGrpc_UpdateProductQuantity_Handler,
 		},
+		{
+			MethodName: "RestoreProductQuantity",
+			Handler:    _ProductGrpc_RestoreProductQuantity_Handler,
+		},
+		{
+			MethodName: "ReserveStock",
+			Handler:    _ProductGrpc_ReserveStock_Handler,
+		},
+		{
+			MethodName: "CommitReservation",
+			Handler:    _ProductGrpc_CommitReservation_Handler,
+		},
+		{
+			MethodName: "ReleaseReservation",
+			Handler:    _ProductGrpc_ReleaseReservation_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "service_product.proto",
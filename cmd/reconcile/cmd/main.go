@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/tricong1998/go-ecom/cmd/reconcile/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/reconcile/internal/database"
+	"github.com/tricong1998/go-ecom/cmd/reconcile/internal/reconcile"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"gorm.io/gorm"
+)
+
+type options struct {
+	format   string
+	out      string
+	since    time.Duration
+	emit     bool
+	interval time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.format, "format", reconcile.FormatJSON, "report format, json or csv")
+	flag.StringVar(&opts.out, "out", "", "file to write the report to, stdout when empty")
+	flag.DurationVar(&opts.since, "since", 0, "only reconcile records created in this last duration, everything when 0")
+	flag.BoolVar(&opts.emit, "emit", false, "write corrective payment.confirmed events to the payment outbox")
+	flag.DurationVar(&opts.interval, "interval", 0, "run again every interval until stopped, once when 0")
+	flag.Parse()
+
+	// The report goes to stdout, so the logs go to stderr.
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+	if opts.format != reconcile.FormatJSON && opts.format != reconcile.FormatCSV {
+		log.Fatal().Msgf("Unknown report format %q", opts.format)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot load config")
+	}
+
+	orderDB, err := database.Initialize(&cfg.OrderDB)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot initialize order database")
+	}
+	paymentDB, err := database.Initialize(&cfg.PaymentDB)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot initialize payment database")
+	}
+	store := reconcile.NewStore(orderDB, paymentDB)
+
+	if opts.interval == 0 {
+		err = run(store, paymentDB, opts, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Cannot reconcile payments")
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for {
+		err = run(store, paymentDB, opts, log)
+		if err != nil {
+			log.Error().Err(err).Msg("Cannot reconcile payments")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run reconciles the orders and payments once, writes the report and emits
+// the corrections when asked to.
+func run(store *reconcile.Store, paymentDB *gorm.DB, opts options, log zerolog.Logger) error {
+	startedAt := time.Now()
+	var since time.Time
+	if opts.since > 0 {
+		since = startedAt.Add(-opts.since)
+	}
+
+	orders, payments, err := store.Load(since)
+	if err != nil {
+		return err
+	}
+
+	report := reconcile.Report{
+		StartedAt:  startedAt,
+		Orders:     len(orders),
+		Payments:   len(payments),
+		Mismatches: reconcile.Reconcile(orders, payments),
+	}
+	err = writeReport(&report, opts)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Reconciled %d orders and %d payments, found %d mismatches",
+		report.Orders, report.Payments, len(report.Mismatches))
+
+	if !opts.emit {
+		return nil
+	}
+	corrections := reconcile.Corrections(report.Mismatches)
+	err = paymentDB.Transaction(func(tx *gorm.DB) error {
+		for _, correction := range corrections {
+			err := rabbitmq.WriteOutbox(tx, rabbitmq.PAYMENT_CONFIRMED_ROUTING_KEY, correction)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Emitted %d corrections", len(corrections))
+	return nil
+}
+
+func writeReport(report *reconcile.Report, opts options) error {
+	var w io.Writer = os.Stdout
+	if opts.out != "" {
+		file, err := os.Create(opts.out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return report.Write(w, opts.format)
+}
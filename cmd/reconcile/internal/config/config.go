@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+type DBConfig struct {
+	DBHost     string
+	DBPort     string
+	DBUser     string
+	DBPassword string
+	DBName     string
+}
+
+type Config struct {
+	OrderDB   DBConfig
+	PaymentDB DBConfig
+}
+
+func Load() (*Config, error) {
+	// Load .env file
+	err := godotenv.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		OrderDB:   loadDBConfig("ORDER_DB_NAME"),
+		PaymentDB: loadDBConfig("PAYMENT_DB_NAME"),
+	}
+
+	return config, nil
+}
+
+// loadDBConfig reads the database server shared by the services, and the
+// database name from nameEnv.
+func loadDBConfig(nameEnv string) DBConfig {
+	dbConfig := DBConfig{
+		DBHost:     os.Getenv("DB_HOST"),
+		DBPort:     os.Getenv("DB_PORT"),
+		DBUser:     os.Getenv("DB_USER"),
+		DBPassword: os.Getenv("DB_PASSWORD"),
+		DBName:     os.Getenv(nameEnv),
+	}
+
+	if dbConfig.DBHost == "" {
+		dbConfig.DBHost = "localhost"
+	}
+
+	if dbConfig.DBPort == "" {
+		dbConfig.DBPort = "5432"
+	}
+
+	if dbConfig.DBName == "" {
+		dbConfig.DBName = "simple-ecom"
+	}
+
+	return dbConfig
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/tricong1998/go-ecom/cmd/reconcile/internal/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func Initialize(dbConfig *config.DBConfig) (*gorm.DB, error) {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
+		dbConfig.DBHost,
+		dbConfig.DBUser,
+		dbConfig.DBPassword,
+		dbConfig.DBName,
+		dbConfig.DBPort,
+	)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,36 @@
+package reconcile
+
+import (
+	paymentModels "github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+)
+
+const correctionReason = "corrected by payment reconciliation"
+
+// Corrections returns the payment.confirmed events that bring the orders back
+// in line with their payments: an order still waiting for a payment that was
+// taken is confirmed paid, and a paid order whose only payment failed is
+// failed. Other mismatches need a person to look at them.
+func Corrections(mismatches []Mismatch) []events.PaymentConfirmed {
+	var corrections []events.PaymentConfirmed
+	for _, m := range mismatches {
+		switch {
+		case m.Kind == KindUnpaidOrderCharged && m.OrderStatus == OrderStatusAwaitingPayment:
+			corrections = append(corrections, events.PaymentConfirmed{
+				OrderId:   m.OrderId,
+				PaymentId: m.PaymentId,
+				Status:    paymentModels.PaymentStatusSuccess,
+				Reason:    correctionReason,
+			})
+		case m.Kind == KindOrderWithoutPayment && m.OrderStatus == OrderStatusPaid &&
+			(m.PaymentStatus == paymentModels.PaymentStatusFailed || m.PaymentStatus == paymentModels.PaymentStatusVoided):
+			corrections = append(corrections, events.PaymentConfirmed{
+				OrderId:   m.OrderId,
+				PaymentId: m.PaymentId,
+				Status:    paymentModels.PaymentStatusFailed,
+				Reason:    correctionReason,
+			})
+		}
+	}
+	return corrections
+}
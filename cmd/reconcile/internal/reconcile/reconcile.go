@@ -0,0 +1,176 @@
+// Package reconcile matches the orders of the order service with the payments
+// of the payment service, and reports the orders and payments that disagree.
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+
+	paymentModels "github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+// Order statuses of the order service. The order models are internal to that
+// service, so the reconciliation reads the orders table on its own.
+const (
+	OrderStatusCreated         = "created"
+	OrderStatusAwaitingPayment = "awaiting_payment"
+	OrderStatusPaid            = "paid"
+	OrderStatusFulfilling      = "fulfilling"
+	OrderStatusShipped         = "shipped"
+	OrderStatusDelivered       = "delivered"
+	OrderStatusCancelled       = "cancelled"
+	OrderStatusRefunded        = "refunded"
+	OrderStatusFailed          = "failed"
+)
+
+const (
+	// KindOrderWithoutPayment is an order marked paid that has no payment
+	// the money was taken for.
+	KindOrderWithoutPayment = "order_without_payment"
+	// KindPaymentWithoutOrder is a payment whose order does not exist.
+	KindPaymentWithoutOrder = "payment_without_order"
+	// KindAmountMismatch is a settled payment whose amount or currency
+	// differs from the amount of its order.
+	KindAmountMismatch = "amount_mismatch"
+	// KindUnpaidOrderCharged is an order that is not marked paid although the
+	// money of one of its payments was taken and not fully refunded.
+	KindUnpaidOrderCharged = "unpaid_order_charged"
+)
+
+// Order is the part of an order of the order service the reconciliation
+// needs.
+type Order struct {
+	ID        uint
+	Status    string
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	PaymentId uint
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
+
+// Mismatch is one disagreement between an order and its payments. OrderId or
+// PaymentId is zero when that side is missing.
+type Mismatch struct {
+	Kind          string      `json:"kind"`
+	OrderId       uint        `json:"order_id"`
+	OrderStatus   string      `json:"order_status"`
+	OrderAmount   money.Money `json:"order_amount"`
+	PaymentId     uint        `json:"payment_id"`
+	PaymentStatus string      `json:"payment_status"`
+	PaymentAmount money.Money `json:"payment_amount"`
+	Detail        string      `json:"detail"`
+}
+
+// Reconcile matches every payment to its order by Payment.OrderID and returns
+// the mismatches, ordered by order and then payment id.
+func Reconcile(orders []Order, payments []paymentModels.Payment) []Mismatch {
+	paymentsByOrder := make(map[uint][]paymentModels.Payment)
+	for _, payment := range payments {
+		paymentsByOrder[payment.OrderID] = append(paymentsByOrder[payment.OrderID], payment)
+	}
+
+	var mismatches []Mismatch
+	ordersById := make(map[uint]bool, len(orders))
+	for _, order := range orders {
+		ordersById[order.ID] = true
+		mismatches = append(mismatches, reconcileOrder(order, paymentsByOrder[order.ID])...)
+	}
+
+	for _, payment := range payments {
+		if ordersById[payment.OrderID] {
+			continue
+		}
+		mismatches = append(mismatches, Mismatch{
+			Kind:          KindPaymentWithoutOrder,
+			PaymentId:     payment.ID,
+			PaymentStatus: payment.Status,
+			PaymentAmount: payment.Amount,
+			Detail:        fmt.Sprintf("order %d does not exist", payment.OrderID),
+		})
+	}
+
+	sort.SliceStable(mismatches, func(i, j int) bool {
+		if mismatches[i].OrderId != mismatches[j].OrderId {
+			return mismatches[i].OrderId < mismatches[j].OrderId
+		}
+		return mismatches[i].PaymentId < mismatches[j].PaymentId
+	})
+	return mismatches
+}
+
+func reconcileOrder(order Order, payments []paymentModels.Payment) []Mismatch {
+	var mismatches []Mismatch
+	newMismatch := func(kind string, payment *paymentModels.Payment, detail string) Mismatch {
+		mismatch := Mismatch{
+			Kind:        kind,
+			OrderId:     order.ID,
+			OrderStatus: order.Status,
+			OrderAmount: order.Amount,
+			Detail:      detail,
+		}
+		if payment != nil {
+			mismatch.PaymentId = payment.ID
+			mismatch.PaymentStatus = payment.Status
+			mismatch.PaymentAmount = payment.Amount
+		}
+		return mismatch
+	}
+
+	settled := settledPayment(payments)
+	switch {
+	case isPaidOrder(order.Status) && settled == nil:
+		mismatches = append(mismatches, newMismatch(
+			KindOrderWithoutPayment, latestPayment(payments), "order is paid but no payment was settled",
+		))
+	case !isPaidOrder(order.Status) && settled != nil && settled.Status != paymentModels.PaymentStatusRefunded:
+		mismatches = append(mismatches, newMismatch(
+			KindUnpaidOrderCharged, settled, fmt.Sprintf("payment is %s but order is %s", settled.Status, order.Status),
+		))
+	}
+
+	if settled != nil && settled.Amount != order.Amount {
+		mismatches = append(mismatches, newMismatch(
+			KindAmountMismatch, settled, fmt.Sprintf("order is %s but payment is %s", order.Amount, settled.Amount),
+		))
+	}
+	return mismatches
+}
+
+// isPaidOrder reports whether an order in this status was paid for.
+func isPaidOrder(status string) bool {
+	switch status {
+	case OrderStatusPaid, OrderStatusFulfilling, OrderStatusShipped, OrderStatusDelivered:
+		return true
+	}
+	return false
+}
+
+// settledPayment returns the latest payment whose money was taken, refunded
+// or not, or nil when there is none.
+func settledPayment(payments []paymentModels.Payment) *paymentModels.Payment {
+	var settled *paymentModels.Payment
+	for i := range payments {
+		switch payments[i].Status {
+		case paymentModels.PaymentStatusSuccess,
+			paymentModels.PaymentStatusPartiallyRefunded,
+			paymentModels.PaymentStatusRefunded:
+			if settled == nil || payments[i].ID > settled.ID {
+				settled = &payments[i]
+			}
+		}
+	}
+	return settled
+}
+
+func latestPayment(payments []paymentModels.Payment) *paymentModels.Payment {
+	var latest *paymentModels.Payment
+	for i := range payments {
+		if latest == nil || payments[i].ID > latest.ID {
+			latest = &payments[i]
+		}
+	}
+	return latest
+}
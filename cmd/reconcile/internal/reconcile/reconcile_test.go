@@ -0,0 +1,57 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	paymentModels "github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+func newPayment(id, orderId uint, amount int64, status string) paymentModels.Payment {
+	payment := paymentModels.Payment{OrderID: orderId, Amount: money.New(amount, "USD"), Status: status}
+	payment.ID = id
+	return payment
+}
+
+func TestReconcile(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Status: OrderStatusPaid, Amount: money.New(100, "USD")},
+		{ID: 2, Status: OrderStatusPaid, Amount: money.New(100, "USD")},
+		{ID: 3, Status: OrderStatusAwaitingPayment, Amount: money.New(100, "USD")},
+		{ID: 4, Status: OrderStatusDelivered, Amount: money.New(100, "USD")},
+		{ID: 5, Status: OrderStatusCancelled, Amount: money.New(100, "USD")},
+	}
+	payments := []paymentModels.Payment{
+		newPayment(1, 1, 100, paymentModels.PaymentStatusFailed),
+		newPayment(2, 1, 100, paymentModels.PaymentStatusSuccess),
+		newPayment(3, 2, 100, paymentModels.PaymentStatusFailed),
+		newPayment(4, 3, 100, paymentModels.PaymentStatusSuccess),
+		newPayment(5, 4, 90, paymentModels.PaymentStatusSuccess),
+		newPayment(6, 5, 100, paymentModels.PaymentStatusRefunded),
+		newPayment(7, 9, 100, paymentModels.PaymentStatusSuccess),
+	}
+
+	mismatches := Reconcile(orders, payments)
+
+	var kinds []string
+	for _, m := range mismatches {
+		kinds = append(kinds, m.Kind)
+	}
+	assert.Equal(t, []string{
+		KindPaymentWithoutOrder,
+		KindOrderWithoutPayment,
+		KindUnpaidOrderCharged,
+		KindAmountMismatch,
+	}, kinds)
+	assert.Equal(t, uint(7), mismatches[0].PaymentId)
+	assert.Equal(t, uint(2), mismatches[1].OrderId)
+	assert.Equal(t, uint(3), mismatches[1].PaymentId)
+
+	corrections := Corrections(mismatches)
+	assert.Len(t, corrections, 2)
+	assert.Equal(t, uint(2), corrections[0].OrderId)
+	assert.Equal(t, paymentModels.PaymentStatusFailed, corrections[0].Status)
+	assert.Equal(t, uint(3), corrections[1].OrderId)
+	assert.Equal(t, paymentModels.PaymentStatusSuccess, corrections[1].Status)
+}
@@ -0,0 +1,67 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Report is the outcome of one reconciliation run.
+type Report struct {
+	StartedAt  time.Time  `json:"started_at"`
+	Orders     int        `json:"orders"`
+	Payments   int        `json:"payments"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+// csvHeader lists the CSV columns. Amounts are in minor units of the currency
+// in the next column.
+var csvHeader = []string{
+	"kind", "order_id", "order_status", "order_amount", "order_currency",
+	"payment_id", "payment_status", "payment_amount", "payment_currency", "detail",
+}
+
+// Write writes the report in format, either FormatJSON or FormatCSV. The CSV
+// report only holds the mismatches.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		err := writer.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.Mismatches {
+			err = writer.Write([]string{
+				m.Kind,
+				strconv.FormatUint(uint64(m.OrderId), 10),
+				m.OrderStatus,
+				strconv.FormatInt(m.OrderAmount.Amount, 10),
+				m.OrderAmount.Currency,
+				strconv.FormatUint(uint64(m.PaymentId), 10),
+				m.PaymentStatus,
+				strconv.FormatInt(m.PaymentAmount.Amount, 10),
+				m.PaymentAmount.Currency,
+				m.Detail,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
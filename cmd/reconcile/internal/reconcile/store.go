@@ -0,0 +1,72 @@
+package reconcile
+
+import (
+	"time"
+
+	paymentModels "github.com/tricong1998/go-ecom/cmd/payment/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Store reads the orders and payments to reconcile from the databases of the
+// order and payment services.
+type Store struct {
+	orderDB   *gorm.DB
+	paymentDB *gorm.DB
+}
+
+func NewStore(orderDB, paymentDB *gorm.DB) *Store {
+	return &Store{orderDB, paymentDB}
+}
+
+// Load returns the orders and payments created since then, together with the
+// orders of those payments and the payments of those orders, so that records
+// on both sides of the window are still matched. A zero since loads
+// everything.
+func (s *Store) Load(since time.Time) ([]Order, []paymentModels.Payment, error) {
+	var orders []Order
+	err := s.orderDB.Where("created_at >= ?", since).Order("id").Find(&orders).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var payments []paymentModels.Payment
+	err = s.paymentDB.Where("created_at >= ?", since).Order("id").Find(&payments).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orderIds := make(map[uint]bool, len(orders))
+	for _, order := range orders {
+		orderIds[order.ID] = true
+	}
+
+	var missingOrderIds []uint
+	for _, payment := range payments {
+		if !orderIds[payment.OrderID] {
+			missingOrderIds = append(missingOrderIds, payment.OrderID)
+		}
+	}
+	if len(missingOrderIds) > 0 {
+		var older []Order
+		err = s.orderDB.Where("id IN ?", missingOrderIds).Find(&older).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		orders = append(orders, older...)
+	}
+
+	if len(orderIds) > 0 {
+		ids := make([]uint, 0, len(orderIds))
+		for id := range orderIds {
+			ids = append(ids, id)
+		}
+		var older []paymentModels.Payment
+		err = s.paymentDB.Where("order_id IN ? AND created_at < ?", ids, since).Find(&older).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		payments = append(payments, older...)
+	}
+
+	return orders, payments, nil
+}
@@ -54,6 +54,9 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Cannot connect rabbit")
 	}
+	outboxRelay := rabbitmq.NewOutboxRelay(context.Background(), db, rabbitConn, log, rabbitmq.E_COM_EXCHANGE, "direct")
+	go outboxRelay.Run()
+
 	userRepo := repository.NewUserRepository(db)
 	userPointRepo := repository.NewUserPointRepository(db)
 	userPointService := services.NewUserPointService(userPointRepo)
@@ -69,6 +72,17 @@ func main() {
 			log.Error().Err(err).Msg("Consume message error")
 		}
 	}()
+	reverseUserPointDependencies := rabbit_handler.ReverseUserPointDependencies{
+		Logger:      log,
+		UserService: userService,
+	}
+	orderCancelledConsumer := rabbitmq.NewConsumer[*rabbit_handler.ReverseUserPointDependencies](context.Background(), &rabbitConfig, rabbitConn, log, rabbit_handler.ReverseUserPoint, rabbitmq.E_COM_EXCHANGE, "direct", rabbitmq.ORDER_CANCELLED_QUEUE, rabbitmq.ORDER_CANCELLED_ROUTING_KEY)
+	go func() {
+		err := orderCancelledConsumer.ConsumeMessage(dto.OrderCancelled{}, &reverseUserPointDependencies)
+		if err != nil {
+			log.Error().Err(err).Msg("Consume message error")
+		}
+	}()
 	go runGrpcServer(cfg, db, log)
 	runGinServer(cfg, db, log)
 }
@@ -76,11 +90,15 @@ func main() {
 func runGinServer(cfg *config.Config, db *gorm.DB, log zerolog.Logger) {
 	// Initialize router
 	routes := gin.Default()
+	err := routes.SetTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot set trusted proxies")
+	}
 	api.SetupRoutes(routes, db, cfg, &log)
 
 	// Start server
 	address := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
-	err := routes.Run(address)
+	err = routes.Run(address)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Cannot run server")
 	}
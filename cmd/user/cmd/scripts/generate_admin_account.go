@@ -56,4 +56,5 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	userRepo.CreateUser(&user)
 	log.Info().Msg("Admin account created successfully")
+	log.Info().Msg("Enroll two factor authentication with POST /users/me/2fa/enroll, or require it for admins with PUT /users/roles/admin/2fa")
 }
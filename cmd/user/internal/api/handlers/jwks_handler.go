@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tricong1998/go-ecom/pkg/token"
+)
+
+type JWKSHandler struct {
+	JWKS *token.JWKS
+}
+
+func NewJWKSHandler(jwks *token.JWKS) *JWKSHandler {
+	return &JWKSHandler{jwks}
+}
+
+// ReadJWKS publishes the public keys the other services verify access tokens
+// with.
+func (jwksHandler *JWKSHandler) ReadJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jwksHandler.JWKS)
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/token"
+)
+
+// LoginTwoFactor finishes a two step login with a TOTP or recovery code.
+func (userHandler *UserHandler) LoginTwoFactor(ctx *gin.Context) {
+	var input dto.LoginTwoFactorDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := userHandler.LoginService.VerifyTwoFactor(input.ChallengeToken, input.Code, ctx.ClientIP())
+	if err != nil {
+		loginError(ctx, err)
+		return
+	}
+
+	userHandler.createToken(ctx, user)
+}
+
+// LoginEnrollTwoFactor enrolls the user of a login that requires two factor
+// authentication.
+func (userHandler *UserHandler) LoginEnrollTwoFactor(ctx *gin.Context) {
+	var input dto.ChallengeTokenDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, _, err := userHandler.TwoFactorService.ReadChallenge(input.ChallengeToken, services.TwoFactorEnrollmentRole)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	userHandler.enrollTwoFactor(ctx, user)
+}
+
+// LoginConfirmTwoFactor confirms the enrollment of a login that requires two
+// factor authentication, and finishes the login.
+func (userHandler *UserHandler) LoginConfirmTwoFactor(ctx *gin.Context) {
+	var input dto.LoginTwoFactorDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, payload, err := userHandler.TwoFactorService.ReadChallenge(input.ChallengeToken, services.TwoFactorEnrollmentRole)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+	recoveryCodes, err := userHandler.TwoFactorService.Confirm(user, input.Code)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+	err = userHandler.TwoFactorService.ConsumeChallenge(payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, refreshToken, err := userHandler.JwtService.CreateToken(user.Username, user.ID, user.Role)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token":   accessToken,
+		"refresh_token":  refreshToken,
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// EnrollTwoFactor starts the enrollment of the user of the request, who must
+// confirm it with a first code.
+func (userHandler *UserHandler) EnrollTwoFactor(ctx *gin.Context) {
+	user, ok := userHandler.readAuthorizedUser(ctx)
+	if !ok {
+		return
+	}
+
+	userHandler.enrollTwoFactor(ctx, user)
+}
+
+func (userHandler *UserHandler) ConfirmTwoFactor(ctx *gin.Context) {
+	var input dto.TwoFactorCodeDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	user, ok := userHandler.readAuthorizedUser(ctx)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := userHandler.TwoFactorService.Confirm(user, input.Code)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+func (userHandler *UserHandler) DisableTwoFactor(ctx *gin.Context) {
+	var input dto.TwoFactorCodeDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	user, ok := userHandler.readAuthorizedUser(ctx)
+	if !ok {
+		return
+	}
+
+	err := userHandler.TwoFactorService.Disable(user, input.Code)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user of the
+// request, for instance once most of them are used.
+func (userHandler *UserHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var input dto.TwoFactorCodeDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	user, ok := userHandler.readAuthorizedUser(ctx)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := userHandler.TwoFactorService.RegenerateRecoveryCodes(user, input.Code)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+// SetTwoFactorRequirement makes two factor authentication required, or not,
+// for the users of a role. Users of the role without it must enroll at their
+// next login.
+func (userHandler *UserHandler) SetTwoFactorRequirement(ctx *gin.Context) {
+	var roleRequest dto.TwoFactorRoleRequest
+	if err := ctx.ShouldBindUri(&roleRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var input dto.TwoFactorRequirementDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.TwoFactorService.SetRequired(roleRequest.Role, *input.Required)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"role": roleRequest.Role, "required": *input.Required})
+}
+
+func (userHandler *UserHandler) enrollTwoFactor(ctx *gin.Context, user *models.User) {
+	enrollment, err := userHandler.TwoFactorService.Enroll(user)
+	if err != nil {
+		ctx.JSON(twoFactorErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.TwoFactorEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	})
+}
+
+func (userHandler *UserHandler) readAuthorizedUser(ctx *gin.Context) (*models.User, bool) {
+	payload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
+	user, err := userHandler.UserService.ReadUser(payload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return nil, false
+	}
+	return user, true
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode),
+		errors.Is(err, services.ErrInvalidChallengeToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorRequired):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
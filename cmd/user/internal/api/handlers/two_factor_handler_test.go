@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/mocks"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/totp"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/token"
+	"gorm.io/gorm"
+)
+
+func TestLoginTwoFactor(t *testing.T) {
+	secret, _ := totp.GenerateSecret()
+	enabledAt := time.Now()
+	user := &models.User{Model: gorm.Model{ID: 1}, Username: "username", Role: "admin", TOTPSecret: secret, TOTPEnabledAt: &enabledAt}
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+
+	testCases := []struct {
+		name       string
+		code       string
+		revoked    bool
+		mockFunc   func(loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository)
+		expectFunc func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository)
+	}{
+		{
+			name: "OK",
+			code: code,
+			mockFunc: func(loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				twoFactorRepo.On("UseTOTPCounter", uint(1), mock.AnythingOfType("int64")).Return(nil).Once()
+				loginAttemptRepo.On("ResetLoginAttempts", "username:username").Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response map[string]string
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.NotEmpty(t, response["access_token"])
+				twoFactorRepo.AssertExpectations(t)
+				loginAttemptRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			code: "ABCDE-fghij",
+			mockFunc: func(loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				twoFactorRepo.On("ConsumeRecoveryCode", uint(1), mock.MatchedBy(func(codeHash string) bool {
+					return len(codeHash) == 64
+				})).Return(nil).Once()
+				loginAttemptRepo.On("ResetLoginAttempts", "username:username").Return(nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				twoFactorRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			code: code,
+			mockFunc: func(loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				twoFactorRepo.On("UseTOTPCounter", uint(1), mock.Anything).Return(gorm.ErrRecordNotFound)
+				loginAttemptRepo.On("RecordLoginFailure", mock.Anything, mock.Anything).Return(&models.LoginAttempt{Failures: 1}, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				loginAttemptRepo.AssertNumberOfCalls(t, "RecordLoginFailure", 2)
+				loginAttemptRepo.AssertNotCalled(t, "ResetLoginAttempts", mock.Anything)
+			},
+		},
+		{
+			name:    "RevokedChallenge",
+			code:    code,
+			revoked: true,
+			mockFunc: func(loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				twoFactorRepo.AssertNotCalled(t, "UseTOTPCounter", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			userRepo := new(mocks.MockUserRepository)
+			userRepo.On("ReadUser", uint(1)).Return(user, nil)
+			loginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+			loginAttemptRepo.On("ListLoginAttempts", mock.Anything).Return([]models.LoginAttempt{}, nil)
+			twoFactorRepo := new(mocks.MockTwoFactorRepository)
+			refreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+			refreshTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+			tokenMaker, _ := token.NewJWTMaker("12345678901234567890123456789012")
+			revocationStore := token.NewMemoryRevocationStore()
+			authConfig := config.AuthConfig{
+				AccessTokenDuration:    time.Hour,
+				RefreshTokenDuration:   time.Hour * 24 * 30,
+				ChallengeTokenDuration: time.Minute,
+			}
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, refreshTokenRepo, revocationStore, new(mocks.MockOutboxWriter), authConfig)
+			twoFactorService := services.NewTwoFactorService(userRepo, twoFactorRepo, tokenMaker, revocationStore, authConfig)
+			loginService := services.NewLoginService(userRepo, loginAttemptRepo, twoFactorService, config.LoginConfig{MaxFailures: 5, IPMaxFailures: 20})
+			userHandler := NewUserHandler(nil, jwtService, nil, loginService, twoFactorService)
+			challengeToken, _ := twoFactorService.CreateChallenge(user, services.TwoFactorChallengeRole)
+			if tc.revoked {
+				_, payload, _ := twoFactorService.ReadChallenge(challengeToken, services.TwoFactorChallengeRole)
+				_ = twoFactorService.ConsumeChallenge(payload)
+			}
+			tc.mockFunc(loginAttemptRepo, twoFactorRepo)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(dto.LoginTwoFactorDto{ChallengeToken: challengeToken, Code: tc.code})
+			c.Request, _ = http.NewRequest(http.MethodPost, "/users/login/2fa", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			// Act
+			userHandler.LoginTwoFactor(c)
+
+			// Assert
+			tc.expectFunc(w, loginAttemptRepo, twoFactorRepo)
+		})
+	}
+}
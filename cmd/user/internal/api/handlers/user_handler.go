@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/services"
@@ -12,15 +15,25 @@ import (
 	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
 	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
 	"github.com/tricong1998/go-ecom/pkg/token"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	UserService services.IUserService
-	JwtService  services.IJwtService
+	UserService      services.IUserService
+	JwtService       services.IJwtService
+	AccountService   services.IAccountService
+	LoginService     services.ILoginService
+	TwoFactorService services.ITwoFactorService
 }
 
-func NewUserHandler(userService services.IUserService, jwtService services.IJwtService) *UserHandler {
-	return &UserHandler{userService, jwtService}
+func NewUserHandler(
+	userService services.IUserService,
+	jwtService services.IJwtService,
+	accountService services.IAccountService,
+	loginService services.ILoginService,
+	twoFactorService services.ITwoFactorService,
+) *UserHandler {
+	return &UserHandler{userService, jwtService, accountService, loginService, twoFactorService}
 }
 
 func (userHandler *UserHandler) CreateUser(ctx *gin.Context) {
@@ -38,15 +51,21 @@ func (userHandler *UserHandler) CreateUser(ctx *gin.Context) {
 
 	user := models.User{
 		Username: input.Username,
+		Email:    normalizeEmail(input.Email),
 		FullName: input.FullName,
 		Password: hashedPassword,
 		Role:     "user",
 	}
 	if err := userHandler.UserService.CreateUser(&user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.JSON(userErrorStatus(err), errorResponse(err))
 		return
 	}
 
+	// The account exists either way; the user can ask for another email.
+	if err := userHandler.AccountService.SendVerificationEmail(&user); err != nil {
+		_ = ctx.Error(err)
+	}
+
 	ctx.JSON(http.StatusCreated, dto.ToUserResponse(&user))
 }
 
@@ -98,25 +117,32 @@ func (userHandler *UserHandler) UpdateMe(ctx *gin.Context) {
 		return
 	}
 
-	var input dto.CreateUserDto
+	var input dto.UpdateMeDto
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	var readUserRequest dto.ReadUserRequest
-	if err := ctx.ShouldBindUri(&readUserRequest); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
-
 	user.Username = input.Username
 	user.FullName = input.FullName
+	// A new email has to be verified again.
+	email := normalizeEmail(input.Email)
+	emailChanged := email != "" && email != user.Email
+	if emailChanged {
+		user.Email = email
+		user.EmailVerifiedAt = nil
+	}
 	if err := userHandler.UserService.UpdateUser(user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.JSON(userErrorStatus(err), errorResponse(err))
 		return
 	}
 
+	if emailChanged {
+		if err := userHandler.AccountService.SendVerificationEmail(user); err != nil {
+			_ = ctx.Error(err)
+		}
+	}
+
 	ctx.JSON(http.StatusCreated, dto.ToUserResponse(user))
 }
 
@@ -171,26 +197,47 @@ func (userHandler *UserHandler) Login(ctx *gin.Context) {
 		return
 	}
 
-	user, err := userHandler.UserService.GetUserByUsername(input.Username)
+	result, err := userHandler.LoginService.Login(input.Username, input.Password, ctx.ClientIP())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		loginError(ctx, err)
 		return
 	}
-	err = util.ComparePassword(user.Password, input.Password)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if result.ChallengeToken != "" {
+		ctx.JSON(http.StatusOK, dto.LoginChallengeResponse{
+			ChallengeToken:              result.ChallengeToken,
+			TwoFactorRequired:           result.ChallengeRole == services.TwoFactorChallengeRole,
+			TwoFactorEnrollmentRequired: result.ChallengeRole == services.TwoFactorEnrollmentRole,
+		})
 		return
 	}
 
-	accessToken, _, err := userHandler.JwtService.CreateToken(user.Username, user.ID, user.Role)
+	userHandler.createToken(ctx, result.User)
+}
+
+// createToken answers a finished login with an access and refresh token.
+func (userHandler *UserHandler) createToken(ctx *gin.Context, user *models.User) {
+	accessToken, refreshToken, err := userHandler.JwtService.CreateToken(user.Username, user.ID, user.Role)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	refreshToken, _, err := userHandler.JwtService.CreateToken(user.Username, user.ID, user.Role)
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+func (userHandler *UserHandler) RefreshToken(ctx *gin.Context) {
+	var input dto.RefreshTokenDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	accessToken, refreshToken, err := userHandler.JwtService.RefreshToken(input.RefreshToken)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.JSON(refreshTokenErrorStatus(err), errorResponse(err))
 		return
 	}
 
@@ -199,3 +246,180 @@ func (userHandler *UserHandler) Login(ctx *gin.Context) {
 		"refresh_token": refreshToken,
 	})
 }
+
+// Logout revokes the access token of the request and, when it is given, the
+// refresh token of the same login.
+func (userHandler *UserHandler) Logout(ctx *gin.Context) {
+	var input dto.LogoutDto
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	payload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
+	err := userHandler.JwtService.Logout(payload, input.RefreshToken)
+	if err != nil {
+		ctx.JSON(refreshTokenErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// RevokeUserSessions signs the user out of every session.
+func (userHandler *UserHandler) RevokeUserSessions(ctx *gin.Context) {
+	var readUserRequest dto.ReadUserRequest
+	if err := ctx.ShouldBindUri(&readUserRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.JwtService.RevokeUserSessions(readUserRequest.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// UnlockUser lets a user locked out after failed logins log in again.
+func (userHandler *UserHandler) UnlockUser(ctx *gin.Context) {
+	var readUserRequest dto.ReadUserRequest
+	if err := ctx.ShouldBindUri(&readUserRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.LoginService.Unlock(readUserRequest.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func (userHandler *UserHandler) VerifyEmail(ctx *gin.Context) {
+	var input dto.VerifyEmailDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.AccountService.VerifyEmail(input.Token)
+	if err != nil {
+		ctx.JSON(accountErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// ResendVerificationEmail mails the user of the request a new verification
+// link.
+func (userHandler *UserHandler) ResendVerificationEmail(ctx *gin.Context) {
+	payload := ctx.MustGet(middleware.AuthorizationPayloadKey).(*token.Payload)
+	user, err := userHandler.UserService.ReadUser(payload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	err = userHandler.AccountService.SendVerificationEmail(user)
+	if err != nil {
+		ctx.JSON(accountErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// ForgotPassword answers the same whether or not the email has an account.
+func (userHandler *UserHandler) ForgotPassword(ctx *gin.Context) {
+	var input dto.ForgotPasswordDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.AccountService.ForgotPassword(normalizeEmail(input.Email))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{})
+}
+
+func (userHandler *UserHandler) ResetPassword(ctx *gin.Context) {
+	var input dto.ResetPasswordDto
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := userHandler.AccountService.ResetPassword(input.Token, input.Password)
+	if err != nil {
+		ctx.JSON(accountErrorStatus(err), errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func loginError(ctx *gin.Context, err error) {
+	var lockedErr *services.LoginLockedError
+	switch {
+	case errors.As(err, &lockedErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(lockedErr.RetryAfter.Round(time.Second).Seconds())))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+	case errors.Is(err, services.ErrInvalidCredentials),
+		errors.Is(err, services.ErrInvalidTwoFactorCode),
+		errors.Is(err, services.ErrInvalidChallengeToken):
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
+
+func userErrorStatus(err error) int {
+	if errors.Is(err, services.ErrUserExists) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAccountToken):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrEmailAlreadyVerified),
+		errors.Is(err, services.ErrNoEmail):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func refreshTokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, token.ErrInvalidToken),
+		errors.Is(err, token.ErrExpiredToken),
+		errors.Is(err, services.ErrRefreshTokenRevoked),
+		errors.Is(err, services.ErrRefreshTokenReused):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -14,11 +14,16 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/mail"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/mocks"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/util"
 	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
 	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
 	"github.com/tricong1998/go-ecom/pkg/token"
+	"gorm.io/gorm"
 )
 
 func expectBodyUser(t *testing.T, w *httptest.ResponseRecorder, mockResponse *models.User) {
@@ -44,6 +49,7 @@ func TestCreateUser(t *testing.T) {
 			setupInputFunc: func(input *dto.CreateUserDto, mockResponse *models.User) {
 				input.FullName = "Full name"
 				input.Username = "username"
+				input.Email = "user@example.com"
 				input.Password = "password"
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
@@ -81,11 +87,27 @@ func TestCreateUser(t *testing.T) {
 				assert.Equal(t, http.StatusBadRequest, w.Code)
 			},
 		},
+		{
+			name: "DuplicateUser",
+			setupInputFunc: func(input *dto.CreateUserDto, mockResponse *models.User) {
+				input.FullName = "Full name"
+				input.Username = "username"
+				input.Email = "user@example.com"
+				input.Password = "password"
+			},
+			mockFunc: func(userRepo *mocks.MockUserRepository, mockResponse *models.User) {
+				userRepo.On("CreateUser", mock.AnythingOfType("*models.User")).Return(gorm.ErrDuplicatedKey)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.User) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+			},
+		},
 		{
 			name: "CreateUserError",
 			setupInputFunc: func(input *dto.CreateUserDto, mockResponse *models.User) {
 				input.FullName = "Full name"
 				input.Username = "username"
+				input.Email = "user@example.com"
 				input.Password = "password"
 				mockResponse.ID = 1
 				mockResponse.CreatedAt = time.Now()
@@ -113,13 +135,20 @@ func TestCreateUser(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to create token maker: %v", err)
 			}
-			jwtService := services.NewJwtService(tokenMaker, config.AuthConfig{
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, new(mocks.MockRefreshTokenRepository), token.NewMemoryRevocationStore(), new(mocks.MockOutboxWriter), config.AuthConfig{
 				AccessTokenSecret:    "test",
 				AccessTokenDuration:  time.Hour,
 				RefreshTokenSecret:   "test",
 				RefreshTokenDuration: time.Hour * 24 * 30,
 			})
-			userHandler := NewUserHandler(userService, jwtService)
+			userTokenRepo := new(mocks.MockUserTokenRepository)
+			userTokenRepo.On("CreateUserToken", mock.AnythingOfType("*models.UserToken")).Return(nil)
+			mailSender := new(mocks.MockMailSender)
+			mailSender.On("Send", mock.MatchedBy(func(message mail.Message) bool {
+				return message.To == "user@example.com"
+			})).Return(nil)
+			accountService := services.NewAccountService(userRepo, userTokenRepo, jwtService, mailSender, config.AccountConfig{})
+			userHandler := NewUserHandler(userService, jwtService, accountService, nil, nil)
 			var user dto.CreateUserDto
 			var mockResponse models.User
 			tc.setupInputFunc(&user, &mockResponse)
@@ -200,13 +229,14 @@ func TestReadUser(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to create token maker: %v", err)
 			}
-			jwtService := services.NewJwtService(tokenMaker, config.AuthConfig{
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, new(mocks.MockRefreshTokenRepository), token.NewMemoryRevocationStore(), new(mocks.MockOutboxWriter), config.AuthConfig{
 				AccessTokenSecret:    "test",
 				AccessTokenDuration:  time.Hour,
 				RefreshTokenSecret:   "test",
 				RefreshTokenDuration: time.Hour * 24 * 30,
 			})
-			userHandler := NewUserHandler(userService, jwtService)
+			accountService := services.NewAccountService(userRepo, new(mocks.MockUserTokenRepository), jwtService, new(mocks.MockMailSender), config.AccountConfig{})
+			userHandler := NewUserHandler(userService, jwtService, accountService, nil, nil)
 			var input dto.ReadUserRequest
 			var mockResponse models.User
 			tc.setupInputFunc(&input, &mockResponse)
@@ -324,13 +354,14 @@ func TestListUser(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to create token maker: %v", err)
 			}
-			jwtService := services.NewJwtService(tokenMaker, config.AuthConfig{
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, new(mocks.MockRefreshTokenRepository), token.NewMemoryRevocationStore(), new(mocks.MockOutboxWriter), config.AuthConfig{
 				AccessTokenSecret:    "test",
 				AccessTokenDuration:  time.Hour,
 				RefreshTokenSecret:   "test",
 				RefreshTokenDuration: time.Hour * 24 * 30,
 			})
-			userHandler := NewUserHandler(userService, jwtService)
+			accountService := services.NewAccountService(userRepo, new(mocks.MockUserTokenRepository), jwtService, new(mocks.MockMailSender), config.AccountConfig{})
+			userHandler := NewUserHandler(userService, jwtService, accountService, nil, nil)
 			var input dto.ListUserQuery
 			var total int64
 			mockResponse := tc.setupInputFunc(&input, &total)
@@ -353,65 +384,93 @@ func TestListUser(t *testing.T) {
 func TestUpdateUser(t *testing.T) {
 	testCases := []struct {
 		name           string
-		setupInputFunc func(input *dto.CreateUserDto, mockResponse *models.User)
-		mockFunc       func(userRepo *mocks.MockUserRepository, mockResponse *models.User)
-		expectFunc     func(w *httptest.ResponseRecorder, mockResponse *models.User)
+		setupInputFunc func(input *dto.UpdateMeDto, mockResponse *models.User)
+		mockFunc       func(userRepo *mocks.MockUserRepository, mailSender *mocks.MockMailSender, mockResponse *models.User)
+		expectFunc     func(w *httptest.ResponseRecorder, mailSender *mocks.MockMailSender, mockResponse *models.User)
 	}{
 		{
 			name: "OK",
-			setupInputFunc: func(input *dto.CreateUserDto, mockResponse *models.User) {
+			setupInputFunc: func(input *dto.UpdateMeDto, mockResponse *models.User) {
 				input.FullName = "New full name"
 				input.Username = "newUsername"
-				mockResponse.ID = 1
-				mockResponse.CreatedAt = time.Now()
-				mockResponse.UpdatedAt = mockResponse.CreatedAt
 				mockResponse.FullName = input.FullName
 				mockResponse.Username = input.Username
-
 			},
-			mockFunc: func(userRepo *mocks.MockUserRepository, mockResponse *models.User) {
-				userRepo.On("UpdateUser", mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
-					arg := args.Get(0).(*models.User)
-					arg.ID = mockResponse.ID
-					arg.CreatedAt = mockResponse.CreatedAt
-					arg.UpdatedAt = mockResponse.UpdatedAt
-				})
+			mockFunc: func(userRepo *mocks.MockUserRepository, mailSender *mocks.MockMailSender, mockResponse *models.User) {
+				userRepo.On("UpdateUser", mock.AnythingOfType("*models.User")).Return(nil)
 			},
-			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.User) {
+			expectFunc: func(w *httptest.ResponseRecorder, mailSender *mocks.MockMailSender, mockResponse *models.User) {
 				assert.Equal(t, http.StatusCreated, w.Code)
 				expectBodyUser(t, w, mockResponse)
+				var response dto.UserResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "old@example.com", response.Email)
+				assert.True(t, response.EmailVerified)
+				mailSender.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "ChangeEmail",
+			setupInputFunc: func(input *dto.UpdateMeDto, mockResponse *models.User) {
+				input.FullName = "Full name"
+				input.Username = "username"
+				input.Email = "New@Example.com"
+				mockResponse.FullName = input.FullName
+				mockResponse.Username = input.Username
+			},
+			mockFunc: func(userRepo *mocks.MockUserRepository, mailSender *mocks.MockMailSender, mockResponse *models.User) {
+				userRepo.On("UpdateUser", mock.MatchedBy(func(user *models.User) bool {
+					return user.Email == "new@example.com" && user.EmailVerifiedAt == nil
+				})).Return(nil).Once()
+				mailSender.On("Send", mock.MatchedBy(func(message mail.Message) bool {
+					return message.To == "new@example.com"
+				})).Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mailSender *mocks.MockMailSender, mockResponse *models.User) {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var response dto.UserResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "new@example.com", response.Email)
+				assert.False(t, response.EmailVerified)
+				mailSender.AssertExpectations(t)
 			},
 		},
 		{
 			name: "BadInput",
-			setupInputFunc: func(input *dto.CreateUserDto, mockResponse *models.User) {
+			setupInputFunc: func(input *dto.UpdateMeDto, mockResponse *models.User) {
 			},
-			mockFunc: func(userRepo *mocks.MockUserRepository, mockResponse *models.User) {
-				userRepo.On("UpdateUser", mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
-					arg := args.Get(0).(*models.User)
-					arg.ID = mockResponse.ID
-					arg.CreatedAt = mockResponse.CreatedAt
-					arg.UpdatedAt = mockResponse.UpdatedAt
-				})
+			mockFunc: func(userRepo *mocks.MockUserRepository, mailSender *mocks.MockMailSender, mockResponse *models.User) {
 			},
-			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.User) {
+			expectFunc: func(w *httptest.ResponseRecorder, mailSender *mocks.MockMailSender, mockResponse *models.User) {
 				assert.Equal(t, http.StatusBadRequest, w.Code)
 			},
 		},
+		{
+			name: "DuplicateEmail",
+			setupInputFunc: func(input *dto.UpdateMeDto, mockResponse *models.User) {
+				input.FullName = "Full name"
+				input.Username = "username"
+				input.Email = "taken@example.com"
+			},
+			mockFunc: func(userRepo *mocks.MockUserRepository, mailSender *mocks.MockMailSender, mockResponse *models.User) {
+				userRepo.On("UpdateUser", mock.AnythingOfType("*models.User")).Return(gorm.ErrDuplicatedKey)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, mailSender *mocks.MockMailSender, mockResponse *models.User) {
+				assert.Equal(t, http.StatusConflict, w.Code)
+				mailSender.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
 		{
 			name: "UpdateUserError",
-			setupInputFunc: func(input *dto.CreateUserDto, mockResponse *models.User) {
+			setupInputFunc: func(input *dto.UpdateMeDto, mockResponse *models.User) {
 				input.FullName = "Full name"
 				input.Username = "username"
-				mockResponse.ID = 1
-				mockResponse.CreatedAt = time.Now()
-				mockResponse.UpdatedAt = mockResponse.CreatedAt
 			},
-			mockFunc: func(userRepo *mocks.MockUserRepository, mockResponse *models.User) {
+			mockFunc: func(userRepo *mocks.MockUserRepository, mailSender *mocks.MockMailSender, mockResponse *models.User) {
 				err := errors.New("Error")
 				userRepo.On("UpdateUser", mock.AnythingOfType("*models.User")).Return(err)
 			},
-			expectFunc: func(w *httptest.ResponseRecorder, mockResponse *models.User) {
+			expectFunc: func(w *httptest.ResponseRecorder, mailSender *mocks.MockMailSender, mockResponse *models.User) {
 				assert.Equal(t, http.StatusInternalServerError, w.Code)
 			},
 		},
@@ -425,33 +484,462 @@ func TestUpdateUser(t *testing.T) {
 			userPointRepo := new(mocks.MockUserPointRepository)
 			userPointService := services.NewUserPointService(userPointRepo)
 			userService := services.NewUserService(userRepo, userPointService)
-			tokenMaker, err := token.NewJWTMaker("test")
+			tokenMaker, err := token.NewJWTMaker("12345678901234567890123456789012")
 			if err != nil {
 				t.Fatalf("Failed to create token maker: %v", err)
 			}
-			jwtService := services.NewJwtService(tokenMaker, config.AuthConfig{
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, new(mocks.MockRefreshTokenRepository), token.NewMemoryRevocationStore(), new(mocks.MockOutboxWriter), config.AuthConfig{
 				AccessTokenSecret:    "test",
 				AccessTokenDuration:  time.Hour,
 				RefreshTokenSecret:   "test",
 				RefreshTokenDuration: time.Hour * 24 * 30,
 			})
-			userHandler := NewUserHandler(userService, jwtService)
-			var user dto.CreateUserDto
-			var mockResponse models.User
+			userTokenRepo := new(mocks.MockUserTokenRepository)
+			userTokenRepo.On("CreateUserToken", mock.AnythingOfType("*models.UserToken")).Return(nil)
+			mailSender := new(mocks.MockMailSender)
+			accountService := services.NewAccountService(userRepo, userTokenRepo, jwtService, mailSender, config.AccountConfig{})
+			userHandler := NewUserHandler(userService, jwtService, accountService, nil, nil)
+			verifiedAt := time.Now()
+			storedUser := &models.User{
+				Model:           gorm.Model{ID: 1, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+				Username:        "username",
+				Email:           "old@example.com",
+				EmailVerifiedAt: &verifiedAt,
+				FullName:        "Full name",
+			}
+			userRepo.On("ReadUser", uint(1)).Return(storedUser, nil)
+			var user dto.UpdateMeDto
+			mockResponse := *storedUser
 			tc.setupInputFunc(&user, &mockResponse)
-			tc.mockFunc(userRepo, &mockResponse)
+			tc.mockFunc(userRepo, mailSender, &mockResponse)
 			gin.SetMode(gin.TestMode)
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
 
 			jsonUser, _ := json.Marshal(user)
-			c.Request, _ = http.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(jsonUser))
+			c.Request, _ = http.NewRequest(http.MethodPut, "/users/update-me", bytes.NewBuffer(jsonUser))
 			c.Request.Header.Set("Content-Type", "application/json")
-			c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(mockResponse.ID)}}
+			c.Set(middleware.AuthorizationPayloadKey, &token.Payload{UserId: 1, Username: "username", Role: "user"})
 
 			userHandler.UpdateMe(c)
 
-			tc.expectFunc(w, &mockResponse)
+			tc.expectFunc(w, mailSender, &mockResponse)
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	accessTokenMaker, _ := token.NewJWTMaker("12345678901234567890123456789012")
+	refreshTokenMaker, _ := token.NewRefreshTokenMaker("abcdefghijklmnopqrstuvwxyz123456")
+
+	testCases := []struct {
+		name       string
+		tokenFunc  func() (string, *token.Payload)
+		mockFunc   func(refreshTokenRepo *mocks.MockRefreshTokenRepository, payload *token.Payload)
+		expectFunc func(w *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository)
+	}{
+		{
+			name: "OK",
+			tokenFunc: func() (string, *token.Payload) {
+				refreshToken, payload, _ := refreshTokenMaker.CreateToken("username", 1, time.Hour, "user")
+				return refreshToken, payload
+			},
+			mockFunc: func(refreshTokenRepo *mocks.MockRefreshTokenRepository, payload *token.Payload) {
+				refreshTokenRepo.On("ReadRefreshToken", payload.ID.String()).
+					Return(&models.RefreshToken{ID: payload.ID.String(), FamilyID: "family", UserId: 1}, nil)
+				refreshTokenRepo.On("RotateRefreshToken", payload.ID.String(), mock.MatchedBy(func(next *models.RefreshToken) bool {
+					return next.FamilyID == "family" && next.ID != payload.ID.String() && next.UserId == 1
+				})).Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response map[string]string
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				_, err = accessTokenMaker.VerifyToken(response["access_token"])
+				assert.NoError(t, err)
+				_, err = refreshTokenMaker.VerifyToken(response["refresh_token"])
+				assert.NoError(t, err)
+				refreshTokenRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "ReusedTokenRevokesFamily",
+			tokenFunc: func() (string, *token.Payload) {
+				refreshToken, payload, _ := refreshTokenMaker.CreateToken("username", 1, time.Hour, "user")
+				return refreshToken, payload
+			},
+			mockFunc: func(refreshTokenRepo *mocks.MockRefreshTokenRepository, payload *token.Payload) {
+				rotatedAt := time.Now()
+				refreshTokenRepo.On("ReadRefreshToken", payload.ID.String()).
+					Return(&models.RefreshToken{ID: payload.ID.String(), FamilyID: "family", UserId: 1, RotatedAt: &rotatedAt}, nil)
+				refreshTokenRepo.On("RevokeRefreshTokenFamily", "family").Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				refreshTokenRepo.AssertExpectations(t)
+				refreshTokenRepo.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "AccessToken",
+			tokenFunc: func() (string, *token.Payload) {
+				accessToken, payload, _ := accessTokenMaker.CreateToken("username", 1, time.Hour, "user")
+				return accessToken, payload
+			},
+			mockFunc: func(refreshTokenRepo *mocks.MockRefreshTokenRepository, payload *token.Payload) {},
+			expectFunc: func(w *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				refreshTokenRepo.AssertNotCalled(t, "ReadRefreshToken", mock.Anything)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			refreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+			jwtService := services.NewJwtService(accessTokenMaker, refreshTokenMaker, refreshTokenRepo, token.NewMemoryRevocationStore(), new(mocks.MockOutboxWriter), config.AuthConfig{
+				AccessTokenDuration:  time.Hour,
+				RefreshTokenDuration: time.Hour * 24 * 30,
+			})
+			userHandler := NewUserHandler(nil, jwtService, nil, nil, nil)
+			refreshToken, payload := tc.tokenFunc()
+			tc.mockFunc(refreshTokenRepo, payload)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(dto.RefreshTokenDto{RefreshToken: refreshToken})
+			c.Request, _ = http.NewRequest(http.MethodPost, "/users/refresh", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			// Act
+			userHandler.RefreshToken(c)
+
+			// Assert
+			tc.expectFunc(w, refreshTokenRepo)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	accessTokenMaker, _ := token.NewJWTMaker("12345678901234567890123456789012")
+	refreshTokenMaker, _ := token.NewRefreshTokenMaker("abcdefghijklmnopqrstuvwxyz123456")
+
+	testCases := []struct {
+		name       string
+		mockFunc   func(refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter, body *dto.LogoutDto)
+		expectFunc func(t *testing.T, first, second *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter)
+	}{
+		{
+			name: "OK",
+			mockFunc: func(refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter, body *dto.LogoutDto) {
+				outbox.On("Write", rabbitmq.TOKENS_REVOKED_ROUTING_KEY, mock.AnythingOfType("events.TokensRevoked")).Return(nil).Once()
+			},
+			expectFunc: func(t *testing.T, first, second *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter) {
+				assert.Equal(t, http.StatusOK, first.Code)
+				assert.Equal(t, http.StatusUnauthorized, second.Code)
+				outbox.AssertExpectations(t)
+			},
+		},
+		{
+			name: "RevokesRefreshTokenFamily",
+			mockFunc: func(refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter, body *dto.LogoutDto) {
+				refreshToken, payload, _ := refreshTokenMaker.CreateToken("username", 1, time.Hour, "user")
+				body.RefreshToken = refreshToken
+				refreshTokenRepo.On("ReadRefreshToken", payload.ID.String()).
+					Return(&models.RefreshToken{ID: payload.ID.String(), FamilyID: "family", UserId: 1}, nil)
+				refreshTokenRepo.On("RevokeRefreshTokenFamily", "family").Return(nil).Once()
+				outbox.On("Write", rabbitmq.TOKENS_REVOKED_ROUTING_KEY, mock.AnythingOfType("events.TokensRevoked")).Return(nil).Once()
+			},
+			expectFunc: func(t *testing.T, first, second *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter) {
+				assert.Equal(t, http.StatusOK, first.Code)
+				assert.Equal(t, http.StatusUnauthorized, second.Code)
+				refreshTokenRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "RefreshTokenOfAnotherUser",
+			mockFunc: func(refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter, body *dto.LogoutDto) {
+				refreshToken, _, _ := refreshTokenMaker.CreateToken("another", 2, time.Hour, "user")
+				body.RefreshToken = refreshToken
+			},
+			expectFunc: func(t *testing.T, first, second *httptest.ResponseRecorder, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter) {
+				assert.Equal(t, http.StatusUnauthorized, first.Code)
+				outbox.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			refreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+			outbox := new(mocks.MockOutboxWriter)
+			revocationStore := token.NewMemoryRevocationStore()
+			jwtService := services.NewJwtService(accessTokenMaker, refreshTokenMaker, refreshTokenRepo, revocationStore, outbox, config.AuthConfig{
+				AccessTokenDuration:  time.Hour,
+				RefreshTokenDuration: time.Hour * 24 * 30,
+			})
+			userHandler := NewUserHandler(nil, jwtService, nil, nil, nil)
+			var body dto.LogoutDto
+			tc.mockFunc(refreshTokenRepo, outbox, &body)
+			accessToken, _, _ := accessTokenMaker.CreateToken("username", 1, time.Hour, "user")
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/users/logout", middleware.AuthMiddleware(accessTokenMaker, revocationStore, []string{}), userHandler.Logout)
+			logout := func() *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				jsonBody, _ := json.Marshal(body)
+				req, _ := http.NewRequest(http.MethodPost, "/users/logout", bytes.NewBuffer(jsonBody))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(middleware.AuthorizationHeaderKey, "Bearer "+accessToken)
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			// Act
+			first := logout()
+			second := logout()
+
+			// Assert
+			tc.expectFunc(t, first, second, refreshTokenRepo, outbox)
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	testCases := []struct {
+		name       string
+		input      dto.ResetPasswordDto
+		mockFunc   func(userRepo *mocks.MockUserRepository, userTokenRepo *mocks.MockUserTokenRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter)
+		expectFunc func(w *httptest.ResponseRecorder, userRepo *mocks.MockUserRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository)
+	}{
+		{
+			name:  "OK",
+			input: dto.ResetPasswordDto{Token: "token", Password: "new password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, userTokenRepo *mocks.MockUserTokenRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter) {
+				userTokenRepo.On("ConsumeUserToken", models.UserTokenPurposeResetPassword, mock.MatchedBy(func(tokenHash string) bool {
+					return len(tokenHash) == 64 && tokenHash != "token"
+				})).Return(&models.UserToken{UserId: 1}, nil)
+				userRepo.On("ReadUser", uint(1)).Return(&models.User{Model: gorm.Model{ID: 1}, Password: "old"}, nil)
+				userRepo.On("UpdateUser", mock.MatchedBy(func(user *models.User) bool {
+					return util.ComparePassword(user.Password, "new password") == nil && user.EmailVerifiedAt != nil
+				})).Return(nil).Once()
+				refreshTokenRepo.On("RevokeUserRefreshTokens", uint(1)).Return(nil).Once()
+				outbox.On("Write", rabbitmq.TOKENS_REVOKED_ROUTING_KEY, mock.Anything).Return(nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockUserRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				userRepo.AssertExpectations(t)
+				refreshTokenRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name:  "InvalidToken",
+			input: dto.ResetPasswordDto{Token: "used", Password: "new password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, userTokenRepo *mocks.MockUserTokenRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter) {
+				userTokenRepo.On("ConsumeUserToken", models.UserTokenPurposeResetPassword, mock.Anything).
+					Return((*models.UserToken)(nil), gorm.ErrRecordNotFound)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockUserRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+			},
+		},
+		{
+			name:  "BadInput",
+			input: dto.ResetPasswordDto{Token: "token"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, userTokenRepo *mocks.MockUserTokenRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository, outbox *mocks.MockOutboxWriter) {
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, userRepo *mocks.MockUserRepository, refreshTokenRepo *mocks.MockRefreshTokenRepository) {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			userRepo := new(mocks.MockUserRepository)
+			userTokenRepo := new(mocks.MockUserTokenRepository)
+			refreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+			outbox := new(mocks.MockOutboxWriter)
+			tokenMaker, _ := token.NewJWTMaker("12345678901234567890123456789012")
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, refreshTokenRepo, token.NewMemoryRevocationStore(), outbox, config.AuthConfig{})
+			accountService := services.NewAccountService(userRepo, userTokenRepo, jwtService, new(mocks.MockMailSender), config.AccountConfig{})
+			userHandler := NewUserHandler(nil, jwtService, accountService, nil, nil)
+			tc.mockFunc(userRepo, userTokenRepo, refreshTokenRepo, outbox)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(tc.input)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/users/reset-password", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			// Act
+			userHandler.ResetPassword(c)
+
+			// Assert
+			tc.expectFunc(w, userRepo, refreshTokenRepo)
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	hashedPassword, _ := util.HashPassword("password")
+	loginConfig := config.LoginConfig{
+		MaxFailures:        3,
+		IPMaxFailures:      10,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: time.Hour,
+		FailureWindow:      time.Hour,
+	}
+	user := &models.User{Model: gorm.Model{ID: 1}, Username: "username", Password: hashedPassword, Role: "user"}
+
+	testCases := []struct {
+		name       string
+		input      dto.LoginUserDto
+		mockFunc   func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository)
+		expectFunc func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository)
+	}{
+		{
+			name:  "OK",
+			input: dto.LoginUserDto{Username: "username", Password: "password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				loginAttemptRepo.On("ListLoginAttempts", mock.Anything).Return([]models.LoginAttempt{}, nil)
+				userRepo.On("GetUserByUsername", "username").Return(user, nil)
+				twoFactorRepo.On("IsTwoFactorRequired", "user").Return(false, nil)
+				loginAttemptRepo.On("ResetLoginAttempts", "username:username").Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				loginAttemptRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name:  "UnknownUser",
+			input: dto.LoginUserDto{Username: "unknown", Password: "password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				loginAttemptRepo.On("ListLoginAttempts", mock.Anything).Return([]models.LoginAttempt{}, nil)
+				userRepo.On("GetUserByUsername", "unknown").Return((*models.User)(nil), gorm.ErrRecordNotFound)
+				loginAttemptRepo.On("RecordLoginFailure", mock.Anything, time.Hour).Return(&models.LoginAttempt{Failures: 1}, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				assert.JSONEq(t, `{"error":"invalid username or password"}`, w.Body.String())
+			},
+		},
+		{
+			name:  "WrongPasswordLocksUser",
+			input: dto.LoginUserDto{Username: "username", Password: "wrong"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				loginAttemptRepo.On("ListLoginAttempts", mock.Anything).Return([]models.LoginAttempt{}, nil)
+				userRepo.On("GetUserByUsername", "username").Return(user, nil)
+				loginAttemptRepo.On("RecordLoginFailure", "username:username", time.Hour).Return(&models.LoginAttempt{Failures: 4}, nil)
+				loginAttemptRepo.On("RecordLoginFailure", mock.Anything, time.Hour).Return(&models.LoginAttempt{Failures: 4}, nil)
+				loginAttemptRepo.On("LockLoginAttempts", "username:username", mock.MatchedBy(func(until time.Time) bool {
+					return time.Until(until) > time.Minute && time.Until(until) <= 2*time.Minute
+				})).Return(nil).Once()
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository) {
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				assert.JSONEq(t, `{"error":"invalid username or password"}`, w.Body.String())
+				loginAttemptRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name:  "TwoFactorChallenge",
+			input: dto.LoginUserDto{Username: "username", Password: "password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				enabledAt := time.Now()
+				twoFactorUser := *user
+				twoFactorUser.TOTPEnabledAt = &enabledAt
+				loginAttemptRepo.On("ListLoginAttempts", mock.Anything).Return([]models.LoginAttempt{}, nil)
+				userRepo.On("GetUserByUsername", "username").Return(&twoFactorUser, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, true, response["two_factor_required"])
+				assert.NotEmpty(t, response["challenge_token"])
+				assert.NotContains(t, response, "access_token")
+				loginAttemptRepo.AssertNotCalled(t, "ResetLoginAttempts", mock.Anything)
+			},
+		},
+		{
+			name:  "TwoFactorEnrollmentRequired",
+			input: dto.LoginUserDto{Username: "username", Password: "password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				loginAttemptRepo.On("ListLoginAttempts", mock.Anything).Return([]models.LoginAttempt{}, nil)
+				userRepo.On("GetUserByUsername", "username").Return(user, nil)
+				twoFactorRepo.On("IsTwoFactorRequired", "user").Return(true, nil)
+				loginAttemptRepo.On("ResetLoginAttempts", "username:username").Return(nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository) {
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, true, response["two_factor_enrollment_required"])
+				assert.NotContains(t, response, "access_token")
+			},
+		},
+		{
+			name:  "Locked",
+			input: dto.LoginUserDto{Username: "username", Password: "password"},
+			mockFunc: func(userRepo *mocks.MockUserRepository, loginAttemptRepo *mocks.MockLoginAttemptRepository, twoFactorRepo *mocks.MockTwoFactorRepository) {
+				lockedUntil := time.Now().Add(time.Minute)
+				loginAttemptRepo.On("ListLoginAttempts", mock.Anything).
+					Return([]models.LoginAttempt{{Key: "username:username", Failures: 3, LockedUntil: &lockedUntil}}, nil)
+			},
+			expectFunc: func(w *httptest.ResponseRecorder, loginAttemptRepo *mocks.MockLoginAttemptRepository) {
+				assert.Equal(t, http.StatusTooManyRequests, w.Code)
+				assert.Equal(t, "60", w.Header().Get("Retry-After"))
+				loginAttemptRepo.AssertNotCalled(t, "RecordLoginFailure", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			userRepo := new(mocks.MockUserRepository)
+			loginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+			refreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+			refreshTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+			tokenMaker, _ := token.NewJWTMaker("12345678901234567890123456789012")
+			jwtService := services.NewJwtService(tokenMaker, tokenMaker, refreshTokenRepo, token.NewMemoryRevocationStore(), new(mocks.MockOutboxWriter), config.AuthConfig{
+				AccessTokenDuration:  time.Hour,
+				RefreshTokenDuration: time.Hour * 24 * 30,
+			})
+			twoFactorRepo := new(mocks.MockTwoFactorRepository)
+			twoFactorService := services.NewTwoFactorService(userRepo, twoFactorRepo, tokenMaker, token.NewMemoryRevocationStore(), config.AuthConfig{ChallengeTokenDuration: time.Minute})
+			loginService := services.NewLoginService(userRepo, loginAttemptRepo, twoFactorService, loginConfig)
+			userHandler := NewUserHandler(nil, jwtService, nil, loginService, twoFactorService)
+			tc.mockFunc(userRepo, loginAttemptRepo, twoFactorRepo)
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body, _ := json.Marshal(tc.input)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/users/login", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			// Act
+			userHandler.Login(c)
+
+			// Assert
+			tc.expectFunc(w, loginAttemptRepo)
 		})
 	}
 }
@@ -1,45 +1,154 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/api/handlers"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/mail"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/user/internal/services"
 	"github.com/tricong1998/go-ecom/pkg/gin/middleware"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
 	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/gorm"
 )
 
 func SetupRoutes(routes *gin.Engine, db *gorm.DB, config *config.Config, log *zerolog.Logger) {
-	tokenMaker, err := token.NewJWTMaker(config.Auth.AccessTokenSecret)
+	tokenMaker, jwks, err := newAccessTokenMaker(config.Auth)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Cannot create token maker")
 		return
 	}
-	jwtService := services.NewJwtService(tokenMaker, config.Auth)
+	refreshTokenMaker, err := newRefreshTokenMaker(config.Auth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot create refresh token maker")
+		return
+	}
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revocationStore := token.NewGormRevocationStore(db)
+	outbox := rabbitmq.NewOutboxWriter(db)
+	jwtService := services.NewJwtService(tokenMaker, refreshTokenMaker, refreshTokenRepo, revocationStore, outbox, config.Auth)
 	userRepo := repository.NewUserRepository(db)
 	userPointRepo := repository.NewUserPointRepository(db)
 	userPointService := services.NewUserPointService(userPointRepo)
 	userService := services.NewUserService(userRepo, userPointService)
-	userHandler := handlers.NewUserHandler(userService, jwtService)
+	mailSender, err := mail.NewSender(config.Mail, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot create mail sender")
+		return
+	}
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	accountService := services.NewAccountService(userRepo, userTokenRepo, jwtService, mailSender, config.Account)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	challengeTokenMaker, err := newChallengeTokenMaker(config.Auth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Cannot create challenge token maker")
+		return
+	}
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	twoFactorService := services.NewTwoFactorService(userRepo, twoFactorRepo, challengeTokenMaker, revocationStore, config.Auth)
+	loginService := services.NewLoginService(userRepo, loginAttemptRepo, twoFactorService, config.Login)
+	userHandler := handlers.NewUserHandler(userService, jwtService, accountService, loginService, twoFactorService)
+
+	jwksHandler := handlers.NewJWKSHandler(jwks)
+	routes.GET("/.well-known/jwks.json", jwksHandler.ReadJWKS)
 
 	userGroup := routes.Group("users")
 	{
 		userGroup.POST("", userHandler.CreateUser)
 		userGroup.POST("/login", userHandler.Login)
+		userGroup.POST("/login/2fa", userHandler.LoginTwoFactor)
+		userGroup.POST("/login/2fa/enroll", userHandler.LoginEnrollTwoFactor)
+		userGroup.POST("/login/2fa/confirm", userHandler.LoginConfirmTwoFactor)
+		userGroup.POST("/refresh", userHandler.RefreshToken)
+		userGroup.POST("/verify-email", userHandler.VerifyEmail)
+		userGroup.POST("/forgot-password", userHandler.ForgotPassword)
+		userGroup.POST("/reset-password", userHandler.ResetPassword)
 	}
-	authRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, []string{}))
+	authRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{}))
 	{
 		authRoutes.GET("/me", userHandler.ReadMe)
+		authRoutes.POST("/logout", userHandler.Logout)
+		authRoutes.POST("/verify-email/resend", userHandler.ResendVerificationEmail)
+		authRoutes.POST("/me/2fa/enroll", userHandler.EnrollTwoFactor)
+		authRoutes.POST("/me/2fa/confirm", userHandler.ConfirmTwoFactor)
+		authRoutes.POST("/me/2fa/disable", userHandler.DisableTwoFactor)
+		authRoutes.POST("/me/2fa/recovery-codes", userHandler.RegenerateRecoveryCodes)
 		authRoutes.GET("/:id", userHandler.ReadUser)
 		authRoutes.PUT("/update-me", userHandler.UpdateMe)
 		authRoutes.DELETE("/:id", userHandler.DeleteUser)
 	}
 
-	adminRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, []string{"admin"}))
+	adminRoutes := userGroup.Group("/").Use(middleware.AuthMiddleware(tokenMaker, revocationStore, []string{"admin"}))
 	{
 		adminRoutes.GET("", userHandler.ListUsers)
+		adminRoutes.POST("/:id/revoke-sessions", userHandler.RevokeUserSessions)
+		adminRoutes.POST("/:id/unlock", userHandler.UnlockUser)
+		adminRoutes.PUT("/roles/:role/2fa", userHandler.SetTwoFactorRequirement)
 	}
 }
+
+// newAccessTokenMaker makes access tokens in the configured format. Tokens
+// signed with private keys are verified with the public keys published as a
+// JWKS, which is empty for the formats using the shared secret.
+func newAccessTokenMaker(auth config.AuthConfig) (token.Maker, *token.JWKS, error) {
+	keys, err := token.LoadSigningKeys(auth.AccessTokenKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	noKeys := &token.JWKS{Keys: []token.JWK{}}
+
+	switch auth.AccessTokenFormat {
+	case token.FormatJWT:
+		if len(keys) == 0 {
+			tokenMaker, err := token.NewJWTMaker(auth.AccessTokenSecret)
+			return tokenMaker, noKeys, err
+		}
+		tokenMaker, err := token.NewAsymmetricMaker(keys...)
+		if err != nil {
+			return nil, nil, err
+		}
+		jwks, err := token.NewJWKS(keys...)
+		return tokenMaker, jwks, err
+	case token.FormatPasetoLocal:
+		tokenMaker, err := token.NewPasetoLocalMaker(auth.AccessTokenSecret)
+		return tokenMaker, noKeys, err
+	case token.FormatPasetoPublic:
+		tokenMaker, err := token.NewPasetoPublicMaker(keys...)
+		if err != nil {
+			return nil, nil, err
+		}
+		jwks, err := token.NewJWKS(keys...)
+		return tokenMaker, jwks, err
+	default:
+		return nil, nil, fmt.Errorf("unknown access token format %q", auth.AccessTokenFormat)
+	}
+}
+
+// newRefreshTokenMaker makes refresh tokens with a secret of their own, so
+// that leaking one of the secrets does not expose the other tokens.
+func newRefreshTokenMaker(auth config.AuthConfig) (token.Maker, error) {
+	if auth.RefreshTokenSecret == "" {
+		return nil, errors.New("REFRESH_TOKEN_SECRET is required")
+	}
+	if auth.RefreshTokenSecret == auth.AccessTokenSecret {
+		return nil, errors.New("REFRESH_TOKEN_SECRET must differ from ACCESS_TOKEN_SECRET")
+	}
+	return token.NewRefreshTokenMaker(auth.RefreshTokenSecret)
+}
+
+// newChallengeTokenMaker makes the challenge tokens of two step logins with a
+// key derived from the refresh token secret, so that they are never accepted
+// as access or refresh tokens, even when the secrets are configured equal.
+func newChallengeTokenMaker(auth config.AuthConfig) (token.Maker, error) {
+	mac := hmac.New(sha256.New, []byte(auth.RefreshTokenSecret))
+	mac.Write([]byte("two-factor-challenge"))
+	return token.NewJWTMaker(hex.EncodeToString(mac.Sum(nil)))
+}
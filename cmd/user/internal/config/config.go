@@ -2,9 +2,11 @@ package config
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"github.com/tricong1998/go-ecom/pkg/util"
 )
 
@@ -16,9 +18,12 @@ type DBConfig struct {
 	DBName     string
 }
 
+// HttpServerConfig takes the client IP address from the X-Forwarded-For
+// header only for requests from TrustedProxies.
 type HttpServerConfig struct {
-	Host string
-	Port string
+	Host           string
+	Port           string
+	TrustedProxies []string
 }
 
 type GrpcServerConfig struct {
@@ -33,11 +38,53 @@ type RabbitMQConfig struct {
 	Password string
 }
 
+// AuthConfig signs access tokens with AccessTokenKeys, a comma separated
+// list of id:path pairs of PEM private keys whose first key signs new tokens,
+// or with AccessTokenSecret when it is empty. AccessTokenFormat is one of the
+// token formats, JWT by default. Logins with two factor authentication get a
+// challenge token lasting ChallengeTokenDuration first, and authenticator
+// apps show the accounts under TwoFactorIssuer.
 type AuthConfig struct {
-	AccessTokenDuration  time.Duration
-	AccessTokenSecret    string
-	RefreshTokenSecret   string
-	RefreshTokenDuration time.Duration
+	AccessTokenFormat      string
+	AccessTokenDuration    time.Duration
+	AccessTokenSecret      string
+	AccessTokenKeys        string
+	RefreshTokenSecret     string
+	RefreshTokenDuration   time.Duration
+	ChallengeTokenDuration time.Duration
+	TwoFactorIssuer        string
+}
+
+// MailConfig picks how emails are sent: through the SMTP server with
+// Sender "smtp", appended to FilePath with "file", or logged with "log".
+type MailConfig struct {
+	Sender       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	FilePath     string
+}
+
+// AccountConfig sets the links mailed to users, which point to AppURL, and how
+// long they work.
+type AccountConfig struct {
+	AppURL               string
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+}
+
+// LoginConfig locks a username after MaxFailures failed logins, and an IP
+// address after IPMaxFailures, for LockoutDuration doubled on every further
+// failure up to MaxLockoutDuration. Failures older than FailureWindow are
+// forgotten.
+type LoginConfig struct {
+	MaxFailures        int
+	IPMaxFailures      int
+	LockoutDuration    time.Duration
+	MaxLockoutDuration time.Duration
+	FailureWindow      time.Duration
 }
 
 type Config struct {
@@ -46,6 +93,9 @@ type Config struct {
 	RabbitMQConfig RabbitMQConfig
 	DB             DBConfig
 	Auth           AuthConfig
+	Mail           MailConfig
+	Account        AccountConfig
+	Login          LoginConfig
 	Env            string
 }
 
@@ -59,8 +109,9 @@ func Load() (*Config, error) {
 	config := &Config{
 		Env: os.Getenv("APP_ENV"),
 		Server: HttpServerConfig{
-			Port: os.Getenv("USER_SERVER_PORT"),
-			Host: os.Getenv("USER_SERVER_HOST"),
+			Port:           os.Getenv("USER_SERVER_PORT"),
+			Host:           os.Getenv("USER_SERVER_HOST"),
+			TrustedProxies: parseList(os.Getenv("USER_TRUSTED_PROXIES")),
 		},
 		GrpcServer: GrpcServerConfig{
 			Port: os.Getenv("USER_GRPC_SERVER_PORT"),
@@ -80,13 +131,66 @@ func Load() (*Config, error) {
 			Password: os.Getenv("AMQP_SERVER_PASSWORD"),
 		},
 		Auth: AuthConfig{
-			AccessTokenSecret:    os.Getenv("ACCESS_TOKEN_SECRET"),
-			RefreshTokenSecret:   os.Getenv("REFRESH_TOKEN_SECRET"),
-			AccessTokenDuration:  util.ParseDuration(os.Getenv("ACCESS_TOKEN_DURATION"), 15*time.Minute),
-			RefreshTokenDuration: util.ParseDuration(os.Getenv("REFRESH_TOKEN_DURATION"), 24*time.Hour),
+			AccessTokenFormat:      os.Getenv("ACCESS_TOKEN_FORMAT"),
+			AccessTokenSecret:      os.Getenv("ACCESS_TOKEN_SECRET"),
+			AccessTokenKeys:        os.Getenv("ACCESS_TOKEN_KEYS"),
+			RefreshTokenSecret:     os.Getenv("REFRESH_TOKEN_SECRET"),
+			AccessTokenDuration:    util.ParseDuration(os.Getenv("ACCESS_TOKEN_DURATION"), 15*time.Minute),
+			RefreshTokenDuration:   util.ParseDuration(os.Getenv("REFRESH_TOKEN_DURATION"), 24*time.Hour),
+			ChallengeTokenDuration: util.ParseDuration(os.Getenv("TWO_FACTOR_CHALLENGE_DURATION"), 5*time.Minute),
+			TwoFactorIssuer:        os.Getenv("TWO_FACTOR_ISSUER"),
+		},
+		Mail: MailConfig{
+			Sender:       os.Getenv("MAIL_SENDER"),
+			From:         os.Getenv("MAIL_FROM"),
+			SMTPHost:     os.Getenv("SMTP_HOST"),
+			SMTPPort:     os.Getenv("SMTP_PORT"),
+			SMTPUser:     os.Getenv("SMTP_USER"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+			FilePath:     os.Getenv("MAIL_FILE_PATH"),
+		},
+		Account: AccountConfig{
+			AppURL:               os.Getenv("APP_URL"),
+			EmailVerificationTTL: util.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TTL"), 24*time.Hour),
+			PasswordResetTTL:     util.ParseDuration(os.Getenv("PASSWORD_RESET_TTL"), time.Hour),
+		},
+		Login: LoginConfig{
+			MaxFailures:        int(util.ParseInt64(os.Getenv("LOGIN_MAX_FAILURES"), 5)),
+			IPMaxFailures:      int(util.ParseInt64(os.Getenv("LOGIN_IP_MAX_FAILURES"), 20)),
+			LockoutDuration:    util.ParseDuration(os.Getenv("LOGIN_LOCKOUT_DURATION"), time.Minute),
+			MaxLockoutDuration: util.ParseDuration(os.Getenv("LOGIN_MAX_LOCKOUT_DURATION"), time.Hour),
+			FailureWindow:      util.ParseDuration(os.Getenv("LOGIN_FAILURE_WINDOW"), 24*time.Hour),
 		},
 	}
 
+	if config.Auth.AccessTokenFormat == "" {
+		config.Auth.AccessTokenFormat = token.FormatJWT
+	}
+
+	if config.Auth.TwoFactorIssuer == "" {
+		config.Auth.TwoFactorIssuer = "go-ecom"
+	}
+
+	if config.Mail.Sender == "" {
+		config.Mail.Sender = "log"
+	}
+
+	if config.Mail.From == "" {
+		config.Mail.From = "no-reply@localhost"
+	}
+
+	if config.Mail.SMTPPort == "" {
+		config.Mail.SMTPPort = "587"
+	}
+
+	if config.Mail.FilePath == "" {
+		config.Mail.FilePath = "mail.log"
+	}
+
+	if config.Account.AppURL == "" {
+		config.Account.AppURL = "http://localhost:3000"
+	}
+
 	if config.Server.Port == "" {
 		config.Server.Port = "3333"
 	}
@@ -110,3 +214,14 @@ func Load() (*Config, error) {
 
 	return config, nil
 }
+
+func parseList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
 	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
+	"github.com/tricong1998/go-ecom/pkg/token"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -22,6 +24,8 @@ func Initialize(dbConfig *config.DBConfig) (*gorm.DB, error) {
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Unique violations come back as gorm.ErrDuplicatedKey.
+		TranslateError: true,
 	})
 
 	if err != nil {
@@ -35,6 +39,14 @@ func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
 		&models.UserPoint{},
+		&models.RefreshToken{},
+		&models.UserToken{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.RoleSetting{},
+		&token.RevokedToken{},
+		&token.RevokedUserTokens{},
+		&rabbitmq.OutboxMessage{},
 		// &models.Order{},
 		// Add other models here as needed
 	)
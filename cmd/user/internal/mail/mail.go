@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+)
+
+const (
+	SenderSMTP = "smtp"
+	SenderFile = "file"
+	SenderLog  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails to users.
+type Sender interface {
+	Send(message Message) error
+}
+
+// SMTPSender sends emails through an SMTP server, authenticating with PLAIN
+// when a user is configured.
+type SMTPSender struct {
+	host     string
+	port     string
+	user     string
+	password string
+	from     string
+}
+
+func NewSMTPSender(host, port, user, password, from string) *SMTPSender {
+	return &SMTPSender{host, port, user, password, from}
+}
+
+func (s *SMTPSender) Send(message Message) error {
+	var auth smtp.Auth
+	if s.user != "" {
+		auth = smtp.PlainAuth("", s.user, s.password, s.host)
+	}
+	address := net.JoinHostPort(s.host, s.port)
+	return smtp.SendMail(address, auth, s.from, []string{message.To}, format(s.from, message))
+}
+
+// FileSender appends emails to a file instead of sending them, for local
+// development and tests.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+func NewFileSender(path, from string) *FileSender {
+	return &FileSender{path: path, from: from}
+}
+
+func (s *FileSender) Send(message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(format(s.from, message), "\r\n"...))
+	return err
+}
+
+// LogSender writes emails to the log instead of sending them.
+type LogSender struct {
+	log *zerolog.Logger
+}
+
+func NewLogSender(log *zerolog.Logger) *LogSender {
+	return &LogSender{log}
+}
+
+func (s *LogSender) Send(message Message) error {
+	s.log.Info().
+		Str("to", message.To).
+		Str("subject", message.Subject).
+		Str("body", message.Body).
+		Msg("Email not sent, logged instead")
+	return nil
+}
+
+// NewSender returns the sender configured by mailConfig.Sender, one of
+// SenderSMTP, SenderFile and SenderLog.
+func NewSender(mailConfig config.MailConfig, log *zerolog.Logger) (Sender, error) {
+	switch mailConfig.Sender {
+	case SenderSMTP:
+		return NewSMTPSender(mailConfig.SMTPHost, mailConfig.SMTPPort, mailConfig.SMTPUser, mailConfig.SMTPPassword, mailConfig.From), nil
+	case SenderFile:
+		return NewFileSender(mailConfig.FilePath, mailConfig.From), nil
+	case SenderLog:
+		return NewLogSender(log), nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", mailConfig.Sender)
+	}
+}
+
+func format(from string, message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", sanitizeHeader(message.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", sanitizeHeader(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// sanitizeHeader drops line breaks, which would let a value add headers.
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
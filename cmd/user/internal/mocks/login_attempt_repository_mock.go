@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+)
+
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) ListLoginAttempts(keys ...string) ([]models.LoginAttempt, error) {
+	args := m.Called(keys)
+	return args.Get(0).([]models.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) RecordLoginFailure(key string, window time.Duration) (*models.LoginAttempt, error) {
+	args := m.Called(key, window)
+	return args.Get(0).(*models.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) LockLoginAttempts(key string, until time.Time) error {
+	args := m.Called(key, until)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) ResetLoginAttempts(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
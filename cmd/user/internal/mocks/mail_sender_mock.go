@@ -0,0 +1,15 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/mail"
+)
+
+type MockMailSender struct {
+	mock.Mock
+}
+
+func (m *MockMailSender) Send(message mail.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
@@ -0,0 +1,14 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOutboxWriter struct {
+	mock.Mock
+}
+
+func (m *MockOutboxWriter) Write(routingKey string, msg interface{}) error {
+	args := m.Called(routingKey, msg)
+	return args.Error(0)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+)
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(refreshToken *models.RefreshToken) error {
+	args := m.Called(refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) ReadRefreshToken(id string) (*models.RefreshToken, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RotateRefreshToken(id string, next *models.RefreshToken) error {
+	args := m.Called(id, next)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokenFamily(familyId string) error {
+	args := m.Called(familyId)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUserRefreshTokens(userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
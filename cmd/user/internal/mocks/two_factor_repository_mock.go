@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+)
+
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) SaveTOTPSecret(userId uint, secret string) error {
+	args := m.Called(userId, secret)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) EnableTwoFactor(userId uint, counter int64, recoveryCodes []models.RecoveryCode) error {
+	args := m.Called(userId, counter, recoveryCodes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) DisableTwoFactor(userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseTOTPCounter(userId uint, counter int64) error {
+	args := m.Called(userId, counter)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(userId uint, recoveryCodes []models.RecoveryCode) error {
+	args := m.Called(userId, recoveryCodes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) ConsumeRecoveryCode(userId uint, codeHash string) error {
+	args := m.Called(userId, codeHash)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) IsTwoFactorRequired(role string) (bool, error) {
+	args := m.Called(role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) SetTwoFactorRequired(role string, required bool) error {
+	args := m.Called(role, required)
+	return args.Error(0)
+}
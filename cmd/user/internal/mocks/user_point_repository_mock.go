@@ -29,6 +29,11 @@ func (m *MockUserPointRepository) DeleteUserPoint(id uint) error {
 	return args.Error(0)
 }
 
+func (m *MockUserPointRepository) DeleteUserPointByOrderId(orderId uint) error {
+	args := m.Called(orderId)
+	return args.Error(0)
+}
+
 func (m *MockUserPointRepository) ListUserPoints(
 	perPage, page int32,
 	userId *uint,
@@ -41,3 +41,8 @@ func (m *MockUserRepository) GetUserByUsername(username string) (*models.User, e
 	args := m.Called(username)
 	return args.Get(0).(*models.User), args.Error(1)
 }
+
+func (m *MockUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*models.User), args.Error(1)
+}
@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+)
+
+type MockUserTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserTokenRepository) CreateUserToken(userToken *models.UserToken) error {
+	args := m.Called(userToken)
+	return args.Error(0)
+}
+
+func (m *MockUserTokenRepository) ConsumeUserToken(purpose, tokenHash string) (*models.UserToken, error) {
+	args := m.Called(purpose, tokenHash)
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
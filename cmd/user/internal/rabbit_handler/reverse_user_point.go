@@ -0,0 +1,28 @@
+package rabbit_handler
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog"
+	"github.com/streadway/amqp"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/services"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
+)
+
+type ReverseUserPointDependencies struct {
+	UserService *services.UserService
+	Logger      zerolog.Logger
+}
+
+func ReverseUserPoint(queue string, msg amqp.Delivery, dependencies *ReverseUserPointDependencies) error {
+	dependencies.Logger.Info().Msgf("Message received on queue: %s with message: %s", queue, string(msg.Body))
+
+	var orderCancelled dto.OrderCancelled
+
+	err := json.Unmarshal(msg.Body, &orderCancelled)
+	if err != nil {
+		return err
+	}
+
+	return dependencies.UserService.ReverseUserPoint(orderCancelled)
+}
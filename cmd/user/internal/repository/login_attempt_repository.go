@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+type ILoginAttemptRepository interface {
+	ListLoginAttempts(keys ...string) ([]models.LoginAttempt, error)
+	RecordLoginFailure(key string, window time.Duration) (*models.LoginAttempt, error)
+	LockLoginAttempts(key string, until time.Time) error
+	ResetLoginAttempts(key string) error
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db}
+}
+
+func (repo *LoginAttemptRepository) ListLoginAttempts(keys ...string) ([]models.LoginAttempt, error) {
+	var loginAttempts []models.LoginAttempt
+	err := repo.db.Where("key IN ?", keys).Find(&loginAttempts).Error
+	return loginAttempts, err
+}
+
+// RecordLoginFailure counts a failed login for key and returns the updated
+// count. Failures older than window are forgotten.
+func (repo *LoginAttemptRepository) RecordLoginFailure(key string, window time.Duration) (*models.LoginAttempt, error) {
+	now := time.Now()
+	loginAttempt := models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now}
+	err := repo.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "failures"}, Value: gorm.Expr(
+					"CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END",
+					now.Add(-window),
+				)},
+				{Column: clause.Column{Name: "last_failure_at"}, Value: gorm.Expr("excluded.last_failure_at")},
+				{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("excluded.updated_at")},
+			},
+		},
+		clause.Returning{},
+	).Create(&loginAttempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &loginAttempt, nil
+}
+
+func (repo *LoginAttemptRepository) LockLoginAttempts(key string, until time.Time) error {
+	return repo.db.Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (repo *LoginAttemptRepository) ResetLoginAttempts(key string) error {
+	return repo.db.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
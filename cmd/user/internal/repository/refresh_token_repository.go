@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenRotated is returned when rotating a token that was already
+// rotated.
+var ErrRefreshTokenRotated = errors.New("refresh token was already rotated")
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+type IRefreshTokenRepository interface {
+	CreateRefreshToken(input *models.RefreshToken) error
+	ReadRefreshToken(id string) (*models.RefreshToken, error)
+	RotateRefreshToken(id string, next *models.RefreshToken) error
+	RevokeRefreshTokenFamily(familyId string) error
+	RevokeUserRefreshTokens(userId uint) error
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db}
+}
+
+func (repo *RefreshTokenRepository) CreateRefreshToken(input *models.RefreshToken) error {
+	return repo.db.Create(input).Error
+}
+
+func (repo *RefreshTokenRepository) ReadRefreshToken(id string) (*models.RefreshToken, error) {
+	var refreshToken models.RefreshToken
+	err := repo.db.Where("id = ?", id).First(&refreshToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// RotateRefreshToken marks the token id as rotated and stores next in the
+// same transaction. Only one of concurrent rotations of a token succeeds, the
+// others get ErrRefreshTokenRotated.
+func (repo *RefreshTokenRepository) RotateRefreshToken(id string, next *models.RefreshToken) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenRotated
+		}
+		return tx.Create(next).Error
+	})
+}
+
+func (repo *RefreshTokenRepository) RevokeRefreshTokenFamily(familyId string) error {
+	return repo.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (repo *RefreshTokenRepository) RevokeUserRefreshTokens(userId uint) error {
+	return repo.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+}
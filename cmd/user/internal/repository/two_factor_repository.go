@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+type ITwoFactorRepository interface {
+	SaveTOTPSecret(userId uint, secret string) error
+	EnableTwoFactor(userId uint, counter int64, recoveryCodes []models.RecoveryCode) error
+	DisableTwoFactor(userId uint) error
+	UseTOTPCounter(userId uint, counter int64) error
+	ReplaceRecoveryCodes(userId uint, recoveryCodes []models.RecoveryCode) error
+	ConsumeRecoveryCode(userId uint, codeHash string) error
+	IsTwoFactorRequired(role string) (bool, error)
+	SetTwoFactorRequired(role string, required bool) error
+}
+
+func NewTwoFactorRepository(db *gorm.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db}
+}
+
+// SaveTOTPSecret stores the secret of an enrollment, which is not enabled
+// until it is confirmed.
+func (repo *TwoFactorRepository) SaveTOTPSecret(userId uint, secret string) error {
+	return repo.db.Model(&models.User{}).Where("id = ? AND totp_enabled_at IS NULL", userId).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_counter": 0}).Error
+}
+
+// EnableTwoFactor enables the enrolled secret, whose step counter was just
+// used to confirm it, along with the recovery codes.
+func (repo *TwoFactorRepository) EnableTwoFactor(userId uint, counter int64, recoveryCodes []models.RecoveryCode) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userId).
+			Updates(map[string]interface{}{"totp_enabled_at": time.Now(), "totp_last_counter": counter}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userId, recoveryCodes)
+	})
+}
+
+func (repo *TwoFactorRepository) DisableTwoFactor(userId uint) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userId).
+			Updates(map[string]interface{}{"totp_secret": "", "totp_enabled_at": nil, "totp_last_counter": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPCounter records that the code of the step counter was used. Codes of
+// that step or earlier ones are not found anymore, even by concurrent
+// requests.
+func (repo *TwoFactorRepository) UseTOTPCounter(userId uint, counter int64) error {
+	result := repo.db.Model(&models.User{}).Where("id = ? AND totp_last_counter < ?", userId, counter).
+		Update("totp_last_counter", counter)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (repo *TwoFactorRepository) ReplaceRecoveryCodes(userId uint, recoveryCodes []models.RecoveryCode) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userId, recoveryCodes)
+	})
+}
+
+// ConsumeRecoveryCode marks the code as used; used and unknown codes are not
+// found.
+func (repo *TwoFactorRepository) ConsumeRecoveryCode(userId uint, codeHash string) error {
+	result := repo.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (repo *TwoFactorRepository) IsTwoFactorRequired(role string) (bool, error) {
+	var roleSettings []models.RoleSetting
+	err := repo.db.Where("role = ?", role).Limit(1).Find(&roleSettings).Error
+	if err != nil || len(roleSettings) == 0 {
+		return false, err
+	}
+	return roleSettings[0].RequireTwoFactor, nil
+}
+
+func (repo *TwoFactorRepository) SetTwoFactorRequired(role string, required bool) error {
+	return repo.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "role"}},
+		DoUpdates: clause.AssignmentColumns([]string{"require_two_factor", "updated_at"}),
+	}).Create(&models.RoleSetting{Role: role, RequireTwoFactor: required}).Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uint, recoveryCodes []models.RecoveryCode) error {
+	err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&recoveryCodes).Error
+}
@@ -18,6 +18,7 @@ type IUserPointRepository interface {
 	) ([]models.UserPoint, int64, error)
 	UpdateUserPoint(input *models.UserPoint) error
 	DeleteUserPoint(id uint) error
+	DeleteUserPointByOrderId(orderId uint) error
 }
 
 func NewUserPointRepository(db *gorm.DB) *UserPointRepository {
@@ -66,3 +67,7 @@ func (userRepo *UserPointRepository) UpdateUserPoint(input *models.UserPoint) er
 func (userRepo *UserPointRepository) DeleteUserPoint(id uint) error {
 	return userRepo.db.Delete(&models.UserPoint{}, id).Error
 }
+
+func (userRepo *UserPointRepository) DeleteUserPointByOrderId(orderId uint) error {
+	return userRepo.db.Where("order_id = ?", orderId).Delete(&models.UserPoint{}).Error
+}
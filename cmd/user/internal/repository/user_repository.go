@@ -13,6 +13,7 @@ type IUserRepository interface {
 	CreateUser(input *models.User) error
 	ReadUser(id uint) (*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
 	ListUsers(
 		perPage, page int32,
 		username *string,
@@ -47,6 +48,15 @@ func (userRepo *UserRepository) GetUserByUsername(username string) (*models.User
 	return user, nil
 }
 
+func (userRepo *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+	var user *models.User
+	err := userRepo.db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (userRepo *UserRepository) ListUsers(
 	perPage, page int32,
 	username *string,
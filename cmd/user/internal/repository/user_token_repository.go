@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository struct {
+	db *gorm.DB
+}
+
+type IUserTokenRepository interface {
+	CreateUserToken(input *models.UserToken) error
+	ConsumeUserToken(purpose, tokenHash string) (*models.UserToken, error)
+}
+
+func NewUserTokenRepository(db *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{db}
+}
+
+// CreateUserToken stores input and drops the unused tokens of the same user
+// and purpose, so that only the latest email works.
+func (repo *UserTokenRepository) CreateUserToken(input *models.UserToken) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", input.UserId, input.Purpose).
+			Delete(&models.UserToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(input).Error
+	})
+}
+
+// ConsumeUserToken marks the token as used and returns it. A token can only
+// be consumed once, even by concurrent requests; tokens that are unknown,
+// expired or already used are not found.
+func (repo *UserTokenRepository) ConsumeUserToken(purpose, tokenHash string) (*models.UserToken, error) {
+	var userToken models.UserToken
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.UserToken{}).
+			Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("token_hash = ?", tokenHash).First(&userToken).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &userToken, nil
+}
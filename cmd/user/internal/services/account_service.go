@@ -0,0 +1,181 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/mail"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/util"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAccountToken  = errors.New("token is invalid or expired")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrNoEmail              = errors.New("user has no email")
+)
+
+// AccountService mails users single use links to verify their email and to
+// reset their password.
+type AccountService struct {
+	userRepo      repository.IUserRepository
+	userTokenRepo repository.IUserTokenRepository
+	jwtService    IJwtService
+	mailSender    mail.Sender
+	accountConfig config.AccountConfig
+}
+
+type IAccountService interface {
+	SendVerificationEmail(user *models.User) error
+	VerifyEmail(token string) error
+	ForgotPassword(email string) error
+	ResetPassword(token string, password string) error
+}
+
+func NewAccountService(
+	userRepo repository.IUserRepository,
+	userTokenRepo repository.IUserTokenRepository,
+	jwtService IJwtService,
+	mailSender mail.Sender,
+	accountConfig config.AccountConfig,
+) *AccountService {
+	return &AccountService{userRepo, userTokenRepo, jwtService, mailSender, accountConfig}
+}
+
+// SendVerificationEmail mails user a link to verify their email. Links sent
+// before stop working.
+func (accountService *AccountService) SendVerificationEmail(user *models.User) error {
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := accountService.createToken(user.ID, models.UserTokenPurposeVerifyEmail, accountService.accountConfig.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return accountService.mailSender.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nOpen this link to verify your email:\n%s\n\nThe link expires in %s.",
+			user.FullName, accountService.link("/verify-email", token), accountService.accountConfig.EmailVerificationTTL,
+		),
+	})
+}
+
+func (accountService *AccountService) VerifyEmail(token string) error {
+	user, err := accountService.consumeToken(models.UserTokenPurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return accountService.userRepo.UpdateUser(user)
+}
+
+// ForgotPassword mails a password reset link to the user of email. It
+// succeeds for unknown emails too, so that it does not tell which emails have
+// an account.
+func (accountService *AccountService) ForgotPassword(email string) error {
+	user, err := accountService.userRepo.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := accountService.createToken(user.ID, models.UserTokenPurposeResetPassword, accountService.accountConfig.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	return accountService.mailSender.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nOpen this link to choose a new password:\n%s\n\nThe link expires in %s. If you did not ask for it, ignore this email.",
+			user.FullName, accountService.link("/reset-password", token), accountService.accountConfig.PasswordResetTTL,
+		),
+	})
+}
+
+// ResetPassword sets the password of the user the token was mailed to, and
+// signs them out of every session. Receiving the link also proves they own
+// their email.
+func (accountService *AccountService) ResetPassword(token string, password string) error {
+	user, err := accountService.consumeToken(models.UserTokenPurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := util.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	err = accountService.userRepo.UpdateUser(user)
+	if err != nil {
+		return err
+	}
+	return accountService.jwtService.RevokeUserSessions(user.ID)
+}
+
+// createToken stores the hash of a new random token and returns the token.
+func (accountService *AccountService) createToken(userId uint, purpose string, ttl time.Duration) (string, error) {
+	random := make([]byte, 32)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+
+	err = accountService.userTokenRepo.CreateUserToken(&models.UserToken{
+		UserId:    userId,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (accountService *AccountService) consumeToken(purpose, token string) (*models.User, error) {
+	userToken, err := accountService.userTokenRepo.ConsumeUserToken(purpose, hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAccountToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return accountService.userRepo.ReadUser(userToken.UserId)
+}
+
+func (accountService *AccountService) link(path, token string) string {
+	return accountService.accountConfig.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
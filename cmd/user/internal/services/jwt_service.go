@@ -1,36 +1,110 @@
 package services
 
 import (
+	"errors"
+	"time"
+
 	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/events"
+	"github.com/tricong1998/go-ecom/pkg/rabbitmq"
 	"github.com/tricong1998/go-ecom/pkg/token"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenRevoked = errors.New("refresh token is revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
 )
 
 type JwtService struct {
-	tokenMaker token.Maker
-	authConfig config.AuthConfig
+	tokenMaker        token.Maker
+	refreshTokenMaker token.Maker
+	refreshTokenRepo  repository.IRefreshTokenRepository
+	revocationStore   token.RevocationStore
+	outbox            rabbitmq.IOutboxWriter
+	authConfig        config.AuthConfig
 }
 
 type IJwtService interface {
 	CreateToken(username string, userId uint, role string) (string, string, error)
+	RefreshToken(refreshToken string) (string, string, error)
 	VerifyToken(token string) (string, error)
+	Logout(payload *token.Payload, refreshToken string) error
+	RevokeUserSessions(userId uint) error
 }
 
-func NewJwtService(tokenMaker token.Maker, authConfig config.AuthConfig) *JwtService {
-	return &JwtService{tokenMaker, authConfig}
+func NewJwtService(
+	tokenMaker token.Maker,
+	refreshTokenMaker token.Maker,
+	refreshTokenRepo repository.IRefreshTokenRepository,
+	revocationStore token.RevocationStore,
+	outbox rabbitmq.IOutboxWriter,
+	authConfig config.AuthConfig,
+) *JwtService {
+	return &JwtService{tokenMaker, refreshTokenMaker, refreshTokenRepo, revocationStore, outbox, authConfig}
 }
 
+// CreateToken returns an access token and the refresh token of a new token
+// family.
 func (jwtService *JwtService) CreateToken(username string, userId uint, role string) (string, string, error) {
 	accessToken, _, err := jwtService.tokenMaker.CreateToken(username, userId, jwtService.authConfig.AccessTokenDuration, role)
 	if err != nil {
 		return "", "", err
 	}
-	refreshToken, _, err := jwtService.tokenMaker.CreateToken(username, userId, jwtService.authConfig.RefreshTokenDuration, role)
+	refreshToken, payload, err := jwtService.refreshTokenMaker.CreateToken(username, userId, jwtService.authConfig.RefreshTokenDuration, role)
+	if err != nil {
+		return "", "", err
+	}
+	err = jwtService.refreshTokenRepo.CreateRefreshToken(toRefreshToken(payload, payload.ID.String()))
 	if err != nil {
 		return "", "", err
 	}
 	return accessToken, refreshToken, nil
 }
 
+// RefreshToken exchanges refreshToken for a new access token and rotates it
+// into a new refresh token of its family. Using a token that was already
+// rotated revokes its family, since either the client or an attacker holds a
+// stolen copy.
+func (jwtService *JwtService) RefreshToken(refreshToken string) (string, string, error) {
+	payload, err := jwtService.refreshTokenMaker.VerifyToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	stored, err := jwtService.refreshTokenRepo.ReadRefreshToken(payload.ID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", token.ErrInvalidToken
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if stored.RevokedAt != nil {
+		return "", "", ErrRefreshTokenRevoked
+	}
+	if stored.RotatedAt != nil {
+		return "", "", jwtService.revokeFamily(stored.FamilyID)
+	}
+
+	accessToken, _, err := jwtService.tokenMaker.CreateToken(payload.Username, payload.UserId, jwtService.authConfig.AccessTokenDuration, payload.Role)
+	if err != nil {
+		return "", "", err
+	}
+	nextToken, nextPayload, err := jwtService.refreshTokenMaker.CreateToken(payload.Username, payload.UserId, jwtService.authConfig.RefreshTokenDuration, payload.Role)
+	if err != nil {
+		return "", "", err
+	}
+	err = jwtService.refreshTokenRepo.RotateRefreshToken(stored.ID, toRefreshToken(nextPayload, stored.FamilyID))
+	if errors.Is(err, repository.ErrRefreshTokenRotated) {
+		return "", "", jwtService.revokeFamily(stored.FamilyID)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, nextToken, nil
+}
+
 func (jwtService *JwtService) VerifyToken(token string) (string, error) {
 	payload, err := jwtService.tokenMaker.VerifyToken(token)
 	if err != nil {
@@ -38,3 +112,72 @@ func (jwtService *JwtService) VerifyToken(token string) (string, error) {
 	}
 	return payload.Username, nil
 }
+
+// Logout revokes the access token of payload and, when refreshToken is given,
+// the token family it belongs to. The other services are told through the
+// outbox.
+func (jwtService *JwtService) Logout(payload *token.Payload, refreshToken string) error {
+	if refreshToken != "" {
+		refreshPayload, err := jwtService.refreshTokenMaker.VerifyToken(refreshToken)
+		if err != nil {
+			return err
+		}
+		if refreshPayload.UserId != payload.UserId {
+			return token.ErrInvalidToken
+		}
+		stored, err := jwtService.refreshTokenRepo.ReadRefreshToken(refreshPayload.ID.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if stored != nil {
+			err = jwtService.refreshTokenRepo.RevokeRefreshTokenFamily(stored.FamilyID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := jwtService.revocationStore.RevokeToken(payload.ID, payload.ExpiredAt)
+	if err != nil {
+		return err
+	}
+	return jwtService.outbox.Write(rabbitmq.TOKENS_REVOKED_ROUTING_KEY, events.TokensRevoked{
+		TokenId:   payload.ID,
+		UserId:    payload.UserId,
+		ExpiredAt: payload.ExpiredAt,
+	})
+}
+
+// RevokeUserSessions revokes every access and refresh token issued to userId
+// so far.
+func (jwtService *JwtService) RevokeUserSessions(userId uint) error {
+	now := time.Now()
+	err := jwtService.refreshTokenRepo.RevokeUserRefreshTokens(userId)
+	if err != nil {
+		return err
+	}
+	err = jwtService.revocationStore.RevokeUserTokens(userId, now)
+	if err != nil {
+		return err
+	}
+	return jwtService.outbox.Write(rabbitmq.TOKENS_REVOKED_ROUTING_KEY, events.TokensRevoked{
+		UserId:        userId,
+		RevokedBefore: now,
+	})
+}
+
+func (jwtService *JwtService) revokeFamily(familyId string) error {
+	if err := jwtService.refreshTokenRepo.RevokeRefreshTokenFamily(familyId); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func toRefreshToken(payload *token.Payload, familyId string) *models.RefreshToken {
+	return &models.RefreshToken{
+		ID:        payload.ID.String(),
+		FamilyID:  familyId,
+		UserId:    payload.UserId,
+		ExpiresAt: payload.ExpiredAt,
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/util"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// LoginLockedError refuses a login while its username or IP address is locked
+// out after too many failures.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// dummyPasswordHash is compared against when the username does not exist, so
+// that unknown usernames take as long as wrong passwords.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := util.HashPassword("dummy password")
+	return hash
+})
+
+// LoginResult is a finished login of User, or the first step of a two step
+// login when ChallengeToken is set. The challenge has the role
+// TwoFactorChallengeRole, or TwoFactorEnrollmentRole when the user must enroll
+// in two factor authentication first.
+type LoginResult struct {
+	User           *models.User
+	ChallengeToken string
+	ChallengeRole  string
+}
+
+// LoginService checks credentials and tracks failed logins per username and
+// per IP address, locking them out for longer and longer.
+type LoginService struct {
+	userRepo         repository.IUserRepository
+	loginAttemptRepo repository.ILoginAttemptRepository
+	twoFactorService ITwoFactorService
+	loginConfig      config.LoginConfig
+}
+
+type ILoginService interface {
+	Login(username, password, ip string) (*LoginResult, error)
+	VerifyTwoFactor(challengeToken, code, ip string) (*models.User, error)
+	Unlock(userId uint) error
+}
+
+func NewLoginService(
+	userRepo repository.IUserRepository,
+	loginAttemptRepo repository.ILoginAttemptRepository,
+	twoFactorService ITwoFactorService,
+	loginConfig config.LoginConfig,
+) *LoginService {
+	return &LoginService{userRepo, loginAttemptRepo, twoFactorService, loginConfig}
+}
+
+// Login checks the password of username. Unknown usernames and wrong
+// passwords both fail with ErrInvalidCredentials.
+func (loginService *LoginService) Login(username, password, ip string) (*LoginResult, error) {
+	usernameKey, ipKey := usernameAttemptKey(username), ipAttemptKey(ip)
+	err := loginService.checkLocked(usernameKey, ipKey)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := loginService.userRepo.GetUserByUsername(username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	passwordHash := dummyPasswordHash()
+	if user != nil {
+		passwordHash = user.Password
+	}
+	if util.ComparePassword(passwordHash, password) != nil || user == nil {
+		err := loginService.recordFailures(usernameKey, ipKey)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// The failures of the username are kept until the second factor is
+	// verified too, so that codes cannot be guessed between logins.
+	if user.TOTPEnabledAt != nil {
+		return loginService.challenge(user, TwoFactorChallengeRole)
+	}
+	required, err := loginService.twoFactorService.IsRequired(user)
+	if err != nil {
+		return nil, err
+	}
+	err = loginService.loginAttemptRepo.ResetLoginAttempts(usernameKey)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return loginService.challenge(user, TwoFactorEnrollmentRole)
+	}
+	return &LoginResult{User: user}, nil
+}
+
+// VerifyTwoFactor finishes the login of a challenge token with a TOTP or
+// recovery code. Wrong codes count as failed logins.
+func (loginService *LoginService) VerifyTwoFactor(challengeToken, code, ip string) (*models.User, error) {
+	user, payload, err := loginService.twoFactorService.ReadChallenge(challengeToken, TwoFactorChallengeRole)
+	if err != nil {
+		return nil, err
+	}
+	usernameKey, ipKey := usernameAttemptKey(user.Username), ipAttemptKey(ip)
+	err = loginService.checkLocked(usernameKey, ipKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = loginService.twoFactorService.VerifyCode(user, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		err := loginService.recordFailures(usernameKey, ipKey)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = loginService.twoFactorService.ConsumeChallenge(payload)
+	if err != nil {
+		return nil, err
+	}
+	err = loginService.loginAttemptRepo.ResetLoginAttempts(usernameKey)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Unlock clears the failed logins of the user, who can log in again at once.
+func (loginService *LoginService) Unlock(userId uint) error {
+	user, err := loginService.userRepo.ReadUser(userId)
+	if err != nil {
+		return err
+	}
+	return loginService.loginAttemptRepo.ResetLoginAttempts(usernameAttemptKey(user.Username))
+}
+
+func (loginService *LoginService) challenge(user *models.User, role string) (*LoginResult, error) {
+	challengeToken, err := loginService.twoFactorService.CreateChallenge(user, role)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{User: user, ChallengeToken: challengeToken, ChallengeRole: role}, nil
+}
+
+func (loginService *LoginService) checkLocked(keys ...string) error {
+	loginAttempts, err := loginService.loginAttemptRepo.ListLoginAttempts(keys...)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, loginAttempt := range loginAttempts {
+		if loginAttempt.LockedUntil != nil && loginAttempt.LockedUntil.After(now) {
+			retryAfter = max(retryAfter, loginAttempt.LockedUntil.Sub(now))
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{retryAfter}
+	}
+	return nil
+}
+
+// recordFailures counts a failure for the username and the IP address. Only
+// successes reset the username: an attacker must not clear the lockout of
+// their IP address by logging into an account of their own.
+func (loginService *LoginService) recordFailures(usernameKey, ipKey string) error {
+	err := loginService.recordFailure(usernameKey, loginService.loginConfig.MaxFailures)
+	if err != nil {
+		return err
+	}
+	return loginService.recordFailure(ipKey, loginService.loginConfig.IPMaxFailures)
+}
+
+func (loginService *LoginService) recordFailure(key string, maxFailures int) error {
+	loginAttempt, err := loginService.loginAttemptRepo.RecordLoginFailure(key, loginService.loginConfig.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if loginAttempt.Failures < maxFailures {
+		return nil
+	}
+	lockout := lockoutDuration(loginAttempt.Failures-maxFailures, loginService.loginConfig)
+	return loginService.loginAttemptRepo.LockLoginAttempts(key, time.Now().Add(lockout))
+}
+
+// lockoutDuration doubles the lockout with every failure past the limit.
+func lockoutDuration(extraFailures int, loginConfig config.LoginConfig) time.Duration {
+	lockout := loginConfig.LockoutDuration
+	for i := 0; i < extraFailures && lockout < loginConfig.MaxLockoutDuration; i++ {
+		lockout *= 2
+	}
+	return min(lockout, loginConfig.MaxLockoutDuration)
+}
+
+func usernameAttemptKey(username string) string {
+	return "username:" + strings.ToLower(username)
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
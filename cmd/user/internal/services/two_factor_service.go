@@ -0,0 +1,244 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tricong1998/go-ecom/cmd/user/internal/config"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/repository"
+	"github.com/tricong1998/go-ecom/cmd/user/internal/totp"
+	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"github.com/tricong1998/go-ecom/pkg/token"
+	"gorm.io/gorm"
+)
+
+// The roles of challenge tokens, which are only accepted to finish a login.
+const (
+	TwoFactorChallengeRole  = "two_factor_challenge"
+	TwoFactorEnrollmentRole = "two_factor_enrollment"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrInvalidTwoFactorCode  = errors.New("invalid two factor code")
+	ErrTwoFactorEnabled      = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled  = errors.New("two factor authentication is not enrolled")
+	ErrTwoFactorNotEnabled   = errors.New("two factor authentication is not enabled")
+	ErrTwoFactorRequired     = errors.New("two factor authentication is required for this role")
+	ErrInvalidChallengeToken = errors.New("invalid challenge token")
+)
+
+type TwoFactorEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// TwoFactorService manages TOTP two factor authentication: enrollment,
+// confirmation with a first code, recovery codes, and the challenge tokens
+// of two step logins.
+type TwoFactorService struct {
+	userRepo            repository.IUserRepository
+	twoFactorRepo       repository.ITwoFactorRepository
+	challengeTokenMaker token.Maker
+	revocationStore     token.RevocationStore
+	authConfig          config.AuthConfig
+}
+
+type ITwoFactorService interface {
+	Enroll(user *models.User) (*TwoFactorEnrollment, error)
+	Confirm(user *models.User, code string) ([]string, error)
+	Disable(user *models.User, code string) error
+	RegenerateRecoveryCodes(user *models.User, code string) ([]string, error)
+	VerifyCode(user *models.User, code string) error
+	IsRequired(user *models.User) (bool, error)
+	SetRequired(role string, required bool) error
+	CreateChallenge(user *models.User, role string) (string, error)
+	ReadChallenge(challengeToken string, role string) (*models.User, *token.Payload, error)
+	ConsumeChallenge(payload *token.Payload) error
+}
+
+func NewTwoFactorService(
+	userRepo repository.IUserRepository,
+	twoFactorRepo repository.ITwoFactorRepository,
+	challengeTokenMaker token.Maker,
+	revocationStore token.RevocationStore,
+	authConfig config.AuthConfig,
+) *TwoFactorService {
+	return &TwoFactorService{userRepo, twoFactorRepo, challengeTokenMaker, revocationStore, authConfig}
+}
+
+// Enroll creates a new secret for user, replacing an unconfirmed one.
+func (twoFactorService *TwoFactorService) Enroll(user *models.User) (*TwoFactorEnrollment, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = twoFactorService.twoFactorRepo.SaveTOTPSecret(user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(twoFactorService.authConfig.TwoFactorIssuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables two factor authentication once code shows the secret was
+// added to an authenticator app, and returns the recovery codes.
+func (twoFactorService *TwoFactorService) Confirm(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	counter, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastCounter)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, recoveryCodes, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = twoFactorService.twoFactorRepo.EnableTwoFactor(user.ID, counter, recoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	user.TOTPLastCounter = counter
+	return codes, nil
+}
+
+// Disable turns two factor authentication off, unless the role of user
+// requires it.
+func (twoFactorService *TwoFactorService) Disable(user *models.User, code string) error {
+	required, err := twoFactorService.IsRequired(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	err = twoFactorService.VerifyCode(user, code)
+	if err != nil {
+		return err
+	}
+	return twoFactorService.twoFactorRepo.DisableTwoFactor(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of user.
+func (twoFactorService *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	err := twoFactorService.VerifyCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, recoveryCodes, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = twoFactorService.twoFactorRepo.ReplaceRecoveryCodes(user.ID, recoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyCode accepts a TOTP code or a recovery code of user. Either can be
+// used only once.
+func (twoFactorService *TwoFactorService) VerifyCode(user *models.User, code string) error {
+	if user.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if len(strings.TrimSpace(code)) == totp.Digits {
+		counter, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastCounter)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		err := twoFactorService.twoFactorRepo.UseTOTPCounter(user.ID, counter)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	err := twoFactorService.twoFactorRepo.ConsumeRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// IsRequired tells whether the role of user requires two factor
+// authentication.
+func (twoFactorService *TwoFactorService) IsRequired(user *models.User) (bool, error) {
+	return twoFactorService.twoFactorRepo.IsTwoFactorRequired(user.Role)
+}
+
+func (twoFactorService *TwoFactorService) SetRequired(role string, required bool) error {
+	return twoFactorService.twoFactorRepo.SetTwoFactorRequired(role, required)
+}
+
+// CreateChallenge returns a short lived token that lets user finish a login
+// with a second factor, or enroll one when role is TwoFactorEnrollmentRole.
+func (twoFactorService *TwoFactorService) CreateChallenge(user *models.User, role string) (string, error) {
+	challengeToken, _, err := twoFactorService.challengeTokenMaker.CreateToken(user.Username, user.ID, twoFactorService.authConfig.ChallengeTokenDuration, role)
+	return challengeToken, err
+}
+
+// ReadChallenge returns the user of a challenge token created for role.
+func (twoFactorService *TwoFactorService) ReadChallenge(challengeToken string, role string) (*models.User, *token.Payload, error) {
+	payload, err := twoFactorService.challengeTokenMaker.VerifyToken(challengeToken)
+	if err != nil || payload.Role != role {
+		return nil, nil, ErrInvalidChallengeToken
+	}
+	revoked, err := twoFactorService.revocationStore.IsRevoked(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, ErrInvalidChallengeToken
+	}
+	user, err := twoFactorService.userRepo.ReadUser(payload.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, payload, nil
+}
+
+// ConsumeChallenge revokes a challenge token once its login is finished.
+func (twoFactorService *TwoFactorService) ConsumeChallenge(payload *token.Payload) error {
+	return twoFactorService.revocationStore.RevokeToken(payload.ID, payload.ExpiredAt)
+}
+
+// newRecoveryCodes returns new recovery codes, formatted like
+// "abcde-fghij", and the records of their hashes.
+func newRecoveryCodes(userId uint) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	recoveryCodes := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random := make([]byte, 10)
+		_, err := rand.Read(random)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(random))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		recoveryCodes = append(recoveryCodes, models.RecoveryCode{UserId: userId, CodeHash: hashToken(code)})
+	}
+	return codes, recoveryCodes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -18,6 +18,7 @@ type IUserPointService interface {
 	) ([]models.UserPoint, int64, error)
 	UpdateUserPoint(user *models.UserPoint) error
 	DeleteUserPoint(id uint) error
+	DeleteUserPointByOrderId(orderId uint) error
 }
 
 func NewUserPointService(userRepo repository.IUserPointRepository) *UserPointService {
@@ -50,3 +51,7 @@ func (us *UserPointService) UpdateUserPoint(user *models.UserPoint) error {
 func (us *UserPointService) DeleteUserPoint(id uint) error {
 	return us.UserPointRepo.DeleteUserPoint(id)
 }
+
+func (us *UserPointService) DeleteUserPointByOrderId(orderId uint) error {
+	return us.UserPointRepo.DeleteUserPointByOrderId(orderId)
+}
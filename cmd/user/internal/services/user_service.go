@@ -1,13 +1,19 @@
 package services
 
 import (
+	"errors"
 	"time"
 
 	"github.com/tricong1998/go-ecom/cmd/user/internal/repository"
 	"github.com/tricong1998/go-ecom/cmd/user/pkg/dto"
 	"github.com/tricong1998/go-ecom/cmd/user/pkg/models"
+	"gorm.io/gorm"
 )
 
+// ErrUserExists is returned when another user already has the username or
+// email.
+var ErrUserExists = errors.New("username or email is already taken")
+
 type UserService struct {
 	UserRepo         repository.IUserRepository
 	UserPointService IUserPointService
@@ -31,7 +37,7 @@ func NewUserService(userRepo repository.IUserRepository, userPointSvc IUserPoint
 
 func (us *UserService) CreateUser(user *models.User) error {
 	err := us.UserRepo.CreateUser(user)
-	return err
+	return userError(err)
 }
 
 func (us *UserService) ReadUser(id uint) (*models.User, error) {
@@ -53,7 +59,7 @@ func (us *UserService) ListUsers(
 
 func (us *UserService) UpdateUser(user *models.User) error {
 	err := us.UserRepo.UpdateUser(user)
-	return err
+	return userError(err)
 }
 
 func (us *UserService) DeleteUser(id uint) error {
@@ -71,6 +77,19 @@ func (us *UserService) CreateUserPoint(productCreated dto.CreateUserPoint) error
 	userPoint.UserId = productCreated.UserId
 	expiryTime := time.Now().Add(time.Hour * 24 * 365)
 	userPoint.ExpiryTime = expiryTime
-	userPoint.Point = productCreated.Amount
+	// One point is granted per minor unit of the order amount.
+	userPoint.Point = uint(max(productCreated.Amount.Amount, 0))
 	return us.UserPointService.CreateUserPoint(&userPoint)
 }
+
+// ReverseUserPoint takes back the points granted for a cancelled order.
+func (us *UserService) ReverseUserPoint(orderCancelled dto.OrderCancelled) error {
+	return us.UserPointService.DeleteUserPointByOrderId(orderCancelled.OrderId)
+}
+
+func userError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrUserExists
+	}
+	return err
+}
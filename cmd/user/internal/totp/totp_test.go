@@ -0,0 +1,45 @@
+package totp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The RFC vectors have 8 digits; these are their last 6.
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := Code(rfcSecret, Counter(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, expected, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+	code, err := Code(secret, Counter(now.Add(-Period)))
+	require.NoError(t, err)
+
+	counter, ok := Validate(secret, code, now, 0)
+	require.True(t, ok)
+	require.Equal(t, Counter(now)-1, counter)
+
+	_, ok = Validate(secret, code, now, counter)
+	require.False(t, ok)
+	_, ok = Validate(secret, code, now.Add(2*Period), 0)
+	require.False(t, ok)
+}
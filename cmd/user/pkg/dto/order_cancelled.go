@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/tricong1998/go-ecom/pkg/money"
+
+type OrderCancelled struct {
+	OrderId uint        `json:"order_id"`
+	UserId  uint        `json:"user_id"`
+	Amount  money.Money `json:"amount"`
+	Reason  string      `json:"reason"`
+}
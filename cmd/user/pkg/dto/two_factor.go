@@ -0,0 +1,31 @@
+package dto
+
+type ChallengeTokenDto struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type LoginTwoFactorDto struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorCodeDto struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorRoleRequest struct {
+	Role string `uri:"role" binding:"required,oneof=admin user"`
+}
+
+type TwoFactorRequirementDto struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
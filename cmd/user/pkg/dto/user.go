@@ -8,26 +8,66 @@ import (
 
 type CreateUserDto struct {
 	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 	FullName string `json:"full_name" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+// UpdateMeDto changes the profile of the signed in user. An empty Email keeps
+// the current one.
+type UpdateMeDto struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	FullName string `json:"full_name" binding:"required"`
+}
+
 type LoginUserDto struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+// LoginChallengeResponse asks for a second step to finish a login: a two
+// factor code, or an enrollment when TwoFactorEnrollmentRequired is set.
+type LoginChallengeResponse struct {
+	ChallengeToken              string `json:"challenge_token"`
+	TwoFactorRequired           bool   `json:"two_factor_required"`
+	TwoFactorEnrollmentRequired bool   `json:"two_factor_enrollment_required"`
+}
+
+type RefreshTokenDto struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutDto struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type VerifyEmailDto struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordDto struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordDto struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 type ReadUserRequest struct {
 	ID uint `uri:"id" binding:"required,min=1"`
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	FullName  string    `json:"full_name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Role      string    `json:"role"`
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	FullName      string    `json:"full_name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Role          string    `json:"role"`
 }
 
 type ListUserQuery struct {
@@ -43,11 +83,13 @@ type ListUserResponse struct {
 
 func ToUserResponse(user *models.User) *UserResponse {
 	return &UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		FullName:  user.FullName,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Role:      user.Role,
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		FullName:      user.FullName,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Role:          user.Role,
 	}
 }
@@ -1,7 +1,9 @@
 package dto
 
+import "github.com/tricong1998/go-ecom/pkg/money"
+
 type CreateUserPoint struct {
-	OrderId uint `json:"order_id" gorm:"unique"`
-	UserId  uint `json:"user_id" `
-	Amount  uint `json:"amount"`
+	OrderId uint        `json:"order_id" gorm:"unique"`
+	UserId  uint        `json:"user_id" `
+	Amount  money.Money `json:"amount"`
 }
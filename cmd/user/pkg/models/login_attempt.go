@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LoginAttempt counts the recent failed logins of a username or of an IP
+// address, identified by Key, and how long further logins are refused.
+type LoginAttempt struct {
+	Key           string     `json:"key" gorm:"primaryKey;size:255"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RefreshToken is an issued refresh token, stored by the ID of its payload.
+// Every refresh of a login rotates its token into a new one of the same
+// family, so that reusing a rotated token can revoke the whole login.
+type RefreshToken struct {
+	ID        string     `json:"id" gorm:"primaryKey;size:36"`
+	FamilyID  string     `json:"family_id" gorm:"size:36;index"`
+	UserId    uint       `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RecoveryCode logs a user in once in place of a TOTP code. Only the SHA-256
+// hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserId    uint       `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"size:64"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RoleSetting holds the security settings of a role.
+type RoleSetting struct {
+	Role             string    `json:"role" gorm:"primaryKey;size:32"`
+	RequireTwoFactor bool      `json:"require_two_factor"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
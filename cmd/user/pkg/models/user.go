@@ -1,16 +1,26 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// User is an account. Accounts created before emails were required have an
+// empty Email, which the unique index leaves out. TOTPSecret is set when two
+// factor authentication is enrolled, and only used once TOTPEnabledAt is set.
 type User struct {
 	gorm.Model
-	Username   string      `json:"username" gorm:"unique"`
-	Password   string      `json:"password"`
-	Role       string      `json:"role"`
-	FullName   string      `json:"full_name"`
-	UserPoints []UserPoint `json:"user_points"`
+	Username        string      `json:"username" gorm:"unique"`
+	Email           string      `json:"email" gorm:"index:idx_users_email,unique,where:email <> ''"`
+	EmailVerifiedAt *time.Time  `json:"email_verified_at"`
+	Password        string      `json:"password"`
+	TOTPSecret      string      `json:"-"`
+	TOTPEnabledAt   *time.Time  `json:"totp_enabled_at"`
+	TOTPLastCounter int64       `json:"-"`
+	Role            string      `json:"role"`
+	FullName        string      `json:"full_name"`
+	UserPoints      []UserPoint `json:"user_points"`
 }
 
 type Role string
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	UserTokenPurposeVerifyEmail   = "verify_email"
+	UserTokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken is a single use token mailed to a user to prove they own their
+// email. Only the SHA-256 hash of the token is stored.
+type UserToken struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserId    uint       `json:"user_id" gorm:"index"`
+	Purpose   string     `json:"purpose" gorm:"size:32"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,124 @@
+// Package events holds the messages exchanged by the services when orders are
+// processed asynchronously. Every service reacts to the events of the others on
+// E_COM_EXCHANGE instead of being called over gRPC by the order service.
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tricong1998/go-ecom/pkg/money"
+)
+
+const (
+	// ProcessingModeSync places orders by calling the other services over gRPC
+	// while the request is open.
+	ProcessingModeSync = "sync"
+	// ProcessingModeAsync accepts orders right away and lets the services
+	// complete them by exchanging events.
+	ProcessingModeAsync = "async"
+)
+
+type OrderItem struct {
+	ProductId uint `json:"product_id"`
+	Quantity  uint `json:"quantity"`
+}
+
+// OrderCreated asks the product service to reserve the order items.
+type OrderCreated struct {
+	OrderId       uint        `json:"order_id"`
+	UserId        uint        `json:"user_id"`
+	Amount        money.Money `json:"amount"`
+	PaymentMethod string      `json:"payment_method"`
+	Items         []OrderItem `json:"items"`
+}
+
+// StockReserved tells the payment service to charge the order.
+type StockReserved struct {
+	OrderId       uint        `json:"order_id"`
+	UserId        uint        `json:"user_id"`
+	Amount        money.Money `json:"amount"`
+	PaymentMethod string      `json:"payment_method"`
+	ReservationId uint        `json:"reservation_id"`
+}
+
+type StockReservationFailed struct {
+	OrderId uint   `json:"order_id"`
+	Reason  string `json:"reason"`
+}
+
+// PaymentSucceeded tells the product service to commit the reservation. The
+// payment is only authorized at this point, and captured once the stock is
+// committed.
+type PaymentSucceeded struct {
+	OrderId       uint `json:"order_id"`
+	PaymentId     uint `json:"payment_id"`
+	ReservationId uint `json:"reservation_id"`
+}
+
+// PaymentFailed tells the product service to release the reservation. It is
+// published when the payment cannot be authorized, or cannot be captured after
+// the stock was committed.
+type PaymentFailed struct {
+	OrderId       uint   `json:"order_id"`
+	PaymentId     uint   `json:"payment_id"`
+	ReservationId uint   `json:"reservation_id"`
+	Reason        string `json:"reason"`
+}
+
+// StockCommitted tells the payment service to capture the payment.
+type StockCommitted struct {
+	OrderId       uint `json:"order_id"`
+	PaymentId     uint `json:"payment_id"`
+	ReservationId uint `json:"reservation_id"`
+}
+
+// StockCommitFailed tells the payment service to void or refund the payment,
+// the reservation having expired while the payment was authorized.
+type StockCommitFailed struct {
+	OrderId       uint   `json:"order_id"`
+	PaymentId     uint   `json:"payment_id"`
+	ReservationId uint   `json:"reservation_id"`
+	Reason        string `json:"reason"`
+}
+
+// PaymentCaptured tells the order service that the order is paid.
+type PaymentCaptured struct {
+	OrderId       uint `json:"order_id"`
+	PaymentId     uint `json:"payment_id"`
+	ReservationId uint `json:"reservation_id"`
+}
+
+// PaymentRefunded is published whenever a refund of a payment succeeds.
+// RefundedAmount is the total refunded so far, and Status the status of the
+// payment after the refund.
+type PaymentRefunded struct {
+	OrderId        uint        `json:"order_id"`
+	PaymentId      uint        `json:"payment_id"`
+	RefundId       uint        `json:"refund_id"`
+	Amount         money.Money `json:"amount"`
+	RefundedAmount money.Money `json:"refunded_amount"`
+	Status         string      `json:"status"`
+	Reason         string      `json:"reason"`
+}
+
+// PaymentConfirmed is published when the provider confirms, through a
+// webhook, a payment that was left pending. Status is the new status of the
+// payment.
+type PaymentConfirmed struct {
+	OrderId   uint   `json:"order_id"`
+	PaymentId uint   `json:"payment_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+}
+
+// TokensRevoked is published by the user service whenever access tokens are
+// revoked, so that every service rejects them. A TokenId revokes that token
+// until ExpiredAt, otherwise every token of UserId issued before
+// RevokedBefore is revoked.
+type TokensRevoked struct {
+	TokenId       uuid.UUID `json:"token_id"`
+	UserId        uint      `json:"user_id"`
+	ExpiredAt     time.Time `json:"expired_at"`
+	RevokedBefore time.Time `json:"revoked_before"`
+}
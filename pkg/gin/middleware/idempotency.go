@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tricong1998/go-ecom/pkg/idempotency"
+	"github.com/tricong1998/go-ecom/pkg/token"
+)
+
+const IdempotentReplayedHeaderKey = "Idempotent-Replayed"
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware stores the first response for every Idempotency-Key
+// and replays it for repeated requests of the same user. It must run after
+// AuthMiddleware. Requests without the header are passed through, and server
+// errors are not stored so that they can be retried.
+func IdempotencyMiddleware(store idempotency.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotency.HeaderKey)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := idempotency.HashRequest([]byte(ctx.Request.Method), []byte(ctx.FullPath()), body)
+		record, replay, err := store.Reserve(idempotencyScope(ctx), key, requestHash, ttl)
+		if errors.Is(err, idempotency.ErrKeyReused) {
+			ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, idempotency.ErrRequestInProgress) {
+			ctx.AbortWithStatusJSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if replay {
+			ctx.Header(IdempotentReplayedHeaderKey, "true")
+			ctx.Data(record.StatusCode, "application/json; charset=utf-8", record.Response)
+			ctx.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			err = store.Release(record)
+		} else {
+			err = store.Complete(record, recorder.Status(), recorder.body.Bytes())
+		}
+		if err != nil {
+			_ = ctx.Error(err)
+		}
+	}
+}
+
+func idempotencyScope(ctx *gin.Context) string {
+	userPayload, ok := ctx.Get(AuthorizationPayloadKey)
+	if !ok {
+		return "anonymous"
+	}
+	return fmt.Sprintf("user:%d", userPayload.(*token.Payload).UserId)
+}
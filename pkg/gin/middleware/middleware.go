@@ -17,7 +17,9 @@ const (
 	AuthorizationPayloadKey = "authorization_payload"
 )
 
-func AuthMiddleware(tokenMaker token.Maker, roles []string) gin.HandlerFunc {
+// AuthMiddleware accepts the bearer tokens made by tokenMaker that were not
+// revoked, and only for roles when it is not empty.
+func AuthMiddleware(tokenMaker token.Maker, revocations token.RevocationStore, roles []string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(AuthorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -47,6 +49,16 @@ func AuthMiddleware(tokenMaker token.Maker, roles []string) gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := revocations.IsRevoked(payload)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if revoked {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+			return
+		}
+
 		if len(roles) > 0 && !utils.Contains(roles, payload.Role) {
 			err := fmt.Errorf("user is not authorized to access this resource")
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
@@ -0,0 +1,132 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// WithOutgoingKey attaches an idempotency key to the metadata of outgoing gRPC
+// calls made with the returned context.
+func WithOutgoingKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, key)
+}
+
+// UnaryServerInterceptor replays the stored response of calls that carry an
+// idempotency key in their metadata. Calls without a key are passed through.
+// Keys are scoped to the method and to the order or payment the request is
+// about, so two callers that pick the same key for different orders do not
+// get each other's response.
+func UnaryServerInterceptor(store Store, ttl time.Duration) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		key := incomingKey(ctx)
+		message, ok := req.(proto.Message)
+		if key == "" || !ok {
+			return handler(ctx, req)
+		}
+
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		scope := grpcScope(info.FullMethod, req)
+		record, replay, err := store.Reserve(scope, key, HashRequest([]byte(scope), body), ttl)
+		if errors.Is(err, ErrKeyReused) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, ErrRequestInProgress) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if replay {
+			return unmarshalResponse(record.Response)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			releaseErr := store.Release(record)
+			if releaseErr != nil {
+				return nil, errors.Join(err, releaseErr)
+			}
+			return nil, err
+		}
+
+		response, err := marshalResponse(resp)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		err = store.Complete(record, int(codes.OK), response)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		return resp, nil
+	}
+}
+
+// grpcScope is the method followed by the order or payment id of the request,
+// when it carries one.
+func grpcScope(fullMethod string, req interface{}) string {
+	if r, ok := req.(interface{ GetOrderId() uint64 }); ok {
+		return fmt.Sprintf("%s:order:%d", fullMethod, r.GetOrderId())
+	}
+	if r, ok := req.(interface{ GetPaymentId() uint64 }); ok {
+		return fmt.Sprintf("%s:payment:%d", fullMethod, r.GetPaymentId())
+	}
+	return fullMethod
+}
+
+func incomingKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(MetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// marshalResponse stores the response as an Any, so that it can be replayed
+// without knowing its type up front.
+func marshalResponse(resp interface{}) ([]byte, error) {
+	message, ok := resp.(proto.Message)
+	if !ok {
+		return nil, errors.New("response is not a proto message")
+	}
+	anyMessage, err := anypb.New(message)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(anyMessage)
+}
+
+func unmarshalResponse(data []byte) (interface{}, error) {
+	var anyMessage anypb.Any
+	err := proto.Unmarshal(data, &anyMessage)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	message, err := anyMessage.UnmarshalNew()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return message, nil
+}
@@ -0,0 +1,117 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	HeaderKey   = "Idempotency-Key"
+	MetadataKey = "idempotency-key"
+)
+
+var (
+	ErrKeyReused         = errors.New("idempotency key was already used with a different request")
+	ErrRequestInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// Record is the stored outcome of the first request made with an idempotency
+// key. Keys are unique per scope, which is the user for HTTP requests and the
+// method and the order or payment for gRPC calls.
+type Record struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Scope       string    `json:"scope" gorm:"uniqueIndex:idx_idempotency_scope_key"`
+	Key         string    `json:"key" gorm:"uniqueIndex:idx_idempotency_scope_key"`
+	RequestHash string    `json:"request_hash"`
+	Completed   bool      `json:"completed"`
+	StatusCode  int       `json:"status_code"`
+	Response    []byte    `json:"response"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+type Store interface {
+	// Reserve claims key for the request. When the key was already used for
+	// the same request the stored record is returned with replay set.
+	Reserve(scope, key, requestHash string, ttl time.Duration) (record *Record, replay bool, err error)
+	// Complete stores the response of a reserved request.
+	Complete(record *Record, statusCode int, response []byte) error
+	// Release drops a reservation so that the request can be retried.
+	Release(record *Record) error
+}
+
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db}
+}
+
+func (s *GormStore) Reserve(scope, key, requestHash string, ttl time.Duration) (*Record, bool, error) {
+	record := Record{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return &record, false, nil
+	}
+
+	var existing Record
+	err := s.db.Where("scope = ? AND key = ?", scope, key).First(&existing).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	if time.Now().After(existing.ExpiresAt) {
+		err = s.db.Delete(&existing).Error
+		if err != nil {
+			return nil, false, err
+		}
+		return s.Reserve(scope, key, requestHash, ttl)
+	}
+	if existing.RequestHash != requestHash {
+		return nil, false, ErrKeyReused
+	}
+	if !existing.Completed {
+		return nil, false, ErrRequestInProgress
+	}
+	return &existing, true, nil
+}
+
+func (s *GormStore) Complete(record *Record, statusCode int, response []byte) error {
+	record.Completed = true
+	record.StatusCode = statusCode
+	record.Response = response
+	return s.db.Save(record).Error
+}
+
+func (s *GormStore) Release(record *Record) error {
+	return s.db.Delete(record).Error
+}
+
+// HashRequest returns a fingerprint of the request parts, used to detect a key
+// that is reused for a different request.
+func HashRequest(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,40 @@
+package money
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// MigrateColumn moves an amount stored in the integer column legacyColumn of
+// model, with its currency in legacyCurrencyColumn, to the columns of the
+// Money field embedded with prefix, then drops legacyColumn. Rows without a
+// currency get DefaultCurrency. It is meant to run after AutoMigrate, and does
+// nothing once legacyColumn is gone. legacyCurrencyColumn may be empty for
+// amounts that never had a currency, and is left in place as it can be shared
+// by several amounts.
+func MigrateColumn(db *gorm.DB, model interface{}, legacyColumn, legacyCurrencyColumn, prefix string) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(model, legacyColumn) {
+		return nil
+	}
+
+	currency := fmt.Sprintf("'%s'", DefaultCurrency)
+	if legacyCurrencyColumn != "" && migrator.HasColumn(model, legacyCurrencyColumn) {
+		currency = fmt.Sprintf("COALESCE(NULLIF(%s, ''), '%s')", legacyCurrencyColumn, DefaultCurrency)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(model).
+			Session(&gorm.Session{AllowGlobalUpdate: true}).
+			Unscoped().
+			Updates(map[string]interface{}{
+				prefix + "minor":    gorm.Expr("COALESCE(?, 0)", gorm.Expr(legacyColumn)),
+				prefix + "currency": gorm.Expr(currency),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(model, legacyColumn)
+	})
+}
@@ -0,0 +1,207 @@
+// Package money represents amounts of money as an integer number of minor
+// units, cents for USD, together with their ISO 4217 currency. Arithmetic is
+// checked: it fails instead of overflowing or mixing currencies.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// DefaultCurrency is used for amounts that were recorded without a currency.
+const DefaultCurrency = "USD"
+
+var (
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrOverflow         = errors.New("amount overflows")
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrNegativeAmount   = errors.New("amount is negative")
+	ErrDivisionByZero   = errors.New("division by zero")
+)
+
+// minorUnits is the number of decimals of the minor unit of every supported
+// currency.
+var minorUnits = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"SGD": 2,
+	"THB": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// Money is an amount in the minor unit of its currency. Embedded in a gorm
+// model with a prefix, it is stored in the columns <prefix>minor and
+// <prefix>currency.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"column:minor"`
+	Currency string `json:"currency" gorm:"column:currency;size:3"`
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Zero returns no money in currency.
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// MinorUnits returns the number of decimals of the minor unit of currency.
+func MinorUnits(currency string) (int, error) {
+	units, ok := minorUnits[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return units, nil
+}
+
+// Validate checks that the currency is supported and the amount is not
+// negative, as for a price.
+func (m Money) Validate() error {
+	_, err := MinorUnits(m.Currency)
+	if err != nil {
+		return err
+	}
+	if m.Amount < 0 {
+		return fmt.Errorf("%w: %s", ErrNegativeAmount, m)
+	}
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + other. A zero amount without a currency takes the currency
+// of the other operand, so that sums can start from Money{}.
+func (m Money) Add(other Money) (Money, error) {
+	currency, err := sameCurrency(m, other)
+	if err != nil {
+		return Money{}, err
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrOverflow, m, other)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: currency}, nil
+}
+
+// Sub returns m - other.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Amount == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrOverflow, m, other)
+	}
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Mul returns m * n.
+func (m Money) Mul(n int64) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(n))
+	if !product.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrOverflow, m, n)
+	}
+	return Money{Amount: product.Int64(), Currency: m.Currency}, nil
+}
+
+// MulRate returns m * numerator / denominator, rounded to a minor unit with
+// mode. It takes percentages and fees, e.g. MulRate(290, 10000, RoundHalfEven)
+// for 2.9%.
+func (m Money) MulRate(numerator, denominator int64, mode RoundingMode) (Money, error) {
+	if denominator == 0 {
+		return Money{}, ErrDivisionByZero
+	}
+	product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(numerator))
+	quotient := divRound(product, big.NewInt(denominator), mode)
+	if !quotient.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s * %d / %d", ErrOverflow, m, numerator, denominator)
+	}
+	return Money{Amount: quotient.Int64(), Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or 1 when m is less than, equal to or greater than other.
+func (m Money) Cmp(other Money) (int, error) {
+	_, err := sameCurrency(m, other)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Min returns the smaller of m and other.
+func (m Money) Min(other Money) (Money, error) {
+	cmp, err := m.Cmp(other)
+	if err != nil {
+		return Money{}, err
+	}
+	if cmp > 0 {
+		return other, nil
+	}
+	return m, nil
+}
+
+// Sum adds values, which must all be in currency.
+func Sum(currency string, values ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, v := range values {
+		var err error
+		total, err = total.Add(v)
+		if err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// String formats m in major units, e.g. "12.34 USD". Amounts of an unknown
+// currency are formatted in minor units.
+func (m Money) String() string {
+	units, err := MinorUnits(m.Currency)
+	if err != nil || units == 0 {
+		return strings.TrimSpace(fmt.Sprintf("%d %s", m.Amount, m.Currency))
+	}
+
+	sign := ""
+	amount := new(big.Int).SetInt64(m.Amount)
+	if amount.Sign() < 0 {
+		sign = "-"
+		amount.Neg(amount)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(units)), nil)
+	major, minor := new(big.Int).QuoRem(amount, scale, new(big.Int))
+	return fmt.Sprintf("%s%s.%0*d %s", sign, major, units, minor.Int64(), m.Currency)
+}
+
+func sameCurrency(a, b Money) (string, error) {
+	switch {
+	case a.Currency == b.Currency:
+		return a.Currency, nil
+	case a.Currency == "" && a.Amount == 0:
+		return b.Currency, nil
+	case b.Currency == "" && b.Amount == 0:
+		return a.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a.Currency, b.Currency)
+}
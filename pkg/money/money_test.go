@@ -0,0 +1,79 @@
+package money
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdd(t *testing.T) {
+	sum, err := New(150, "usd").Add(New(275, "USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, New(425, "USD"), sum)
+
+	sum, err = Money{}.Add(New(100, "EUR"))
+	assert.NoError(t, err)
+	assert.Equal(t, New(100, "EUR"), sum)
+
+	_, err = New(100, "USD").Add(New(100, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = New(math.MaxInt64, "USD").Add(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	_, err = New(math.MinInt64, "USD").Sub(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMul(t *testing.T) {
+	product, err := New(1999, "USD").Mul(3)
+	assert.NoError(t, err)
+	assert.Equal(t, New(5997, "USD"), product)
+
+	_, err = New(math.MaxInt64/2+1, "USD").Mul(2)
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMulRate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		amount int64
+		mode   RoundingMode
+		expect int64
+	}{
+		{"HalfEvenDown", 250, RoundHalfEven, 2},
+		{"HalfEvenUp", 350, RoundHalfEven, 4},
+		{"HalfUp", 250, RoundHalfUp, 3},
+		{"HalfUpNegative", -250, RoundHalfUp, -3},
+		{"Down", 299, RoundDown, 2},
+		{"Up", 201, RoundUp, 3},
+		{"Exact", 300, RoundUp, 3},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			// 1%
+			rated, err := New(tc.amount, "USD").MulRate(1, 100, tc.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, rated.Amount)
+		})
+	}
+
+	_, err := New(100, "USD").MulRate(1, 0, RoundHalfEven)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "12.05 USD", New(1205, "USD").String())
+	assert.Equal(t, "-0.50 EUR", New(-50, "EUR").String())
+	assert.Equal(t, "1.005 KWD", New(1005, "KWD").String())
+	assert.Equal(t, "1500 JPY", New(1500, "JPY").String())
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, New(0, "VND").Validate())
+	assert.ErrorIs(t, New(100, "XXX").Validate(), ErrUnknownCurrency)
+	assert.ErrorIs(t, New(-1, "USD").Validate(), ErrNegativeAmount)
+}
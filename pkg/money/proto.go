@@ -0,0 +1,13 @@
+package money
+
+import "github.com/tricong1998/go-ecom/pkg/money/moneypb"
+
+func ToProto(m Money) *moneypb.Money {
+	return &moneypb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+// FromProto converts a protobuf amount. An unset amount is zero without a
+// currency.
+func FromProto(m *moneypb.Money) Money {
+	return Money{Amount: m.GetAmount(), Currency: m.GetCurrency()}
+}
@@ -0,0 +1,59 @@
+package money
+
+import "math/big"
+
+// RoundingMode decides which minor unit a fraction of a minor unit goes to.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest minor unit, and halves to the even
+	// one. It is the default for computed amounts as it does not drift over
+	// many operations.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest minor unit, and halves away from zero.
+	RoundHalfUp
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// divRound returns n / d rounded with mode.
+func divRound(n, d *big.Int, mode RoundingMode) *big.Int {
+	if d.Sign() < 0 {
+		n = new(big.Int).Neg(n)
+		d = new(big.Int).Neg(d)
+	}
+	quotient, remainder := new(big.Int).QuoRem(n, d, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// away moves the quotient one minor unit away from zero.
+	away := func() *big.Int {
+		if n.Sign() < 0 {
+			return quotient.Sub(quotient, big.NewInt(1))
+		}
+		return quotient.Add(quotient, big.NewInt(1))
+	}
+
+	switch mode {
+	case RoundDown:
+		return quotient
+	case RoundUp:
+		return away()
+	}
+
+	twice := new(big.Int).Abs(remainder)
+	twice.Lsh(twice, 1)
+	switch twice.Cmp(d) {
+	case -1:
+		return quotient
+	case 1:
+		return away()
+	}
+	if mode == RoundHalfEven && quotient.Bit(0) == 0 {
+		return quotient
+	}
+	return away()
+}
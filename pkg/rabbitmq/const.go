@@ -3,3 +3,35 @@ package rabbitmq
 const PAYMENT_ORDER_COMPLETED_QUEUE = "BUY_ORDER_COMPLETED_QUEUE"
 const E_COM_EXCHANGE = "E_COM_EXCHANGE"
 const PAYMENT_ORDER_COMPLETED_ROUTING_KEY = "BUY_ORDER_COMPLETED_QUEUE"
+const ORDER_CANCELLED_QUEUE = "ORDER_CANCELLED_QUEUE"
+const ORDER_CANCELLED_ROUTING_KEY = "order.cancelled"
+
+const ORDER_CREATED_ROUTING_KEY = "order.created"
+const STOCK_RESERVED_ROUTING_KEY = "stock.reserved"
+const STOCK_RESERVATION_FAILED_ROUTING_KEY = "stock.reservation_failed"
+const STOCK_COMMITTED_ROUTING_KEY = "stock.committed"
+const STOCK_COMMIT_FAILED_ROUTING_KEY = "stock.commit_failed"
+const PAYMENT_SUCCEEDED_ROUTING_KEY = "payment.succeeded"
+const PAYMENT_FAILED_ROUTING_KEY = "payment.failed"
+const PAYMENT_REFUNDED_ROUTING_KEY = "payment.refunded"
+const PAYMENT_CAPTURED_ROUTING_KEY = "payment.captured"
+const PAYMENT_CONFIRMED_ROUTING_KEY = "payment.confirmed"
+const TOKENS_REVOKED_ROUTING_KEY = "tokens.revoked"
+
+const PRODUCT_ORDER_CREATED_QUEUE = "PRODUCT_ORDER_CREATED_QUEUE"
+const PRODUCT_PAYMENT_SUCCEEDED_QUEUE = "PRODUCT_PAYMENT_SUCCEEDED_QUEUE"
+const PRODUCT_PAYMENT_FAILED_QUEUE = "PRODUCT_PAYMENT_FAILED_QUEUE"
+const PAYMENT_STOCK_RESERVED_QUEUE = "PAYMENT_STOCK_RESERVED_QUEUE"
+const PAYMENT_STOCK_COMMIT_FAILED_QUEUE = "PAYMENT_STOCK_COMMIT_FAILED_QUEUE"
+const PAYMENT_STOCK_COMMITTED_QUEUE = "PAYMENT_STOCK_COMMITTED_QUEUE"
+const ORDER_STOCK_RESERVED_QUEUE = "ORDER_STOCK_RESERVED_QUEUE"
+const ORDER_STOCK_RESERVATION_FAILED_QUEUE = "ORDER_STOCK_RESERVATION_FAILED_QUEUE"
+const ORDER_STOCK_COMMITTED_QUEUE = "ORDER_STOCK_COMMITTED_QUEUE"
+const ORDER_STOCK_COMMIT_FAILED_QUEUE = "ORDER_STOCK_COMMIT_FAILED_QUEUE"
+const ORDER_PAYMENT_SUCCEEDED_QUEUE = "ORDER_PAYMENT_SUCCEEDED_QUEUE"
+const ORDER_PAYMENT_FAILED_QUEUE = "ORDER_PAYMENT_FAILED_QUEUE"
+const ORDER_PAYMENT_CAPTURED_QUEUE = "ORDER_PAYMENT_CAPTURED_QUEUE"
+const ORDER_PAYMENT_CONFIRMED_QUEUE = "ORDER_PAYMENT_CONFIRMED_QUEUE"
+const ORDER_TOKENS_REVOKED_QUEUE = "ORDER_TOKENS_REVOKED_QUEUE"
+const PRODUCT_TOKENS_REVOKED_QUEUE = "PRODUCT_TOKENS_REVOKED_QUEUE"
+const PAYMENT_TOKENS_REVOKED_QUEUE = "PAYMENT_TOKENS_REVOKED_QUEUE"
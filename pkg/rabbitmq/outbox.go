@@ -0,0 +1,246 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog"
+	uuid "github.com/satori/go.uuid"
+	"github.com/streadway/amqp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+const (
+	defaultOutboxInterval    = 2 * time.Second
+	defaultOutboxBatchSize   = 50
+	defaultOutboxMaxAttempts = 10
+	outboxMaxBackoff         = 5 * time.Minute
+)
+
+// OutboxMessage is an event waiting to be published. It is written in the
+// same database transaction as the state change it describes, and the
+// OutboxRelay publishes it afterwards.
+type OutboxMessage struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	MessageId     string     `json:"message_id" gorm:"uniqueIndex"`
+	RoutingKey    string     `json:"routing_key"`
+	Payload       []byte     `json:"payload"`
+	Status        string     `json:"status" gorm:"index"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func NewOutboxMessage(routingKey string, msg interface{}) (*OutboxMessage, error) {
+	data, err := jsoniter.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxMessage{
+		MessageId:     uuid.NewV4().String(),
+		RoutingKey:    routingKey,
+		Payload:       data,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// WriteOutbox stores msg in the outbox using tx, so that the event is only
+// recorded when the surrounding transaction commits.
+func WriteOutbox(tx *gorm.DB, routingKey string, msg interface{}) error {
+	message, err := NewOutboxMessage(routingKey, msg)
+	if err != nil {
+		return err
+	}
+	return tx.Create(message).Error
+}
+
+type IOutboxWriter interface {
+	Write(routingKey string, msg interface{}) error
+}
+
+// OutboxWriter writes events to the outbox outside of a business transaction,
+// for services whose events are only derived from a completed state change.
+type OutboxWriter struct {
+	db *gorm.DB
+}
+
+func NewOutboxWriter(db *gorm.DB) *OutboxWriter {
+	return &OutboxWriter{db}
+}
+
+func (w *OutboxWriter) Write(routingKey string, msg interface{}) error {
+	return WriteOutbox(w.db, routingKey, msg)
+}
+
+// OutboxRelay publishes the pending outbox messages to the exchange and marks
+// them sent. A message that cannot be published is retried with an
+// exponential backoff, and marked failed after MaxAttempts attempts.
+type OutboxRelay struct {
+	db           *gorm.DB
+	conn         *amqp.Connection
+	log          zerolog.Logger
+	ctx          context.Context
+	exchangeName string
+	exchangeType string
+	Interval     time.Duration
+	BatchSize    int
+	MaxAttempts  int
+}
+
+func NewOutboxRelay(
+	ctx context.Context,
+	db *gorm.DB,
+	conn *amqp.Connection,
+	log zerolog.Logger,
+	exchangeName string,
+	exchangeType string,
+) *OutboxRelay {
+	return &OutboxRelay{
+		ctx:          ctx,
+		db:           db,
+		conn:         conn,
+		log:          log,
+		exchangeName: exchangeName,
+		exchangeType: exchangeType,
+		Interval:     defaultOutboxInterval,
+		BatchSize:    defaultOutboxBatchSize,
+		MaxAttempts:  defaultOutboxMaxAttempts,
+	}
+}
+
+// Run relays the outbox every Interval until the context is done.
+func (r *OutboxRelay) Run() {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := r.RelayPending()
+		if err != nil {
+			r.log.Error().Err(err).Msg("Error in relaying outbox messages")
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publishes one batch of due messages and returns how many were
+// sent. The rows are locked with SKIP LOCKED so several relays can run side
+// by side without publishing a message twice.
+func (r *OutboxRelay) RelayPending() (int, error) {
+	sent := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var messages []OutboxMessage
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", OutboxStatusPending, time.Now()).
+			Order("id").
+			Limit(r.BatchSize).
+			Find(&messages).Error
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		channel, err := r.openChannel()
+		if err != nil {
+			return err
+		}
+		defer channel.Close()
+
+		for i := range messages {
+			message := &messages[i]
+			err := r.publish(channel, message)
+			if err != nil {
+				r.markRetry(message, err)
+			} else {
+				now := time.Now()
+				message.Status = OutboxStatusSent
+				message.SentAt = &now
+				sent++
+			}
+
+			err = tx.Save(message).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return sent, err
+}
+
+func (r *OutboxRelay) openChannel() (*amqp.Channel, error) {
+	channel, err := r.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	err = channel.ExchangeDeclare(
+		r.exchangeName, // name
+		r.exchangeType, // type
+		true,           // durable
+		false,          // auto-deleted
+		false,          // internal
+		false,          // no-wait
+		nil,            // arguments
+	)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	return channel, nil
+}
+
+func (r *OutboxRelay) publish(channel *amqp.Channel, message *OutboxMessage) error {
+	publishingMsg := amqp.Publishing{
+		Body:         message.Payload,
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    message.MessageId,
+		Timestamp:    time.Now(),
+	}
+
+	err := channel.Publish(r.exchangeName, message.RoutingKey, false, false, publishingMsg)
+	if err != nil {
+		return err
+	}
+
+	r.log.Info().Msgf("Published outbox message %s: %s", message.MessageId, string(message.Payload))
+	return nil
+}
+
+func (r *OutboxRelay) markRetry(message *OutboxMessage, err error) {
+	message.Attempts++
+	message.LastError = err.Error()
+	if message.Attempts >= r.MaxAttempts {
+		message.Status = OutboxStatusFailed
+		r.log.Error().Err(err).Msgf("Giving up on outbox message %s", message.MessageId)
+		return
+	}
+
+	delay := time.Second << message.Attempts
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	message.NextAttemptAt = time.Now().Add(delay)
+	r.log.Warn().Err(err).Msgf("Retrying outbox message %s in %s", message.MessageId, delay)
+}
@@ -0,0 +1,133 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const minRSAKeySize = 2048
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrVerifyOnly = errors.New("token maker can only verify tokens")
+)
+
+// SigningKey is a private key with the ID its public key is published under.
+type SigningKey struct {
+	ID         string
+	PrivateKey crypto.Signer
+}
+
+// KeySource returns the public key of the key ID found in a token header.
+type KeySource interface {
+	PublicKey(keyId string) (crypto.PublicKey, error)
+}
+
+// AsymmetricMaker signs tokens with RS256 or EdDSA, depending on the type of
+// the key. The first key signs new tokens; the others only verify, so that
+// tokens signed before a key rotation stay valid until they expire.
+type AsymmetricMaker struct {
+	keys   []SigningKey
+	source KeySource
+}
+
+// CreateToken implements Maker.
+func (maker *AsymmetricMaker) CreateToken(username string, userId uint, duration time.Duration, role string) (string, *Payload, error) {
+	if len(maker.keys) == 0 {
+		return "", nil, ErrVerifyOnly
+	}
+	key := maker.keys[0]
+	method, err := signingMethod(key.PrivateKey.Public())
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload, err := NewPayload(username, userId, duration, role)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jwtToken := jwt.NewWithClaims(method, payload)
+	jwtToken.Header["kid"] = key.ID
+	token, err := jwtToken.SignedString(key.PrivateKey)
+	return token, payload, err
+}
+
+// VerifyToken implements Maker.
+func (maker *AsymmetricMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		keyId, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		publicKey, err := maker.source.PublicKey(keyId)
+		if err != nil {
+			return nil, err
+		}
+		// The algorithm comes from the key, never from the token.
+		method, err := signingMethod(publicKey)
+		if err != nil || method.Alg() != token.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return publicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	err = payload.checkType(TypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// NewAsymmetricMaker returns a maker that signs with the first of keys and
+// verifies with all of them.
+func NewAsymmetricMaker(keys ...SigningKey) (Maker, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+	jwks, err := NewJWKS(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return &AsymmetricMaker{keys, jwks}, nil
+}
+
+// NewVerifier returns a maker that verifies tokens with the public keys of
+// source, and cannot create any.
+func NewVerifier(source KeySource) Maker {
+	return &AsymmetricMaker{source: source}
+}
+
+func signingMethod(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeySize {
+			return nil, fmt.Errorf("invalid key size: RSA keys must have at least %d bits", minRSAKeySize)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
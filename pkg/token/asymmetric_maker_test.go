@@ -0,0 +1,96 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+	"github.com/tricong1998/go-ecom/pkg/util"
+)
+
+func newSigningKeys(t *testing.T) (SigningKey, SigningKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return SigningKey{ID: "rsa-1", PrivateKey: rsaKey}, SigningKey{ID: "ed-1", PrivateKey: edKey}
+}
+
+func TestAsymmetricMaker(t *testing.T) {
+	rsaKey, edKey := newSigningKeys(t)
+
+	for _, key := range []SigningKey{rsaKey, edKey} {
+		maker, err := NewAsymmetricMaker(key)
+		require.NoError(t, err)
+
+		username := util.RandomOwner()
+		token, _, err := maker.CreateToken(username, 1, time.Minute, "user")
+		require.NoError(t, err)
+
+		payload, err := maker.VerifyToken(token)
+		require.NoError(t, err)
+		require.Equal(t, username, payload.Username)
+	}
+}
+
+func TestAsymmetricMakerKeyRotation(t *testing.T) {
+	rsaKey, edKey := newSigningKeys(t)
+	oldMaker, err := NewAsymmetricMaker(rsaKey)
+	require.NoError(t, err)
+	token, _, err := oldMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+
+	rotatedMaker, err := NewAsymmetricMaker(edKey, rsaKey)
+	require.NoError(t, err)
+	_, err = rotatedMaker.VerifyToken(token)
+	require.NoError(t, err)
+
+	newMaker, err := NewAsymmetricMaker(edKey)
+	require.NoError(t, err)
+	_, err = newMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestVerifierWithJWKS(t *testing.T) {
+	rsaKey, edKey := newSigningKeys(t)
+	maker, err := NewAsymmetricMaker(edKey, rsaKey)
+	require.NoError(t, err)
+	jwks, err := NewJWKS(edKey, rsaKey)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks)
+	}))
+	defer server.Close()
+
+	verifier := NewVerifier(NewJWKSClient(server.URL, time.Minute))
+	token, _, err := maker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+
+	_, _, err = verifier.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.ErrorIs(t, err, ErrVerifyOnly)
+}
+
+func TestAsymmetricMakerRejectsOtherAlgorithms(t *testing.T) {
+	rsaKey, _ := newSigningKeys(t)
+	maker, err := NewAsymmetricMaker(rsaKey)
+	require.NoError(t, err)
+	payload, err := NewPayload(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	jwtToken.Header["kid"] = rsaKey.ID
+	token, err := jwtToken.SignedString([]byte(util.RandomString(32)))
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
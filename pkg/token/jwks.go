@@ -0,0 +1,216 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksMinRefresh bounds how often a JWKSClient fetches the key set again for
+// a key ID it does not know.
+const jwksMinRefresh = 30 * time.Second
+
+// JWK is a public key in the JSON Web Key format, for RSA and Ed25519 keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the set of public keys tokens can be verified with.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWKS publishes the public keys of keys.
+func NewJWKS(keys ...SigningKey) (*JWKS, error) {
+	jwks := JWKS{Keys: make([]JWK, 0, len(keys))}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key.ID == "" || seen[key.ID] {
+			return nil, fmt.Errorf("signing key IDs must be unique and not empty: %q", key.ID)
+		}
+		seen[key.ID] = true
+
+		publicKey := key.PrivateKey.Public()
+		method, err := signingMethod(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: method.Alg()}
+		switch publicKey := publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return &jwks, nil
+}
+
+// PublicKey implements KeySource.
+func (jwks *JWKS) PublicKey(keyId string) (crypto.PublicKey, error) {
+	for _, jwk := range jwks.Keys {
+		if jwk.Kid == keyId {
+			return jwk.PublicKey()
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyId)
+}
+
+// PublicKey decodes the key.
+func (jwk JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case jwk.Kty == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+// JWKSClient fetches the key set published at a URL and caches it for a TTL.
+// A key ID missing from the cache triggers an early fetch, so that keys added
+// by a rotation are picked up before the cache expires.
+type JWKSClient struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu        sync.Mutex
+	jwks      *JWKS
+	fetchedAt time.Time
+}
+
+func NewJWKSClient(url string, ttl time.Duration) *JWKSClient {
+	return &JWKSClient{url: url, ttl: ttl, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// PublicKey implements KeySource. The cached keys keep being used while the
+// key set cannot be fetched.
+func (c *JWKSClient) PublicKey(keyId string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	age := time.Since(c.fetchedAt)
+	if c.jwks != nil && age < c.ttl {
+		publicKey, err := c.jwks.PublicKey(keyId)
+		if !errors.Is(err, ErrUnknownKey) || age < jwksMinRefresh {
+			return publicKey, err
+		}
+	}
+
+	err := c.fetch()
+	if err != nil && c.jwks == nil {
+		return nil, err
+	}
+	return c.jwks.PublicKey(keyId)
+}
+
+func (c *JWKSClient) fetch() error {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var jwks JWKS
+	err = json.NewDecoder(resp.Body).Decode(&jwks)
+	if err != nil {
+		return err
+	}
+	c.jwks = &jwks
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+// LoadSigningKeys reads the comma separated list of id:path pairs in spec,
+// each path being a PEM encoded PKCS #8 or PKCS #1 private key. The first key
+// signs new tokens.
+func LoadSigningKeys(spec string) ([]SigningKey, error) {
+	var keys []SigningKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid signing key %q: expected id:path", entry)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", id, err)
+		}
+		keys = append(keys, SigningKey{ID: id, PrivateKey: privateKey})
+	}
+	return keys, nil
+}
+
+// ParsePrivateKeyPEM decodes an RSA or Ed25519 private key.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if _, err := signingMethod(signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
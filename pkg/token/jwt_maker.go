@@ -12,6 +12,7 @@ const minSecreteKeySize = 32
 
 type JWTMaker struct {
 	secretKey string
+	tokenType string
 }
 
 // CreateToken implements Maker.
@@ -21,6 +22,7 @@ func (maker *JWTMaker) CreateToken(username string, userId uint, duration time.D
 	if err != nil {
 		return "", nil, err
 	}
+	payload.Type = maker.tokenType
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 	token, err := jwtToken.SignedString([]byte(maker.secretKey))
@@ -50,14 +52,30 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	if !ok {
 		return nil, ErrInvalidToken
 	}
+	err = payload.checkType(maker.tokenType)
+	if err != nil {
+		return nil, err
+	}
 
 	return payload, nil
 }
 
+// NewJWTMaker returns a maker of access tokens signed with secretKey.
 func NewJWTMaker(secretKey string) (Maker, error) {
+	return newJWTMaker(secretKey, TypeAccess)
+}
+
+// NewRefreshTokenMaker returns a maker of refresh tokens signed with
+// secretKey. Its tokens are rejected by access token makers, and it rejects
+// access tokens.
+func NewRefreshTokenMaker(secretKey string) (Maker, error) {
+	return newJWTMaker(secretKey, TypeRefresh)
+}
+
+func newJWTMaker(secretKey, tokenType string) (Maker, error) {
 	if len(secretKey) < minSecreteKeySize {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecreteKeySize)
 	}
 
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey, tokenType}, nil
 }
@@ -71,3 +71,27 @@ func TestInvalidJwtTokenAlgNone(t *testing.T) {
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestJwtMakerTokenType(t *testing.T) {
+	// Even with the same secret, refresh and access tokens are told apart.
+	secret := util.RandomString(32)
+	accessMaker, err := NewJWTMaker(secret)
+	require.NoError(t, err)
+	refreshMaker, err := NewRefreshTokenMaker(secret)
+	require.NoError(t, err)
+
+	refreshToken, _, err := refreshMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+	payload, err := accessMaker.VerifyToken(refreshToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+	payload, err = refreshMaker.VerifyToken(refreshToken)
+	require.NoError(t, err)
+	require.Equal(t, TypeRefresh, payload.Type)
+
+	accessToken, _, err := accessMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+	payload, err = refreshMaker.VerifyToken(accessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
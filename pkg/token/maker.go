@@ -2,6 +2,13 @@ package token
 
 import "time"
 
+// Token formats a deployment can choose from.
+const (
+	FormatJWT          = "jwt"
+	FormatPasetoLocal  = "paseto-local"
+	FormatPasetoPublic = "paseto-public"
+)
+
 type Maker interface {
 	CreateToken(username string, userId uint, duration time.Duration, role string) (string, *Payload, error)
 	VerifyToken(token string) (*Payload, error)
@@ -0,0 +1,302 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	pasetoLocalHeader  = "v4.local."
+	pasetoPublicHeader = "v4.public."
+	pasetoNonceSize    = 32
+	pasetoMacSize      = 32
+)
+
+// PasetoMaker makes PASETO v4 tokens: local tokens, encrypted with a
+// symmetric key, or public tokens, signed with Ed25519 keys. Public tokens
+// carry the ID of their key in the footer, and rotate like AsymmetricMaker:
+// the first key signs, the others only verify.
+type PasetoMaker struct {
+	symmetricKey []byte
+	keys         []SigningKey
+	source       KeySource
+}
+
+type pasetoFooter struct {
+	Kid string `json:"kid"`
+}
+
+// CreateToken implements Maker.
+func (maker *PasetoMaker) CreateToken(username string, userId uint, duration time.Duration, role string) (string, *Payload, error) {
+	if maker.symmetricKey == nil && len(maker.keys) == 0 {
+		return "", nil, ErrVerifyOnly
+	}
+
+	payload, err := NewPayload(username, userId, duration, role)
+	if err != nil {
+		return "", nil, err
+	}
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if maker.symmetricKey != nil {
+		token, err := pasetoEncrypt(maker.symmetricKey, message)
+		return token, payload, err
+	}
+	key := maker.keys[0]
+	token, err := pasetoSign(key, message)
+	return token, payload, err
+}
+
+// VerifyToken implements Maker.
+func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	var message []byte
+	var err error
+	if maker.symmetricKey != nil {
+		message, err = pasetoDecrypt(maker.symmetricKey, token)
+	} else {
+		message, err = pasetoVerify(maker.source, token)
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	err = json.Unmarshal(message, payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+	err = payload.checkType(TypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// NewPasetoLocalMaker returns a maker of v4.local tokens encrypted with
+// symmetricKey.
+func NewPasetoLocalMaker(symmetricKey string) (Maker, error) {
+	if len(symmetricKey) != chacha20.KeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20.KeySize)
+	}
+	return &PasetoMaker{symmetricKey: []byte(symmetricKey)}, nil
+}
+
+// NewPasetoPublicMaker returns a maker of v4.public tokens signed with the
+// first of keys, which must all be Ed25519 keys.
+func NewPasetoPublicMaker(keys ...SigningKey) (Maker, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+	for _, key := range keys {
+		if _, ok := key.PrivateKey.(ed25519.PrivateKey); !ok {
+			return nil, fmt.Errorf("signing key %s: PASETO v4 public tokens require an Ed25519 key", key.ID)
+		}
+	}
+	jwks, err := NewJWKS(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return &PasetoMaker{keys: keys, source: jwks}, nil
+}
+
+// NewPasetoVerifier returns a maker that verifies v4.public tokens with the
+// public keys of source, and cannot create any.
+func NewPasetoVerifier(source KeySource) Maker {
+	return &PasetoMaker{source: source}
+}
+
+func pasetoEncrypt(key, message []byte) (string, error) {
+	nonce := make([]byte, pasetoNonceSize)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	encryptionKey, counterNonce, authKey, err := pasetoSplitKey(key, nonce)
+	if err != nil {
+		return "", err
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	mac, err := pasetoMac(authKey, pae([]byte(pasetoLocalHeader), nonce, ciphertext, nil, nil))
+	if err != nil {
+		return "", err
+	}
+
+	body := append(append(nonce, ciphertext...), mac...)
+	return pasetoLocalHeader + base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+func pasetoDecrypt(key []byte, token string) ([]byte, error) {
+	body, footer, err := pasetoSplit(token, pasetoLocalHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < pasetoNonceSize+pasetoMacSize {
+		return nil, ErrInvalidToken
+	}
+	nonce := body[:pasetoNonceSize]
+	ciphertext := body[pasetoNonceSize : len(body)-pasetoMacSize]
+	mac := body[len(body)-pasetoMacSize:]
+
+	encryptionKey, counterNonce, authKey, err := pasetoSplitKey(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	expectedMac, err := pasetoMac(authKey, pae([]byte(pasetoLocalHeader), nonce, ciphertext, footer, nil))
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(mac, expectedMac) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+	return message, nil
+}
+
+// pasetoSplitKey derives the encryption key, the XChaCha20 nonce and the
+// authentication key of a v4.local token from its key and random nonce.
+func pasetoSplitKey(key, nonce []byte) ([]byte, []byte, []byte, error) {
+	hash, err := blake2b.New(56, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hash.Write([]byte("paseto-encryption-key"))
+	hash.Write(nonce)
+	derived := hash.Sum(nil)
+
+	authKey, err := pasetoMac(key, append([]byte("paseto-auth-key-for-aead"), nonce...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return derived[:chacha20.KeySize], derived[chacha20.KeySize:], authKey, nil
+}
+
+func pasetoMac(key, message []byte) ([]byte, error) {
+	hash, err := blake2b.New(pasetoMacSize, key)
+	if err != nil {
+		return nil, err
+	}
+	hash.Write(message)
+	return hash.Sum(nil), nil
+}
+
+func pasetoSign(key SigningKey, message []byte) (string, error) {
+	footer, err := json.Marshal(pasetoFooter{Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	privateKey := key.PrivateKey.(ed25519.PrivateKey)
+	signature := ed25519.Sign(privateKey, pae([]byte(pasetoPublicHeader), message, footer, nil))
+
+	body := append(append([]byte{}, message...), signature...)
+	return pasetoPublicHeader + base64.RawURLEncoding.EncodeToString(body) +
+		"." + base64.RawURLEncoding.EncodeToString(footer), nil
+}
+
+func pasetoVerify(source KeySource, token string) ([]byte, error) {
+	body, footer, err := pasetoSplit(token, pasetoPublicHeader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < ed25519.SignatureSize {
+		return nil, ErrInvalidToken
+	}
+	message := body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+
+	// The footer is only trusted to pick the key, and is signed with the rest.
+	var decodedFooter pasetoFooter
+	err = json.Unmarshal(footer, &decodedFooter)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	publicKey, err := source.PublicKey(decodedFooter.Kid)
+	if err != nil {
+		return nil, err
+	}
+	ed25519Key, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	if !ed25519.Verify(ed25519Key, pae([]byte(pasetoPublicHeader), message, footer, nil), signature) {
+		return nil, ErrInvalidToken
+	}
+	return message, nil
+}
+
+// pasetoSplit decodes the body and footer of a token, which must start with
+// header. Tokens of another version, purpose or format are rejected here, and
+// so are encodings with stray bits, which would let a token be altered without
+// changing what it decodes to.
+func pasetoSplit(token, header string) ([]byte, []byte, error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, nil, ErrInvalidToken
+	}
+	parts := strings.Split(strings.TrimPrefix(token, header), ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrInvalidToken
+	}
+	body, err := base64.RawURLEncoding.Strict().DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	var footer []byte
+	if len(parts) == 2 {
+		footer, err = base64.RawURLEncoding.Strict().DecodeString(parts[1])
+		if err != nil {
+			return nil, nil, ErrInvalidToken
+		}
+	}
+	return body, footer, nil
+}
+
+// pae is the pre-authentication encoding of PASETO, which authenticates the
+// pieces of a token together without ambiguity.
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	writeLength := func(n int) {
+		var length [8]byte
+		binary.LittleEndian.PutUint64(length[:], uint64(n)&(1<<63-1))
+		buf.Write(length[:])
+	}
+	writeLength(len(pieces))
+	for _, piece := range pieces {
+		writeLength(len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
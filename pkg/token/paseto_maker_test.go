@@ -0,0 +1,97 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tricong1998/go-ecom/pkg/util"
+)
+
+func newPasetoMakers(t *testing.T) (Maker, Maker) {
+	localMaker, err := NewPasetoLocalMaker(util.RandomString(32))
+	require.NoError(t, err)
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicMaker, err := NewPasetoPublicMaker(SigningKey{ID: "ed-1", PrivateKey: privateKey})
+	require.NoError(t, err)
+	return localMaker, publicMaker
+}
+
+func TestPasetoMaker(t *testing.T) {
+	localMaker, publicMaker := newPasetoMakers(t)
+
+	for _, maker := range []Maker{localMaker, publicMaker} {
+		username := util.RandomOwner()
+		issuedAt := time.Now()
+
+		token, _, err := maker.CreateToken(username, 1, time.Minute, "user")
+		require.NoError(t, err)
+
+		payload, err := maker.VerifyToken(token)
+		require.NoError(t, err)
+		require.Equal(t, username, payload.Username)
+		require.Equal(t, uint(1), payload.UserId)
+		require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	}
+}
+
+func TestPasetoMakerExpiredToken(t *testing.T) {
+	localMaker, publicMaker := newPasetoMakers(t)
+
+	for _, maker := range []Maker{localMaker, publicMaker} {
+		token, _, err := maker.CreateToken(util.RandomOwner(), 1, -time.Minute, "user")
+		require.NoError(t, err)
+
+		payload, err := maker.VerifyToken(token)
+		require.EqualError(t, err, ErrExpiredToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestPasetoMakerRejectsOtherFormats(t *testing.T) {
+	localMaker, publicMaker := newPasetoMakers(t)
+	jwtMaker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	localToken, _, err := localMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+	publicToken, _, err := publicMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+	jwtToken, _, err := jwtMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		maker Maker
+		token string
+	}{
+		{localMaker, publicToken},
+		{localMaker, jwtToken},
+		{publicMaker, localToken},
+		{publicMaker, jwtToken},
+		{jwtMaker, localToken},
+		{jwtMaker, publicToken},
+	} {
+		payload, err := tc.maker.VerifyToken(tc.token)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestPasetoMakerTamperedToken(t *testing.T) {
+	localMaker, _ := newPasetoMakers(t)
+	token, _, err := localMaker.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+	require.NoError(t, err)
+
+	tampered := []byte(token)
+	last := len(tampered) - 1
+	if tampered[last] == 'A' {
+		tampered[last] = 'B'
+	} else {
+		tampered[last] = 'A'
+	}
+	_, err = localMaker.VerifyToken(string(tampered))
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
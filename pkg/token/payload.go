@@ -12,6 +12,13 @@ var (
 	ErrInvalidToken = errors.New("token is invalid")
 )
 
+// Token types, so that a token of one type is never accepted as another even
+// when both are signed with the same key.
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	UserId    uint      `json:"user_id"`
@@ -19,6 +26,8 @@ type Payload struct {
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 	Role      string    `json:"role"`
+	// Type is empty in access tokens issued before tokens had a type.
+	Type string `json:"token_type,omitempty"`
 }
 
 // Valid implements jwt.Claims.
@@ -30,6 +39,19 @@ func (payload *Payload) Valid() error {
 	return nil
 }
 
+// checkType rejects a token that is not of tokenType. A token without a type
+// is an access token.
+func (payload *Payload) checkType(tokenType string) error {
+	payloadType := payload.Type
+	if payloadType == "" {
+		payloadType = TypeAccess
+	}
+	if payloadType != tokenType {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
 func NewPayload(username string, userId uint, duration time.Duration, role string) (*Payload, error) {
 	tokenId, err := uuid.NewRandom()
 	if err != nil {
@@ -43,6 +65,7 @@ func NewPayload(username string, userId uint, duration time.Duration, role strin
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 		Role:      role,
+		Type:      TypeAccess,
 	}
 
 	return payload, nil
@@ -0,0 +1,128 @@
+package token
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRevokedToken = errors.New("token is revoked")
+
+// RevocationStore rejects tokens before they expire. Tokens are revoked one by
+// one by their payload ID, or all together for a user up to a point in time.
+type RevocationStore interface {
+	// RevokeToken rejects the token id until expiredAt, after which it is
+	// rejected anyway.
+	RevokeToken(id uuid.UUID, expiredAt time.Time) error
+	// RevokeUserTokens rejects every token of userId issued before before.
+	RevokeUserTokens(userId uint, before time.Time) error
+	IsRevoked(payload *Payload) (bool, error)
+}
+
+// MemoryRevocationStore keeps revocations in memory, for a single process.
+type MemoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[uuid.UUID]time.Time
+	users  map[uint]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[uuid.UUID]time.Time),
+		users:  make(map[uint]time.Time),
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeToken(id uuid.UUID, expiredAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for tokenId, tokenExpiredAt := range s.tokens {
+		if now.After(tokenExpiredAt) {
+			delete(s.tokens, tokenId)
+		}
+	}
+	s.tokens[id] = expiredAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokeUserTokens(userId uint, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if before.After(s.users[userId]) {
+		s.users[userId] = before
+	}
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(payload *Payload) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[payload.ID]; ok {
+		return true, nil
+	}
+	before, ok := s.users[payload.UserId]
+	return ok && payload.IssuedAt.Before(before), nil
+}
+
+// RevokedToken is a token rejected until it expires.
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid"`
+	ExpiredAt time.Time `json:"expired_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevokedUserTokens rejects the tokens of a user issued before
+// RevokedBefore.
+type RevokedUserTokens struct {
+	UserId        uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// GormRevocationStore keeps revocations in the database of the service.
+type GormRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationStore(db *gorm.DB) *GormRevocationStore {
+	return &GormRevocationStore{db}
+}
+
+func (s *GormRevocationStore) RevokeToken(id uuid.UUID, expiredAt time.Time) error {
+	err := s.db.Where("expired_at < ?", time.Now()).Delete(&RevokedToken{}).Error
+	if err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&RevokedToken{ID: id, ExpiredAt: expiredAt}).Error
+}
+
+func (s *GormRevocationStore) RevokeUserTokens(userId uint, before time.Time) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "revoked_before"}, Value: gorm.Expr("GREATEST(revoked_user_tokens.revoked_before, excluded.revoked_before)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("excluded.updated_at")},
+		},
+	}).Create(&RevokedUserTokens{UserId: userId, RevokedBefore: before}).Error
+}
+
+func (s *GormRevocationStore) IsRevoked(payload *Payload) (bool, error) {
+	var count int64
+	err := s.db.Model(&RevokedToken{}).Where("id = ?", payload.ID).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	err = s.db.Model(&RevokedUserTokens{}).
+		Where("user_id = ? AND ? < revoked_before", payload.UserId, payload.IssuedAt).
+		Count(&count).Error
+	return count > 0, err
+}
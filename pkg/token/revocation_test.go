@@ -0,0 +1,41 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	payload, err := NewPayload("username", 1, time.Minute, "user")
+	require.NoError(t, err)
+	other, err := NewPayload("username", 1, time.Minute, "user")
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(payload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.RevokeToken(payload.ID, payload.ExpiredAt)
+	require.NoError(t, err)
+	revoked, err = store.IsRevoked(payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	revoked, err = store.IsRevoked(other)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = store.RevokeUserTokens(1, time.Now())
+	require.NoError(t, err)
+	revoked, err = store.IsRevoked(other)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	later, err := NewPayload("username", 1, time.Minute, "user")
+	require.NoError(t, err)
+	revoked, err = store.IsRevoked(later)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
@@ -0,0 +1,39 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VerifierConfig tells how a service that only checks access tokens verifies
+// them. Format is one of the token formats. JWKSURL is where the user service
+// publishes its public keys; without it JWTs are verified with Secret.
+type VerifierConfig struct {
+	Format       string
+	Secret       string
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+}
+
+// NewAccessTokenVerifier verifies access tokens in the configured format.
+// Tokens signed by the user service are verified with its public keys, and the
+// others with the shared secret.
+func NewAccessTokenVerifier(cfg VerifierConfig) (Maker, error) {
+	switch cfg.Format {
+	case FormatJWT:
+		if cfg.JWKSURL != "" {
+			return NewVerifier(NewJWKSClient(cfg.JWKSURL, cfg.JWKSCacheTTL)), nil
+		}
+		return NewJWTMaker(cfg.Secret)
+	case FormatPasetoLocal:
+		return NewPasetoLocalMaker(cfg.Secret)
+	case FormatPasetoPublic:
+		if cfg.JWKSURL == "" {
+			return nil, errors.New("PASETO public tokens require a JWKS URL")
+		}
+		return NewPasetoVerifier(NewJWKSClient(cfg.JWKSURL, cfg.JWKSCacheTTL)), nil
+	default:
+		return nil, fmt.Errorf("unknown access token format %q", cfg.Format)
+	}
+}
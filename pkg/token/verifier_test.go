@@ -0,0 +1,33 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tricong1998/go-ecom/pkg/util"
+)
+
+func TestAccessTokenVerifier(t *testing.T) {
+	secret := util.RandomString(32)
+	for _, format := range []string{FormatJWT, FormatPasetoLocal} {
+		verifier, err := NewAccessTokenVerifier(VerifierConfig{Format: format, Secret: secret})
+		require.NoError(t, err)
+		issuer, err := NewAccessTokenVerifier(VerifierConfig{Format: format, Secret: secret})
+		require.NoError(t, err)
+
+		token, _, err := issuer.CreateToken(util.RandomOwner(), 1, time.Minute, "user")
+		require.NoError(t, err)
+		payload, err := verifier.VerifyToken(token)
+		require.NoError(t, err)
+		require.Equal(t, uint(1), payload.UserId)
+	}
+}
+
+func TestAccessTokenVerifierInvalidConfig(t *testing.T) {
+	_, err := NewAccessTokenVerifier(VerifierConfig{Format: FormatPasetoPublic})
+	require.Error(t, err)
+
+	_, err = NewAccessTokenVerifier(VerifierConfig{Format: "unknown", Secret: util.RandomString(32)})
+	require.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"strconv"
+)
+
+func ParseBool(boolStr string, defaultBool bool) bool {
+	if boolStr == "" {
+		return defaultBool
+	}
+	value, err := strconv.ParseBool(boolStr)
+	if err != nil {
+		return defaultBool
+	}
+	return value
+}
+
+func ParseInt64(intStr string, defaultInt int64) int64 {
+	if intStr == "" {
+		return defaultInt
+	}
+	value, err := strconv.ParseInt(intStr, 10, 64)
+	if err != nil {
+		return defaultInt
+	}
+	return value
+}